@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"path/filepath"
+
+	"github.com/reconquest/hierr-go"
+)
+
+// newAdminClient returns HTTP client for admin endpoints of running server,
+// client authenticates using certificate specified via --client-cert and
+// --client-key flags.
+func newAdminClient(args map[string]interface{}) (*http.Client, error) {
+	return newPinnedClient(
+		args["--client-cert"].(string),
+		args["--client-key"].(string),
+		filepath.Join(args["--certs"].(string), "cert.pem"),
+	)
+}
+
+// newPinnedClient returns HTTP client, which authenticates using specified
+// client certificate and accepts only specified server certificate.
+func newPinnedClient(
+	clientCert, clientKey, serverCertFile string,
+) (*http.Client, error) {
+	certificate, err := tls.LoadX509KeyPair(clientCert, clientKey)
+	if err != nil {
+		return nil, usageError{
+			hierr.Errorf(err, "can't load client certificate"),
+		}
+	}
+
+	serverCert, err := readCertificate(serverCertFile)
+	if err != nil {
+		return nil, hierr.Errorf(
+			err, "can't read server certificate %s", serverCertFile,
+		)
+	}
+
+	return &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{
+				Certificates: []tls.Certificate{certificate},
+
+				// server certificate is pinned instead of verifying it
+				// against system roots, because it's usually self-signed
+				InsecureSkipVerify: true,
+				VerifyPeerCertificate: pinCertificate(
+					serverCert, serverCertFile,
+				),
+			},
+		},
+	}, nil
+}
+
+// pinCertificate returns TLS verification function, which accepts only
+// specified certificate.
+func pinCertificate(
+	cert *x509.Certificate, path string,
+) func([][]byte, [][]*x509.Certificate) error {
+	return func(chain [][]byte, _ [][]*x509.Certificate) error {
+		if len(chain) == 0 || !bytes.Equal(chain[0], cert.Raw) {
+			return fmt.Errorf("server certificate doesn't match %s", path)
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/reconquest/hierr-go"
+)
+
+// authorizeWebhookTimeout is time during which authorization webhook should
+// respond.
+const authorizeWebhookTimeout = 5 * time.Second
+
+// Authorizer decides whether hash table entry of token can be served to
+// client, so external policy engines can take part in serving decisions.
+type Authorizer interface {
+	Authorize(subject authorizationSubject) (bool, error)
+}
+
+// authorizationSubject describes request, which is going to be served.
+type authorizationSubject struct {
+	Token  string `json:"token"`
+	Client string `json:"client"`
+
+	// Identity is common name of verified client certificate, it's empty
+	// if client hasn't presented certificate.
+	Identity string `json:"identity,omitempty"`
+}
+
+func newAuthorizationSubject(
+	request *http.Request, token string,
+) authorizationSubject {
+	subject := authorizationSubject{
+		Token:  token,
+		Client: getClientAddress(request),
+	}
+
+	if request.TLS != nil && len(request.TLS.VerifiedChains) > 0 {
+		subject.Identity = request.TLS.VerifiedChains[0][0].Subject.CommonName
+	}
+
+	return subject
+}
+
+// noopAuthorizer allows serving of all tokens to all clients.
+type noopAuthorizer struct{}
+
+func (noopAuthorizer) Authorize(authorizationSubject) (bool, error) {
+	return true, nil
+}
+
+// webhookAuthorizer posts subject as JSON to specified URL, 2xx response
+// allows serving and 403 denies it, other responses are errors.
+type webhookAuthorizer struct {
+	url    string
+	client *http.Client
+}
+
+func newWebhookAuthorizer(url string) *webhookAuthorizer {
+	return &webhookAuthorizer{
+		url:    url,
+		client: &http.Client{Timeout: authorizeWebhookTimeout},
+	}
+}
+
+func (authorizer *webhookAuthorizer) Authorize(
+	subject authorizationSubject,
+) (bool, error) {
+	body, err := json.Marshal(subject)
+	if err != nil {
+		return false, err
+	}
+
+	response, err := authorizer.client.Post(
+		authorizer.url, "application/json", bytes.NewReader(body),
+	)
+	if err != nil {
+		return false, hierr.Errorf(
+			err, "can't send request to %s", authorizer.url,
+		)
+	}
+
+	defer response.Body.Close()
+
+	switch {
+	case response.StatusCode >= 200 && response.StatusCode <= 299:
+		return true, nil
+
+	case response.StatusCode == http.StatusForbidden:
+		return false, nil
+	}
+
+	return false, fmt.Errorf(
+		"%s responded with %s", authorizer.url, response.Status,
+	)
+}
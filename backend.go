@@ -1,15 +1,135 @@
 package main
 
+import (
+	"time"
+)
+
+// RecentClient is client, which has requested hash for token recently.
+type RecentClient struct {
+	Client   string    `json:"client"`
+	LastSeen time.Time `json:"last_seen"`
+}
+
 type Backend interface {
+	// GetPublicKeys returns public SSH keys of specified token. ErrNotFound
+	// is returned when keys of token are not stored at all.
 	GetPublicKeys(token string) (string, error)
 	AddPublicKey(token string, key []byte, truncate bool) error
 	SetHashTable(token string, table []string) error
+
+	// SwapHashTable atomically replaces hash table of specified token,
+	// readers see either whole old or whole new hash table, never partially
+	// written one.
+	SwapHashTable(token string, table []string) error
+
+	// SetHashTableStream stores hash table, which records are received from
+	// specified channel until it is closed, so whole table is not held in
+	// memory. Table is stored only if exactly size records are received,
+	// otherwise existing table is kept. Channel is drained even if error
+	// occurs, so sender is never blocked.
+	SetHashTableStream(token string, size int, records <-chan string) error
+
+	// SetHashTableWithExpiry stores hash table, which entries expire at
+	// specified times, zero time means that entry never expires. Expiries
+	// are stored along with records, so they are replaced atomically with
+	// them. Hash tables stored by other methods have no expiring entries.
+	SetHashTableWithExpiry(
+		token string, table []string, expiries []time.Time,
+	) error
+
+	// DeleteHashTable removes hash table of specified token along with its
+	// metadata and reservations. ErrNotFound is returned when token doesn't
+	// exist.
+	DeleteHashTable(token string) error
+
 	IsHashExists(token string, hash string) (bool, error)
 	GetHash(token string, number int64) (string, error)
+
+	// GetHashWithExpiry returns record with specified number along with time
+	// after which it should not be served, zero time means that record never
+	// expires.
+	GetHashWithExpiry(token string, number int64) (string, time.Time, error)
+
+	// GetHashesWithExpiry returns at most count records starting from
+	// specified number along with their expiries, so records can be checked
+	// for expiry without reading hash table once per record.
+	GetHashesWithExpiry(
+		token string, number int64, count int64,
+	) ([]string, []time.Time, error)
+
+	// GetHashTable returns all records of specified token in order of their
+	// indexes, records are read from single version of hash table, even if
+	// it is replaced concurrently.
+	GetHashTable(token string) ([]string, error)
+
+	ReserveIndex(token string, index int64) (bool, error)
 	IsRecentClient(identifier string) (bool, error)
+
+	// AddRecentClient marks client as recent. It is idempotent: adding
+	// client which is already recent (e.g. when client retries request)
+	// does not create another record, but refreshes time after which client
+	// is no longer recent.
 	AddRecentClient(identifier string) error
+
+	// AddRecentClientRequest counts repeated request of recent client and
+	// returns amount of repeated requests made by client while it is recent,
+	// including this one.
+	AddRecentClientRequest(identifier string) (int, error)
+
+	// DeleteRecentClient removes recent marks of specified remote address for
+	// all tokens, so client will be treated as new on next request.
+	// ErrNotFound is returned when client is not recent.
+	DeleteRecentClient(remote string) error
+
+	// FlushRecentClients removes all recent clients of specified token or
+	// of all tokens if token is empty, and returns amount of removed
+	// clients.
+	FlushRecentClients(token string) (int, error)
+
+	// PruneRecentClients removes clients, which are no longer recent, and
+	// returns amount of removed clients.
+	PruneRecentClients() (int, error)
+
+	// CountExpiredRecentClients returns amount of clients, which are no
+	// longer recent, but haven't been pruned yet.
+	CountExpiredRecentClients() (int, error)
+
+	// GetRecentClientsCount returns amount of distinct recent clients for
+	// specified token.
+	GetRecentClientsCount(token string) (int, error)
+
+	// ListRecentClients returns recent clients of specified token.
+	ListRecentClients(token string) ([]RecentClient, error)
+
 	GetTableSize(token string) (int64, error)
+
+	// GetTokens returns tokens with specified prefix. ErrNotFound is returned
+	// when prefix does not exist at all, empty list is returned when prefix
+	// exists, but there are no tokens in it.
 	GetTokens(prefix string) ([]string, error)
 
+	// GetAllTokens returns all tokens, including tokens in nested
+	// namespaces.
+	GetAllTokens() ([]string, error)
+
+	// GetTokensModifiedSince returns tokens under specified prefix, which
+	// hash tables or metadata have been changed after specified time, prefix
+	// is trimmed like in GetTokens.
+	GetTokensModifiedSince(prefix string, since time.Time) ([]string, error)
+
+	// AddServed increases counter of hash entries served for specified
+	// token by delta, counter is reset when hash table is replaced.
+	AddServed(token string, delta int64) error
+
+	GetTokenInfo(token string) (*tokenInfo, error)
+	SetTokenInfo(token string, info *tokenInfo) error
+
 	Init() error
 }
+
+// drainRecords reads remaining records from channel, so their sender is not
+// blocked forever when receiver has failed.
+func drainRecords(records <-chan string) {
+	for range records {
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// CachingBackend caches hash table sizes and records read from wrapped
+// backend, cached values of token are invalidated when its hash table is
+// replaced through CachingBackend. Hash tables replaced by other processes
+// are seen after cache TTL expires.
+type CachingBackend struct {
+	Backend
+
+	ttl     time.Duration
+	size    int
+	metrics *metrics
+
+	// entries contains cache entries ordered by last access, so least
+	// recently used entry is evicted first.
+	entries *list.List
+	index   map[cacheKey]*list.Element
+	lock    *sync.Mutex
+}
+
+// cacheKey identifies cached value, number is -1 for hash table size.
+type cacheKey struct {
+	token  string
+	number int64
+}
+
+// cachedHash is cached hash table record along with its expiry.
+type cachedHash struct {
+	record string
+	expiry time.Time
+}
+
+type cacheEntry struct {
+	key     cacheKey
+	value   interface{}
+	expires time.Time
+}
+
+func newCachingBackend(
+	backend Backend, ttl time.Duration, size int, metrics *metrics,
+) *CachingBackend {
+	return &CachingBackend{
+		Backend: backend,
+		ttl:     ttl,
+		size:    size,
+		metrics: metrics,
+		entries: list.New(),
+		index:   map[cacheKey]*list.Element{},
+		lock:    &sync.Mutex{},
+	}
+}
+
+func (cache *CachingBackend) GetTableSize(token string) (int64, error) {
+	key := cacheKey{token: token, number: -1}
+
+	if value, ok := cache.get(key); ok {
+		return value.(int64), nil
+	}
+
+	size, err := cache.Backend.GetTableSize(token)
+	if err != nil {
+		return 0, err
+	}
+
+	cache.put(key, size)
+
+	return size, nil
+}
+
+func (cache *CachingBackend) GetHash(
+	token string, number int64,
+) (string, error) {
+	record, _, err := cache.GetHashWithExpiry(token, number)
+
+	return record, err
+}
+
+func (cache *CachingBackend) GetHashWithExpiry(
+	token string, number int64,
+) (string, time.Time, error) {
+	key := cacheKey{token: token, number: number}
+
+	if value, ok := cache.get(key); ok {
+		entry := value.(cachedHash)
+		return entry.record, entry.expiry, nil
+	}
+
+	record, expiry, err := cache.Backend.GetHashWithExpiry(token, number)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	cache.put(key, cachedHash{record: record, expiry: expiry})
+
+	return record, expiry, nil
+}
+
+func (cache *CachingBackend) SetHashTable(token string, table []string) error {
+	defer cache.invalidate(token)
+
+	return cache.Backend.SetHashTable(token, table)
+}
+
+func (cache *CachingBackend) SwapHashTable(token string, table []string) error {
+	defer cache.invalidate(token)
+
+	return cache.Backend.SwapHashTable(token, table)
+}
+
+func (cache *CachingBackend) SetHashTableStream(
+	token string, size int, records <-chan string,
+) error {
+	defer cache.invalidate(token)
+
+	return cache.Backend.SetHashTableStream(token, size, records)
+}
+
+func (cache *CachingBackend) SetHashTableWithExpiry(
+	token string, table []string, expiries []time.Time,
+) error {
+	defer cache.invalidate(token)
+
+	return cache.Backend.SetHashTableWithExpiry(token, table, expiries)
+}
+
+func (cache *CachingBackend) DeleteHashTable(token string) error {
+	defer cache.invalidate(token)
+
+	return cache.Backend.DeleteHashTable(token)
+}
+
+func (cache *CachingBackend) get(key cacheKey) (interface{}, bool) {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	element, ok := cache.index[key]
+	if ok && time.Now().After(element.Value.(*cacheEntry).expires) {
+		cache.remove(element)
+		ok = false
+	}
+
+	if !ok {
+		cache.metrics.inc(metricCacheMisses, key.token)
+		return nil, false
+	}
+
+	cache.metrics.inc(metricCacheHits, key.token)
+
+	cache.entries.MoveToFront(element)
+
+	return element.Value.(*cacheEntry).value, true
+}
+
+func (cache *CachingBackend) put(key cacheKey, value interface{}) {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	if element, ok := cache.index[key]; ok {
+		cache.remove(element)
+	}
+
+	for cache.entries.Len() >= cache.size {
+		cache.remove(cache.entries.Back())
+	}
+
+	cache.index[key] = cache.entries.PushFront(&cacheEntry{
+		key:     key,
+		value:   value,
+		expires: time.Now().Add(cache.ttl),
+	})
+}
+
+// invalidate removes all cached values of specified token.
+func (cache *CachingBackend) invalidate(token string) {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	for key, element := range cache.index {
+		if key.token == token {
+			cache.remove(element)
+		}
+	}
+}
+
+func (cache *CachingBackend) remove(element *list.Element) {
+	cache.entries.Remove(element)
+	delete(cache.index, element.Value.(*cacheEntry).key)
+}
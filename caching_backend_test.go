@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// newBenchmarkFilesystem returns filesystem backend in temporary directory
+// with hash table of pool/token, which has specified size.
+func newBenchmarkFilesystem(b *testing.B, size int) *filesystem {
+	backend := newTestFilesystem(b)
+
+	table := []string{}
+	for i := 0; i < size; i++ {
+		table = append(table, fmt.Sprintf("$5$salt%06d$hash", i))
+	}
+
+	err := backend.SetHashTable("pool/token", table)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	return backend
+}
+
+func BenchmarkCachingBackendGetHash(b *testing.B) {
+	const size = 1000
+
+	for _, test := range []struct {
+		name    string
+		backend func(*filesystem) Backend
+	}{
+		{
+			"uncached",
+			func(backend *filesystem) Backend { return backend },
+		},
+		{
+			"cached",
+			func(backend *filesystem) Backend {
+				return newCachingBackend(backend, time.Hour, size, nil)
+			},
+		},
+		{
+			"evicted",
+			func(backend *filesystem) Backend {
+				return newCachingBackend(backend, time.Hour, size/10, nil)
+			},
+		},
+	} {
+		b.Run(test.name, func(b *testing.B) {
+			backend := test.backend(newBenchmarkFilesystem(b, size))
+
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				_, err := backend.GetHash("pool/token", int64(i%size))
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
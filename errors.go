@@ -0,0 +1,65 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/reconquest/hierr-go"
+)
+
+// Exit codes of shadowd commands, scripts can rely on them.
+const (
+	ExitSuccess  = 0
+	ExitFailure  = 1
+	ExitUsage    = 2
+	ExitNotFound = 3
+	ExitBackend  = 4
+)
+
+var ErrNotFound = errors.New("not found")
+
+var errTableExhausted = errors.New("all hash table entries are reserved")
+
+var errTableExpired = errors.New("all hash table entries have expired")
+
+var errNextExhausted = errors.New(
+	"client has exceeded amount of alternate hash entries",
+)
+
+// usageError is returned when specified arguments or configuration can't be
+// used.
+type usageError struct {
+	error
+}
+
+// backendError is returned when backend can't store or read requested data.
+type backendError struct {
+	error
+}
+
+func getExitCode(err error) int {
+	for err != nil {
+		switch typed := err.(type) {
+		case usageError:
+			return ExitUsage
+
+		case backendError:
+			return ExitBackend
+
+		case hierr.Error:
+			err, _ = typed.Nested.(error)
+			continue
+
+		case *hierr.Error:
+			err, _ = typed.Nested.(error)
+			continue
+		}
+
+		if err == ErrNotFound {
+			return ExitNotFound
+		}
+
+		return ExitFailure
+	}
+
+	return ExitFailure
+}
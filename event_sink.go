@@ -0,0 +1,304 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/reconquest/hierr-go"
+)
+
+const (
+	// eventSinkTimeout is time during which event sink should accept
+	// event.
+	eventSinkTimeout = 10 * time.Second
+
+	// natsDefaultSubject is subject, which events are published to when
+	// NATS URL doesn't specify it.
+	natsDefaultSubject = "shadowd.events"
+
+	eventTypeGeneration = "generation"
+	eventTypeServe      = "serve"
+)
+
+// EventSink delivers events about generated hash tables and served entries
+// to external systems. Events never contain passwords or records.
+type EventSink interface {
+	Publish(kind string, event interface{}) error
+	Close() error
+}
+
+// sinkEnvelope is published for every event, so consumers can distinguish
+// events of different types in the same stream.
+type sinkEnvelope struct {
+	Type  string      `json:"type"`
+	Event interface{} `json:"event"`
+}
+
+func marshalSinkEvent(kind string, event interface{}) ([]byte, error) {
+	return json.Marshal(sinkEnvelope{Type: kind, Event: event})
+}
+
+// newEventSink returns sink for specified URL: http and https URLs receive
+// events as POST requests, nats URLs publish events to subject specified by
+// path and file URLs append events to file as JSON lines.
+func newEventSink(target string) (EventSink, error) {
+	parsed, err := url.Parse(target)
+	if err != nil {
+		return nil, hierr.Errorf(err, "can't parse event sink URL")
+	}
+
+	switch parsed.Scheme {
+	case "http", "https":
+		return &httpEventSink{
+			url:    target,
+			client: &http.Client{Timeout: eventSinkTimeout},
+		}, nil
+
+	case "nats":
+		subject := strings.Trim(parsed.Path, "/")
+		if subject == "" {
+			subject = natsDefaultSubject
+		}
+
+		return &natsEventSink{
+			address: parsed.Host,
+			subject: subject,
+			lock:    &sync.Mutex{},
+		}, nil
+
+	case "file":
+		return &fileEventSink{path: parsed.Path, lock: &sync.Mutex{}}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported event sink scheme: %s", parsed.Scheme)
+}
+
+type httpEventSink struct {
+	url    string
+	client *http.Client
+}
+
+func (sink *httpEventSink) Publish(kind string, event interface{}) error {
+	body, err := marshalSinkEvent(kind, event)
+	if err != nil {
+		return err
+	}
+
+	response, err := sink.client.Post(
+		sink.url, "application/json", bytes.NewReader(body),
+	)
+	if err != nil {
+		return hierr.Errorf(err, "can't send request to %s", sink.url)
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("%s responded with %s", sink.url, response.Status)
+	}
+
+	return nil
+}
+
+func (sink *httpEventSink) Close() error {
+	return nil
+}
+
+// natsEventSink publishes events using NATS text protocol, connection is
+// established on first event and re-established after failure.
+type natsEventSink struct {
+	address string
+	subject string
+
+	conn net.Conn
+	lock *sync.Mutex
+}
+
+func (sink *natsEventSink) Publish(kind string, event interface{}) error {
+	body, err := marshalSinkEvent(kind, event)
+	if err != nil {
+		return err
+	}
+
+	sink.lock.Lock()
+	defer sink.lock.Unlock()
+
+	if sink.conn == nil {
+		err = sink.connect()
+		if err != nil {
+			return hierr.Errorf(err, "can't connect to NATS %s", sink.address)
+		}
+	}
+
+	err = sink.write(
+		"PUB %s %d\r\n%s\r\n", sink.subject, len(body), body,
+	)
+	if err != nil {
+		sink.conn.Close()
+		sink.conn = nil
+
+		return hierr.Errorf(err, "can't publish event to NATS")
+	}
+
+	return nil
+}
+
+func (sink *natsEventSink) connect() error {
+	conn, err := net.DialTimeout("tcp", sink.address, eventSinkTimeout)
+	if err != nil {
+		return err
+	}
+
+	reader := bufio.NewReader(conn)
+
+	conn.SetReadDeadline(time.Now().Add(eventSinkTimeout))
+
+	info, err := reader.ReadString('\n')
+	if err != nil || !strings.HasPrefix(info, "INFO ") {
+		conn.Close()
+		return fmt.Errorf("server has not sent INFO: %q", info)
+	}
+
+	conn.SetReadDeadline(time.Time{})
+
+	sink.conn = conn
+
+	err = sink.write("CONNECT {\"verbose\":false,\"pedantic\":false}\r\n")
+	if err != nil {
+		conn.Close()
+		sink.conn = nil
+		return err
+	}
+
+	go sink.keepalive(conn, reader)
+
+	return nil
+}
+
+// keepalive answers pings of NATS server, so connection is not closed by
+// server while there are no events.
+func (sink *natsEventSink) keepalive(conn net.Conn, reader *bufio.Reader) {
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+
+		if strings.HasPrefix(line, "-ERR") {
+			log.Printf("NATS event sink error: %s", strings.TrimSpace(line))
+			continue
+		}
+
+		if !strings.HasPrefix(line, "PING") {
+			continue
+		}
+
+		sink.lock.Lock()
+		if sink.conn == conn {
+			sink.write("PONG\r\n")
+		}
+		sink.lock.Unlock()
+	}
+}
+
+func (sink *natsEventSink) write(format string, values ...interface{}) error {
+	sink.conn.SetWriteDeadline(time.Now().Add(eventSinkTimeout))
+
+	_, err := fmt.Fprintf(sink.conn, format, values...)
+
+	return err
+}
+
+func (sink *natsEventSink) Close() error {
+	sink.lock.Lock()
+	defer sink.lock.Unlock()
+
+	if sink.conn == nil {
+		return nil
+	}
+
+	err := sink.conn.Close()
+	sink.conn = nil
+
+	return err
+}
+
+// fileEventSink appends events to file as JSON lines, it's useful for log
+// shippers and for testing.
+type fileEventSink struct {
+	path string
+	lock *sync.Mutex
+}
+
+func (sink *fileEventSink) Publish(kind string, event interface{}) error {
+	body, err := marshalSinkEvent(kind, event)
+	if err != nil {
+		return err
+	}
+
+	sink.lock.Lock()
+	defer sink.lock.Unlock()
+
+	file, err := os.OpenFile(
+		sink.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600,
+	)
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	_, err = file.Write(append(body, '\n'))
+
+	return err
+}
+
+func (sink *fileEventSink) Close() error {
+	return nil
+}
+
+// serveEvent is issuance event published to event sink, index of served
+// entry is published only if exposing of index is enabled.
+type serveEvent struct {
+	Token  string    `json:"token"`
+	Client string    `json:"client"`
+	Index  *int64    `json:"index,omitempty"`
+	Time   time.Time `json:"time"`
+}
+
+// forwardIssuanceEvents publishes served entries to specified sink until
+// events are closed, events are dropped if sink can't keep up.
+func forwardIssuanceEvents(
+	events *issuanceEvents, sink EventSink, exposeIndex bool,
+) {
+	subscriber := events.subscribe()
+	defer events.unsubscribe(subscriber)
+
+	for event := range subscriber {
+		published := serveEvent{
+			Token:  event.Token,
+			Client: event.Client,
+			Time:   event.Time,
+		}
+
+		if exposeIndex {
+			index := event.Index
+			published.Index = &index
+		}
+
+		err := sink.Publish(eventTypeServe, published)
+		if err != nil {
+			log.Println(hierr.Errorf(err, "can't publish serve event"))
+		}
+	}
+}
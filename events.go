@@ -0,0 +1,84 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// issuanceEventsBufferSize is amount of events buffered for every
+// subscriber, events are dropped for subscribers which can't keep up.
+const issuanceEventsBufferSize = 100
+
+// issuanceEvent describes hash entry served to client.
+type issuanceEvent struct {
+	Token  string    `json:"token"`
+	Client string    `json:"client"`
+	Index  int64     `json:"index"`
+	Time   time.Time `json:"time"`
+}
+
+// issuanceEvents delivers issuance events to all subscribers, events are
+// kept only in memory.
+type issuanceEvents struct {
+	subscribers map[chan issuanceEvent]struct{}
+	closed      bool
+	lock        *sync.Mutex
+}
+
+func newIssuanceEvents() *issuanceEvents {
+	return &issuanceEvents{
+		subscribers: map[chan issuanceEvent]struct{}{},
+		lock:        &sync.Mutex{},
+	}
+}
+
+func (events *issuanceEvents) subscribe() chan issuanceEvent {
+	subscriber := make(chan issuanceEvent, issuanceEventsBufferSize)
+
+	events.lock.Lock()
+	defer events.lock.Unlock()
+
+	if events.closed {
+		close(subscriber)
+		return subscriber
+	}
+
+	events.subscribers[subscriber] = struct{}{}
+
+	return subscriber
+}
+
+func (events *issuanceEvents) unsubscribe(subscriber chan issuanceEvent) {
+	events.lock.Lock()
+	defer events.lock.Unlock()
+
+	delete(events.subscribers, subscriber)
+}
+
+// publish sends event to all subscribers without blocking, so slow
+// subscribers do not slow down serving of hashes.
+func (events *issuanceEvents) publish(event issuanceEvent) {
+	events.lock.Lock()
+	defer events.lock.Unlock()
+
+	for subscriber := range events.subscribers {
+		select {
+		case subscriber <- event:
+		default:
+		}
+	}
+}
+
+// close closes channels of all subscribers, so streaming requests are
+// finished and server can be shut down.
+func (events *issuanceEvents) close() {
+	events.lock.Lock()
+	defer events.lock.Unlock()
+
+	for subscriber := range events.subscribers {
+		close(subscriber)
+		delete(events.subscribers, subscriber)
+	}
+
+	events.closed = true
+}
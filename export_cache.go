@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/reconquest/hierr-go"
+)
+
+// exportedBundle is compressed bundle of hash table, which has been created
+// at specified time.
+type exportedBundle struct {
+	created    time.Time
+	compressed []byte
+	etag       string
+}
+
+// exportCache keeps compressed bundle of the last exported hash table of
+// every token, so repeated and resumed downloads don't read and compress
+// whole hash table again. Bundle is rebuilt when hash table is replaced,
+// which changes its creation time.
+type exportCache struct {
+	bundles map[string]*exportedBundle
+	lock    *sync.Mutex
+}
+
+func newExportCache() *exportCache {
+	return &exportCache{
+		bundles: map[string]*exportedBundle{},
+		lock:    &sync.Mutex{},
+	}
+}
+
+func (cache *exportCache) get(
+	token string, created time.Time,
+) (*exportedBundle, bool) {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	bundle, ok := cache.bundles[token]
+	if !ok || !bundle.created.Equal(created) {
+		return nil, false
+	}
+
+	return bundle, true
+}
+
+func (cache *exportCache) put(token string, bundle *exportedBundle) {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	cache.bundles[token] = bundle
+}
+
+// exportTableBundle reads hash table of token and compresses it. Bundle is
+// compressed in advance, so interrupted downloads can be resumed using range
+// requests, gzip output doesn't include time and is the same for the same
+// records.
+func exportTableBundle(
+	backend Backend, token string, created time.Time,
+) (*exportedBundle, error) {
+	bundle, err := getTableBundle(backend, token)
+	if err != nil {
+		return nil, err
+	}
+
+	var compressed bytes.Buffer
+
+	compressor := gzip.NewWriter(&compressed)
+
+	err = json.NewEncoder(compressor).Encode(bundle)
+	if err == nil {
+		err = compressor.Close()
+	}
+	if err != nil {
+		return nil, hierr.Errorf(err, "can't compress hash table")
+	}
+
+	checksum := sha256.Sum256(compressed.Bytes())
+
+	return &exportedBundle{
+		created:    created,
+		compressed: compressed.Bytes(),
+		etag:       `"` + hex.EncodeToString(checksum[:16]) + `"`,
+	}, nil
+}
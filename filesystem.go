@@ -1,10 +1,14 @@
 package main
 
 import (
+	"bufio"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"regexp"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -16,8 +20,18 @@ type filesystem struct {
 	hashTablesDir string
 	hashTTL       time.Duration
 	sshKeysDir    string
+	metaDir       string
 	clients       map[string]time.Time
 	clientsLock   *sync.Mutex
+
+	// clientRequests contains amount of repeated requests of recent clients.
+	clientRequests map[string]int
+
+	reservations     map[string]map[int64]bool
+	reservationsLock *sync.Mutex
+
+	// servedLock serializes updates of served counters in metadata files.
+	servedLock *sync.Mutex
 }
 
 func (fs *filesystem) Init() error {
@@ -33,42 +47,187 @@ func (fs *filesystem) Init() error {
 			stat.Mode())
 	}
 
+	return nil
+}
+
+func (fs *filesystem) SetHashTable(token string, table []string) error {
+	return fs.SwapHashTable(token, table)
+}
+
+// SwapHashTable writes hash table to temporary file and renames it over
+// existing one, so readers open either old or new file, never partial one.
+func (fs *filesystem) SwapHashTable(token string, table []string) error {
+	records := make(chan string)
+
 	go func() {
-		for range time.Tick(time.Minute) {
-			fs.cleanupRecentClients()
+		defer close(records)
+
+		for _, record := range table {
+			records <- record
 		}
 	}()
 
-	return nil
+	return fs.SetHashTableStream(token, len(table), records)
 }
 
-func (fs *filesystem) SetHashTable(token string, table []string) error {
+// temporaryTablePattern matches names of temporary files, which hash tables
+// are written to before they are moved in place, such files can be left
+// after interrupted generation and are not tokens.
+var temporaryTablePattern = regexp.MustCompile(`^\..+\.tmp[0-9]+$`)
+
+func (fs *filesystem) SetHashTableStream(
+	token string, size int, records <-chan string,
+) error {
+	defer drainRecords(records)
+
 	path := filepath.Join(fs.hashTablesDir, token)
 
 	dir := filepath.Dir(path)
-	if _, err := os.Stat(dir); os.IsNotExist(err) {
-		err = os.MkdirAll(dir, 0700)
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return hierr.Errorf(
+			err, "can't create directory %s", dir,
+		)
+	}
+
+	// table is written to temporary file, so existing table is served until
+	// new one is complete
+	file, err := ioutil.TempFile(dir, "."+filepath.Base(path)+".tmp")
+	if err != nil {
+		return hierr.Errorf(
+			err, "can't create temporary file in %s", dir,
+		)
+	}
+
+	defer os.Remove(file.Name())
+
+	err = writeHashTableStream(file, size, records)
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	err = file.Close()
+	if err != nil {
+		return hierr.Errorf(
+			err, "can't write file %s", file.Name(),
+		)
+	}
+
+	err = os.Rename(file.Name(), path)
+	if err != nil {
+		return hierr.Errorf(
+			err, "can't move %s to %s", file.Name(), path,
+		)
+	}
+
+	fs.reservationsLock.Lock()
+	delete(fs.reservations, token)
+	fs.reservationsLock.Unlock()
+
+	return fs.updateInfo(token, func(info *tokenInfo) {
+		now := time.Now()
+
+		info.Served = 0
+		info.Parameters = nil
+		info.Created = &now
+		info.Modified = &now
+	})
+}
+
+func writeHashTableStream(
+	file *os.File, size int, records <-chan string,
+) error {
+	writer := bufio.NewWriter(file)
+
+	count := 0
+	length := -1
+	for record := range records {
+		// records are read by offset, so all records should have the same
+		// length
+		if length == -1 {
+			length = len(record)
+		}
+
+		if len(record) != length {
+			return fmt.Errorf(
+				"all records should have the same length, "+
+					"but found records with length %d and %d",
+				length, len(record),
+			)
+		}
+
+		_, err := writer.WriteString(record + "\n")
 		if err != nil {
 			return hierr.Errorf(
-				err, "can't create directory %s", dir,
+				err, "can't write file %s", file.Name(),
 			)
 		}
+
+		count++
 	}
 
-	err := ioutil.WriteFile(
-		path,
-		[]byte(strings.Join(table, "\n")+"\n"),
-		0600,
-	)
+	if count != size {
+		return fmt.Errorf(
+			"hash table stream ended after %d of %d records", count, size,
+		)
+	}
+
+	err := writer.Flush()
 	if err != nil {
 		return hierr.Errorf(
-			err, "can't write file %s", path,
+			err, "can't write file %s", file.Name(),
 		)
 	}
 
 	return nil
 }
 
+func (fs *filesystem) SetHashTableWithExpiry(
+	token string, table []string, expiries []time.Time,
+) error {
+	if len(expiries) != len(table) {
+		return fmt.Errorf(
+			"amount of expiries (%d) doesn't match table size (%d)",
+			len(expiries), len(table),
+		)
+	}
+
+	// expiry follows record on the same line, records have the same length
+	// and expiries are formatted in UTC, so lines have the same length too
+	lines := make([]string, len(table))
+	for number, record := range table {
+		lines[number] = record + " " +
+			expiries[number].UTC().Format(time.RFC3339)
+	}
+
+	return fs.SwapHashTable(token, lines)
+}
+
+func (fs *filesystem) AddServed(token string, delta int64) error {
+	return fs.updateInfo(token, func(info *tokenInfo) {
+		info.Served += delta
+	})
+}
+
+// updateInfo changes metadata of token, updates are serialized, so
+// concurrent updates of served counter are not lost.
+func (fs *filesystem) updateInfo(
+	token string, update func(info *tokenInfo),
+) error {
+	fs.servedLock.Lock()
+	defer fs.servedLock.Unlock()
+
+	info, err := fs.GetTokenInfo(token)
+	if err != nil {
+		return err
+	}
+
+	update(info)
+
+	return fs.SetTokenInfo(token, info)
+}
+
 func (fs *filesystem) AddPublicKey(
 	token string, key []byte, truncate bool,
 ) error {
@@ -128,6 +287,32 @@ func (fs *filesystem) GetPublicKeys(token string) (string, error) {
 	return string(data), nil
 }
 
+func (fs *filesystem) DeleteHashTable(token string) error {
+	path := filepath.Join(fs.hashTablesDir, token)
+
+	err := os.Remove(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return ErrNotFound
+		}
+
+		return hierr.Errorf(err, "can't remove file %s", path)
+	}
+
+	meta := filepath.Join(fs.metaDir, token)
+
+	err = os.Remove(meta)
+	if err != nil && !os.IsNotExist(err) {
+		return hierr.Errorf(err, "can't remove metadata file %s", meta)
+	}
+
+	fs.reservationsLock.Lock()
+	delete(fs.reservations, token)
+	fs.reservationsLock.Unlock()
+
+	return nil
+}
+
 func (fs *filesystem) IsHashExists(token string, hash string) (bool, error) {
 	table, err := openHashTable(filepath.Join(fs.hashTablesDir, token))
 	if err != nil {
@@ -140,6 +325,10 @@ func (fs *filesystem) IsHashExists(token string, hash string) (bool, error) {
 func (fs *filesystem) GetTableSize(token string) (int64, error) {
 	table, err := openHashTable(filepath.Join(fs.hashTablesDir, token))
 	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, ErrNotFound
+		}
+
 		return 0, err
 	}
 
@@ -172,18 +361,244 @@ func (fs *filesystem) AddRecentClient(identifier string) error {
 	return nil
 }
 
+func (fs *filesystem) AddRecentClientRequest(identifier string) (int, error) {
+	fs.clientsLock.Lock()
+	defer fs.clientsLock.Unlock()
+
+	if fs.clientRequests == nil {
+		fs.clientRequests = map[string]int{}
+	}
+
+	fs.clientRequests[identifier]++
+
+	return fs.clientRequests[identifier], nil
+}
+
+func (fs *filesystem) DeleteRecentClient(remote string) error {
+	fs.clientsLock.Lock()
+	defer fs.clientsLock.Unlock()
+
+	deleted := 0
+	for identifier := range fs.clients {
+		if strings.HasPrefix(identifier, remote+"-") {
+			delete(fs.clients, identifier)
+			delete(fs.clientRequests, identifier)
+			deleted++
+		}
+	}
+
+	if deleted == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (fs *filesystem) FlushRecentClients(token string) (int, error) {
+	fs.clientsLock.Lock()
+	defer fs.clientsLock.Unlock()
+
+	flushed := 0
+	for identifier := range fs.clients {
+		if token == "" || strings.HasSuffix(identifier, "-"+token) {
+			delete(fs.clients, identifier)
+			delete(fs.clientRequests, identifier)
+			flushed++
+		}
+	}
+
+	return flushed, nil
+}
+
+func (fs *filesystem) GetRecentClientsCount(token string) (int, error) {
+	fs.clientsLock.Lock()
+	defer fs.clientsLock.Unlock()
+
+	count := 0
+	for identifier := range fs.clients {
+		if strings.HasSuffix(identifier, "-"+token) {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+func (fs *filesystem) ListRecentClients(token string) ([]RecentClient, error) {
+	fs.clientsLock.Lock()
+	defer fs.clientsLock.Unlock()
+
+	clients := []RecentClient{}
+	for identifier, lastSeen := range fs.clients {
+		if !strings.HasSuffix(identifier, "-"+token) {
+			continue
+		}
+
+		clients = append(clients, RecentClient{
+			Client:   strings.TrimSuffix(identifier, "-"+token),
+			LastSeen: lastSeen,
+		})
+	}
+
+	sort.Slice(clients, func(i, j int) bool {
+		return clients[i].Client < clients[j].Client
+	})
+
+	return clients, nil
+}
+
 func (fs *filesystem) GetHash(token string, number int64) (string, error) {
+	record, _, err := fs.GetHashWithExpiry(token, number)
+
+	return record, err
+}
+
+func (fs *filesystem) GetHashWithExpiry(
+	token string, number int64,
+) (string, time.Time, error) {
+	table, err := openHashTable(filepath.Join(fs.hashTablesDir, token))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	defer table.file.Close()
+
+	line, err := table.getRecord(number)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return parseTableLine(string(line))
+}
+
+func (fs *filesystem) GetHashesWithExpiry(
+	token string, number int64, count int64,
+) ([]string, []time.Time, error) {
+	table, err := openHashTable(filepath.Join(fs.hashTablesDir, token))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	defer table.file.Close()
+
+	tableSize, err := table.getSize()
+	if err != nil {
+		return nil, nil, hierr.Errorf(err, "can't get table size")
+	}
+
+	records := []string{}
+	expiries := []time.Time{}
+	for index := number; index < number+count && index < tableSize; index++ {
+		line, err := table.getRecord(index)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		record, expiry, err := parseTableLine(string(line))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		records = append(records, record)
+		expiries = append(expiries, expiry)
+	}
+
+	return records, expiries, nil
+}
+
+func (fs *filesystem) GetHashTable(token string) ([]string, error) {
 	table, err := openHashTable(filepath.Join(fs.hashTablesDir, token))
 	if err != nil {
-		return "", err
+		if os.IsNotExist(err) {
+			return nil, ErrNotFound
+		}
+
+		return nil, err
+	}
+
+	defer table.file.Close()
+
+	return table.getRecords()
+}
+
+// ReserveIndex marks specified hash table entry as served, reservations are
+// kept in memory and reset when hash table is regenerated by the same process.
+func (fs *filesystem) ReserveIndex(token string, index int64) (bool, error) {
+	fs.reservationsLock.Lock()
+	defer fs.reservationsLock.Unlock()
+
+	if fs.reservations == nil {
+		fs.reservations = map[string]map[int64]bool{}
+	}
+
+	if fs.reservations[token] == nil {
+		fs.reservations[token] = map[int64]bool{}
+	}
+
+	if fs.reservations[token][index] {
+		return false, nil
+	}
+
+	fs.reservations[token][index] = true
+
+	return true, nil
+}
+
+func (fs *filesystem) GetAllTokens() ([]string, error) {
+	tokens := []string{}
+	err := filepath.Walk(
+		fs.hashTablesDir,
+		func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if info.IsDir() || temporaryTablePattern.MatchString(info.Name()) {
+				return nil
+			}
+
+			token, err := filepath.Rel(fs.hashTablesDir, path)
+			if err != nil {
+				return err
+			}
+
+			tokens = append(tokens, filepath.ToSlash(token))
+
+			return nil
+		},
+	)
+	if err != nil {
+		return nil, hierr.Errorf(
+			err, "can't walk directory %s", fs.hashTablesDir,
+		)
 	}
 
-	record, err := table.getRecord(number)
+	return tokens, nil
+}
+
+func (fs *filesystem) GetTokensModifiedSince(
+	prefix string, since time.Time,
+) ([]string, error) {
+	// only metadata of tokens under prefix is read, because listing of
+	// prefix shows only them
+	tokens, err := fs.GetTokens(prefix)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+
+	modified := []string{}
+	for _, token := range tokens {
+		info, err := fs.GetTokenInfo(prefix + token)
+		if err != nil {
+			return nil, err
+		}
+
+		if info.Modified != nil && info.Modified.After(since) {
+			modified = append(modified, token)
+		}
 	}
 
-	return string(record), nil
+	return modified, nil
 }
 
 func (fs *filesystem) GetTokens(prefix string) ([]string, error) {
@@ -221,6 +636,10 @@ func (fs *filesystem) GetTokens(prefix string) ([]string, error) {
 				return filepath.SkipDir
 			}
 
+			if temporaryTablePattern.MatchString(info.Name()) {
+				return nil
+			}
+
 			tokens = append(
 				tokens,
 				strings.TrimPrefix(strings.TrimPrefix(path, directory), "/"),
@@ -236,7 +655,63 @@ func (fs *filesystem) GetTokens(prefix string) ([]string, error) {
 	return tokens, nil
 }
 
-func (fs *filesystem) cleanupRecentClients() {
+func (fs *filesystem) GetTokenInfo(token string) (*tokenInfo, error) {
+	path := filepath.Join(fs.metaDir, token)
+
+	info := &tokenInfo{}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return info, nil
+		}
+
+		return nil, hierr.Errorf(
+			err, "can't read metadata file %s", path,
+		)
+	}
+
+	err = json.Unmarshal(data, info)
+	if err != nil {
+		return nil, hierr.Errorf(
+			err, "can't decode metadata file %s", path,
+		)
+	}
+
+	return info, nil
+}
+
+func (fs *filesystem) SetTokenInfo(token string, info *tokenInfo) error {
+	path := filepath.Join(fs.metaDir, token)
+
+	dir := filepath.Dir(path)
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		err = os.MkdirAll(dir, 0700)
+		if err != nil {
+			return hierr.Errorf(
+				err, "can't create directory %s", dir,
+			)
+		}
+	}
+
+	data, err := json.Marshal(info)
+	if err != nil {
+		return hierr.Errorf(
+			err, "can't encode metadata",
+		)
+	}
+
+	err = ioutil.WriteFile(path, data, 0600)
+	if err != nil {
+		return hierr.Errorf(
+			err, "can't write file %s", path,
+		)
+	}
+
+	return nil
+}
+
+func (fs *filesystem) PruneRecentClients() (int, error) {
 	fs.clientsLock.Lock()
 	defer fs.clientsLock.Unlock()
 
@@ -250,5 +725,29 @@ func (fs *filesystem) cleanupRecentClients() {
 		actual[identifier] = requestTime
 	}
 
+	pruned := len(fs.clients) - len(actual)
+
 	fs.clients = actual
+
+	for identifier := range fs.clientRequests {
+		if _, ok := actual[identifier]; !ok {
+			delete(fs.clientRequests, identifier)
+		}
+	}
+
+	return pruned, nil
+}
+
+func (fs *filesystem) CountExpiredRecentClients() (int, error) {
+	fs.clientsLock.Lock()
+	defer fs.clientsLock.Unlock()
+
+	expired := 0
+	for _, requestTime := range fs.clients {
+		if time.Now().Sub(requestTime) > fs.hashTTL {
+			expired++
+		}
+	}
+
+	return expired, nil
 }
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// assertTokens checks that only pool/token is listed by filesystem backend.
+func assertTokens(t *testing.T, backend *filesystem) {
+	t.Helper()
+
+	tokens, err := backend.GetTokens("pool/")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(tokens, []string{"token"}) {
+		t.Fatalf("unexpected tokens %v", tokens)
+	}
+
+	tokens, err = backend.GetAllTokens()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(tokens, []string{"pool/token"}) {
+		t.Fatalf("unexpected tokens of all namespaces %v", tokens)
+	}
+}
+
+func TestTemporaryTablesAreNotListed(t *testing.T) {
+	backend := newTestFilesystem(t)
+
+	err := backend.SetHashTable("pool/token", []string{"$5$salt$hash"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	records := make(chan string)
+	generated := make(chan error)
+	go func() {
+		generated <- backend.SetHashTableStream("pool/token", 2, records)
+	}()
+
+	records <- "$5$salt$next"
+
+	// temporary file is created before records are received
+	dir := filepath.Join(backend.hashTablesDir, "pool")
+	for {
+		files, err := ioutil.ReadDir(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(files) > 1 {
+			break
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	assertTokens(t, backend)
+
+	// generation is interrupted before all records are received
+	close(records)
+
+	err = <-generated
+	if err == nil {
+		t.Fatal("incomplete hash table is stored")
+	}
+
+	assertTokens(t, backend)
+
+	// process, which has been killed during generation, leaves temporary
+	// file behind
+	err = ioutil.WriteFile(
+		filepath.Join(dir, ".token.tmp123456"), []byte("$5$salt$hash\n"),
+		0600,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertTokens(t, backend)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/user"
+	"time"
+
+	"github.com/reconquest/hierr-go"
+)
+
+// generationWebhookTimeout is time during which generation webhook should
+// respond.
+const generationWebhookTimeout = 10 * time.Second
+
+// generationEvent is sent to generation webhook after hash table has been
+// generated, records are never sent.
+type generationEvent struct {
+	Token     string    `json:"token"`
+	Length    int       `json:"length"`
+	Algorithm string    `json:"algorithm"`
+	Operator  string    `json:"operator"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// notifyGeneration posts specified event as JSON to specified URL.
+func notifyGeneration(url string, event generationEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	client := &http.Client{Timeout: generationWebhookTimeout}
+
+	response, err := client.Post(
+		url, "application/json", bytes.NewReader(body),
+	)
+	if err != nil {
+		return hierr.Errorf(err, "can't send request to %s", url)
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("%s responded with %s", url, response.Status)
+	}
+
+	return nil
+}
+
+// getOperator returns name of user, which runs shadowd, user which has
+// invoked sudo is preferred.
+func getOperator() string {
+	if operator := os.Getenv("SUDO_USER"); operator != "" {
+		return operator
+	}
+
+	current, err := user.Current()
+	if err != nil {
+		return os.Getenv("USER")
+	}
+
+	return current.Username
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/reconquest/hierr-go"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// grpcService serves hash tables over gRPC. Records and tokens are requested
+// from the same handlers as over HTTP, so authorization, served tokens,
+// limits, recent clients and metrics apply to both protocols.
+type grpcService struct {
+	UnimplementedShadowdServer
+
+	server  *Server
+	handler http.Handler
+}
+
+// startGRPC starts serving gRPC service on specified TCP address in
+// background.
+func startGRPC(
+	address string,
+	wood *Server,
+	tlsConfig *tls.Config,
+	proxyProtocol bool,
+) (*grpc.Server, error) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, hierr.Errorf(err, "can't listen %s for gRPC", address)
+	}
+
+	if proxyProtocol {
+		listener = proxyListener{listener}
+	}
+
+	server := grpc.NewServer(
+		grpc.Creds(credentials.NewTLS(getGRPCTLSConfig(tlsConfig))),
+	)
+
+	RegisterShadowdServer(
+		server, &grpcService{server: wood, handler: wood.getMux()},
+	)
+
+	go func() {
+		log.Println("starting gRPC listening on", address)
+
+		err := server.Serve(listener)
+		if err != nil {
+			log.Println(hierr.Errorf(err, "can't serve gRPC"))
+		}
+	}()
+
+	return server, nil
+}
+
+// getGRPCTLSConfig returns config, which takes server config on every
+// handshake, because gRPC credentials use copy of config and session ticket
+// keys rotation would not be applied to it.
+func getGRPCTLSConfig(config *tls.Config) *tls.Config {
+	return &tls.Config{
+		GetConfigForClient: func(
+			hello *tls.ClientHelloInfo,
+		) (*tls.Config, error) {
+			if config.GetConfigForClient != nil {
+				return config.GetConfigForClient(hello)
+			}
+
+			return config, nil
+		},
+	}
+}
+
+func (service *grpcService) GetRecord(
+	ctx context.Context, request *GetRecordRequest,
+) (*GetRecordResponse, error) {
+	// token is taken from certificate when it's not specified
+	if isTokenPrefix(request.Token) &&
+		(request.Token != "" || !service.server.tokenFromCert) {
+		return nil, status.Error(codes.InvalidArgument, "token is required")
+	}
+
+	httpRequest, err := newGRPCHTTPRequest(ctx, "/t/"+request.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	if request.ClientId != "" {
+		if !isAdmin(httpRequest) {
+			return nil, status.Error(
+				codes.PermissionDenied,
+				"client_id is accepted only from admins",
+			)
+		}
+
+		httpRequest.RemoteAddr = net.JoinHostPort(request.ClientId, "0")
+	}
+
+	response := httptest.NewRecorder()
+
+	service.handler.ServeHTTP(response, httpRequest)
+
+	if response.Code != http.StatusOK {
+		return nil, getGRPCError(response.Code)
+	}
+
+	return &GetRecordResponse{Record: response.Body.String()}, nil
+}
+
+func (service *grpcService) ListTokens(
+	ctx context.Context, request *ListTokensRequest,
+) (*ListTokensResponse, error) {
+	// the only token of client is taken from certificate, so there is
+	// nothing to list
+	if service.server.tokenFromCert {
+		return nil, status.Error(
+			codes.PermissionDenied, "tokens are taken from certificates",
+		)
+	}
+
+	prefix := request.Prefix
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+
+	httpRequest, err := newGRPCHTTPRequest(ctx, "/t/"+prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	response := httptest.NewRecorder()
+
+	service.handler.ServeHTTP(response, httpRequest)
+
+	switch response.Code {
+	case http.StatusOK:
+		return &ListTokensResponse{
+			Tokens: strings.Split(response.Body.String(), "\n"),
+		}, nil
+
+	case http.StatusNoContent:
+		return &ListTokensResponse{}, nil
+
+	default:
+		return nil, getGRPCError(response.Code)
+	}
+}
+
+// ValidateRecord checks record directly instead of passing it to /v/
+// handler, because records contain slashes, which can't be passed in path.
+func (service *grpcService) ValidateRecord(
+	ctx context.Context, request *ValidateRecordRequest,
+) (*ValidateRecordResponse, error) {
+	if request.Token == "" || request.Record == "" {
+		return nil, status.Error(
+			codes.InvalidArgument, "token and record are required",
+		)
+	}
+
+	err := validateTokenPath(request.Token)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	httpRequest, err := newGRPCHTTPRequest(ctx, "/v/"+request.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	if !service.server.isTokenAllowedByCertificate(httpRequest, request.Token) {
+		return nil, getGRPCError(http.StatusForbidden)
+	}
+
+	if !service.server.servedTokens.allows(request.Token) {
+		return nil, getGRPCError(http.StatusNotFound)
+	}
+
+	valid, err := service.server.isHashValid(request.Token, request.Record)
+	if err != nil {
+		log.Println(
+			hierr.Errorf(err, "can't validate record of %s", request.Token),
+		)
+
+		return nil, getGRPCError(http.StatusInternalServerError)
+	}
+
+	return &ValidateRecordResponse{Valid: valid}, nil
+}
+
+// newGRPCHTTPRequest creates HTTP request to specified path, which has
+// address and TLS state of gRPC client.
+func newGRPCHTTPRequest(
+	ctx context.Context, path string,
+) (*http.Request, error) {
+	request, err := http.NewRequestWithContext(ctx, "GET", "/", nil)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	request.URL.Path = path
+
+	client, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil, status.Error(codes.Internal, "unknown client address")
+	}
+
+	// client address is taken from address without port
+	request.RemoteAddr = client.Addr.String()
+	if _, _, err := net.SplitHostPort(request.RemoteAddr); err != nil {
+		request.RemoteAddr = net.JoinHostPort(request.RemoteAddr, "0")
+	}
+
+	if info, ok := client.AuthInfo.(credentials.TLSInfo); ok {
+		request.TLS = &info.State
+	}
+
+	return request, nil
+}
+
+// getGRPCError returns gRPC error corresponding to HTTP status code.
+func getGRPCError(code int) error {
+	var grpcCode codes.Code
+	switch code {
+	case http.StatusBadRequest, http.StatusMovedPermanently:
+		grpcCode = codes.InvalidArgument
+	case http.StatusForbidden:
+		grpcCode = codes.PermissionDenied
+	case http.StatusNotFound:
+		grpcCode = codes.NotFound
+	case http.StatusGone:
+		grpcCode = codes.FailedPrecondition
+	case http.StatusServiceUnavailable:
+		grpcCode = codes.Unavailable
+	default:
+		grpcCode = codes.Internal
+	}
+
+	return status.Error(grpcCode, http.StatusText(code))
+}
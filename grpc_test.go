@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/docopt/docopt-go"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// newGRPCTestService returns gRPC service backed by filesystem backend in
+// temporary directory with hash table of pool/token, service is configured by
+// specified listener flags.
+func newGRPCTestService(
+	t *testing.T, flags ...string,
+) (*grpcService, []string) {
+	backend := newTestFilesystem(t)
+
+	table := []string{}
+	for i := 0; i < 4; i++ {
+		record, err := generateSHA256("password")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		table = append(table, record)
+	}
+
+	err := backend.SetHashTable("pool/token", table)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	args, err := docopt.Parse(
+		replaceDefaults(usage), append([]string{"-L", "127.0.0.1:0"}, flags...),
+		true, "", false, false,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wood, err := newServer(backend, args, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &grpcService{server: wood, handler: wood.getMux()}, table
+}
+
+// newGRPCTestClient starts gRPC service returned by newGRPCTestService and
+// returns client connected to it.
+func newGRPCTestClient(t *testing.T) (ShadowdClient, []string) {
+	service, table := newGRPCTestService(t)
+
+	listener := bufconn.Listen(1 << 16)
+
+	server := grpc.NewServer()
+	RegisterShadowdServer(server, service)
+
+	go server.Serve(listener)
+
+	t.Cleanup(server.Stop)
+
+	connection, err := grpc.NewClient(
+		"passthrough:///bufconn",
+		grpc.WithContextDialer(
+			func(ctx context.Context, _ string) (net.Conn, error) {
+				return listener.DialContext(ctx)
+			},
+		),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		connection.Close()
+	})
+
+	return NewShadowdClient(connection), table
+}
+
+func TestGRPCGetRecord(t *testing.T) {
+	client, table := newGRPCTestClient(t)
+
+	response, err := client.GetRecord(
+		context.Background(), &GetRecordRequest{Token: "pool/token"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	found := false
+	for _, record := range table {
+		found = found || record == response.Record
+	}
+
+	if !found {
+		t.Fatalf("record %s is not from hash table", response.Record)
+	}
+
+	validation, err := client.ValidateRecord(
+		context.Background(),
+		&ValidateRecordRequest{Token: "pool/token", Record: response.Record},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !validation.Valid {
+		t.Fatalf("served record %s is not valid", response.Record)
+	}
+}
+
+func TestGRPCGetRecordErrors(t *testing.T) {
+	client, _ := newGRPCTestClient(t)
+
+	for _, test := range []struct {
+		request *GetRecordRequest
+		code    codes.Code
+	}{
+		{&GetRecordRequest{Token: "pool/unknown"}, codes.NotFound},
+		{&GetRecordRequest{Token: "pool/"}, codes.InvalidArgument},
+		{
+			&GetRecordRequest{Token: "pool/token", ClientId: "10.0.0.1"},
+			codes.PermissionDenied,
+		},
+	} {
+		_, err := client.GetRecord(context.Background(), test.request)
+		if status.Code(err) != test.code {
+			t.Fatalf("%v: expected %s, got %v", test.request, test.code, err)
+		}
+	}
+}
+
+func TestGRPCListTokens(t *testing.T) {
+	client, _ := newGRPCTestClient(t)
+
+	response, err := client.ListTokens(
+		context.Background(), &ListTokensRequest{Prefix: "pool"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(response.Tokens) != 1 || response.Tokens[0] != "token" {
+		t.Fatalf("unexpected tokens %v", response.Tokens)
+	}
+
+	_, err = client.ListTokens(
+		context.Background(), &ListTokensRequest{Prefix: "unknown"},
+	)
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("expected not found, got %v", err)
+	}
+}
@@ -0,0 +1,345 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/reconquest/hierr-go"
+)
+
+// HandleRecentClients returns recent clients of token as JSON on GET and
+// flushes them on DELETE, only admins are allowed to request it.
+func (server *Server) HandleRecentClients(
+	writer http.ResponseWriter, request *http.Request,
+) {
+	if !isAdmin(request) {
+		writer.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	token := strings.TrimPrefix(request.URL.Path, "/admin/recent/")
+
+	switch request.Method {
+	case "GET":
+	case "DELETE":
+		server.flushRecentClients(writer, token)
+		return
+
+	default:
+		writer.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	clients, err := server.backend.ListRecentClients(token)
+	if err != nil {
+		log.Println(
+			hierr.Errorf(err, "can't list recent clients of %s", token),
+		)
+		writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if server.redactTokens {
+		for i := range clients {
+			clients[i].Client = redact(clients[i].Client)
+		}
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+
+	err = json.NewEncoder(writer).Encode(clients)
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+// flushRecentClients removes recent clients of token or of all tokens if
+// token is empty, amount of removed clients is returned as JSON.
+func (server *Server) flushRecentClients(
+	writer http.ResponseWriter, token string,
+) {
+	flushed, err := server.backend.FlushRecentClients(token)
+	if err != nil {
+		log.Println(
+			hierr.Errorf(err, "can't flush recent clients of '%s'", token),
+		)
+		writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("%d recent clients of '%s' have been flushed", flushed, token)
+
+	server.replicator.publish(recentEvent{Type: recentEventFlush, Token: token})
+
+	writer.Header().Set("Content-Type", "application/json")
+
+	err = json.NewEncoder(writer).Encode(map[string]int{"flushed": flushed})
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+// HandleRecentClientDelete removes recent mark of client specified by remote
+// query parameter, so client will get fresh hash entry on next request.
+func (server *Server) HandleRecentClientDelete(
+	writer http.ResponseWriter, request *http.Request,
+) {
+	if !isAdmin(request) {
+		writer.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	if request.Method != "DELETE" {
+		writer.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	remote := request.URL.Query().Get("remote")
+	if remote == "" {
+		writer.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	err := server.backend.DeleteRecentClient(remote)
+	if err != nil {
+		if err == ErrNotFound {
+			writer.WriteHeader(http.StatusNotFound)
+		} else {
+			log.Println(
+				hierr.Errorf(err, "can't delete recent client %s", remote),
+			)
+			writer.WriteHeader(http.StatusInternalServerError)
+		}
+		return
+	}
+
+	log.Printf("recent client %s has been deleted", remote)
+
+	server.replicator.publish(
+		recentEvent{Type: recentEventDelete, Remote: remote},
+	)
+
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+// HandleRecentReplicate applies recent client event sent by primary
+// server, events are applied directly to backend, so they are never
+// replicated further.
+func (server *Server) HandleRecentReplicate(
+	writer http.ResponseWriter, request *http.Request,
+) {
+	if !isAdmin(request) {
+		writer.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	if request.Method != "POST" {
+		writer.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var event recentEvent
+
+	err := json.NewDecoder(request.Body).Decode(&event)
+	if err != nil || (event.Remote == "" && event.Type != recentEventFlush) {
+		writer.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	switch event.Type {
+	case recentEventAdd:
+		err = server.backend.AddRecentClient(event.Remote)
+
+	case recentEventRequest:
+		_, err = server.backend.AddRecentClientRequest(event.Remote)
+
+	case recentEventDelete:
+		// client may have already expired on standby
+		err = server.backend.DeleteRecentClient(event.Remote)
+		if err == ErrNotFound {
+			err = nil
+		}
+
+	case recentEventFlush:
+		_, err = server.backend.FlushRecentClients(event.Token)
+
+	default:
+		writer.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if err != nil {
+		log.Println(
+			hierr.Errorf(
+				err, "can't apply replicated recent client %s", event.Remote,
+			),
+		)
+		writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+// HandleTail streams issuance events as JSON lines while client is connected,
+// events can be filtered by token and client query parameters.
+func (server *Server) HandleTail(
+	writer http.ResponseWriter, request *http.Request,
+) {
+	if !isAdmin(request) {
+		writer.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	if request.Method != "GET" {
+		writer.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	flusher, ok := writer.(http.Flusher)
+	if !ok {
+		writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	var (
+		token  = request.URL.Query().Get("token")
+		client = request.URL.Query().Get("client")
+	)
+
+	events := server.events.subscribe()
+	defer server.events.unsubscribe(events)
+
+	writer.Header().Set("Content-Type", "application/x-ndjson")
+	writer.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	encoder := json.NewEncoder(writer)
+	for {
+		select {
+		case <-request.Context().Done():
+			return
+
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+
+			if token != "" && event.Token != token {
+				continue
+			}
+
+			if client != "" && event.Client != client {
+				continue
+			}
+
+			err := encoder.Encode(event)
+			if err != nil {
+				log.Println(err)
+				return
+			}
+
+			flusher.Flush()
+		}
+	}
+}
+
+// tableBundle is exported hash table of token.
+type tableBundle struct {
+	Token   string   `json:"token"`
+	Records []string `json:"records"`
+}
+
+// HandleExport streams full hash table of token as gzipped JSON bundle, only
+// admins are allowed to request it, because it exposes all hashes.
+func (server *Server) HandleExport(
+	writer http.ResponseWriter, request *http.Request, token string,
+) {
+	if !isAdmin(request) {
+		writer.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	if request.Method != "GET" {
+		writer.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if token == "" || strings.HasSuffix(token, "/") {
+		writer.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	// creation time is read before records, so bundle of replaced hash
+	// table is never cached as current one
+	info, err := server.backend.GetTokenInfo(token)
+	if err != nil {
+		log.Println(hierr.Errorf(err, "can't export hash table %s", token))
+		writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	var created time.Time
+	if info.Created != nil {
+		created = *info.Created
+	}
+
+	exported, ok := server.exports.get(token, created)
+	if !ok {
+		exported, err = exportTableBundle(server.backend, token, created)
+		if err != nil {
+			if err == ErrNotFound {
+				writer.WriteHeader(http.StatusNotFound)
+			} else {
+				log.Println(
+					hierr.Errorf(err, "can't export hash table %s", token),
+				)
+				writer.WriteHeader(http.StatusInternalServerError)
+			}
+			return
+		}
+
+		// hash tables without creation time can't be told apart
+		if !created.IsZero() {
+			server.exports.put(token, exported)
+		}
+	}
+
+	writer.Header().Set("Content-Type", "application/gzip")
+	writer.Header().Set(
+		"Content-Disposition",
+		`attachment; filename="`+strings.Replace(token, "/", "_", -1)+
+			`.json.gz"`,
+	)
+	writer.Header().Set("ETag", exported.etag)
+
+	http.ServeContent(
+		writer, request, "", time.Time{}, bytes.NewReader(exported.compressed),
+	)
+}
+
+func getTableBundle(backend Backend, token string) (*tableBundle, error) {
+	records, err := backend.GetHashTable(token)
+	if err != nil {
+		return nil, err
+	}
+
+	bundle := &tableBundle{
+		Token:   token,
+		Records: records,
+	}
+
+	return bundle, nil
+}
+
+// redact returns short hash of specified value, so values can be compared,
+// but not revealed.
+func redact(value string) string {
+	hash := sha256.Sum256([]byte(value))
+
+	return hex.EncodeToString(hash[:8])
+}
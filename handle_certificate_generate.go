@@ -17,6 +17,9 @@ import (
 	"github.com/reconquest/hierr-go"
 )
 
+// certDateLayout is layout of date, which certificates are valid till.
+const certDateLayout = "2006-01-02"
+
 func handleCertificateGenerate(
 	backend Backend, args map[string]interface{},
 ) error {
@@ -30,7 +33,7 @@ func handleCertificateGenerate(
 
 	rsaBlockSize, err := strconv.Atoi(rsaBlockSizeRaw)
 	if err != nil {
-		return err
+		return usageError{hierr.Errorf(err, "can't parse key length")}
 	}
 
 	if _, err := os.Stat(certsDir); os.IsNotExist(err) {
@@ -45,17 +48,16 @@ func handleCertificateGenerate(
 		return fmt.Errorf("failed to generate private key: %s", err)
 	}
 
-	invalidAfter, err := time.Parse("2006-02-01", validTill)
+	invalidAfter, err := time.Parse(certDateLayout, validTill)
 	if err != nil {
-		return err
+		return usageError{hierr.Errorf(err, "can't parse certificate date")}
 	}
 
 	invalidBefore := time.Now()
 
-	serialNumberBlockSize := big.NewInt(0).Lsh(big.NewInt(1), 128)
-	serialNumber, err := rand.Int(rand.Reader, serialNumberBlockSize)
+	serialNumber, err := generateSerialNumber()
 	if err != nil {
-		return fmt.Errorf("failed to generate serial number: %s", err)
+		return err
 	}
 
 	cert := x509.Certificate{
@@ -95,7 +97,26 @@ func handleCertificateGenerate(
 		)
 	}
 
-	certOutFd, err := os.Create(filepath.Join(certsDir, "cert.pem"))
+	err = writeCertificate(filepath.Join(certsDir, "cert.pem"), certData)
+	if err != nil {
+		return err
+	}
+
+	return writePrivateKey(filepath.Join(certsDir, "key.pem"), privateKey)
+}
+
+func generateSerialNumber() (*big.Int, error) {
+	serialNumberBlockSize := big.NewInt(0).Lsh(big.NewInt(1), 128)
+	serialNumber, err := rand.Int(rand.Reader, serialNumberBlockSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate serial number: %s", err)
+	}
+
+	return serialNumber, nil
+}
+
+func writeCertificate(path string, certData []byte) error {
+	certOutFd, err := os.Create(path)
 	if err != nil {
 		return hierr.Errorf(
 			err, "can't create certificate file",
@@ -122,8 +143,12 @@ func handleCertificateGenerate(
 		)
 	}
 
+	return nil
+}
+
+func writePrivateKey(path string, privateKey *rsa.PrivateKey) error {
 	keyOutFd, err := os.OpenFile(
-		filepath.Join(certsDir, "key.pem"),
+		path,
 		os.O_WRONLY|os.O_CREATE|os.O_TRUNC,
 		0600,
 	)
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+// handleCertificateVerify checks that certificate and key stored in
+// certificates directory correspond to each other.
+func handleCertificateVerify(args map[string]interface{}) error {
+	var (
+		certFile = filepath.Join(args["--certs"].(string), "cert.pem")
+		keyFile  = filepath.Join(args["--certs"].(string), "key.pem")
+	)
+
+	_, err := loadCertificatePair(certFile, keyFile)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Certificate %s matches key %s.\n", certFile, keyFile)
+
+	return nil
+}
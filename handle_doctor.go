@@ -0,0 +1,120 @@
+package main
+
+import (
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/reconquest/hierr-go"
+)
+
+type doctorCheck struct {
+	name string
+	run  func() error
+}
+
+// handleDoctor runs all configuration checks, prints report and fails if any
+// of checks is failed.
+func handleDoctor(backend Backend, args map[string]interface{}) error {
+	certsDir := args["--certs"].(string)
+
+	checks := []doctorCheck{
+		{"backend can be initialized", backend.Init},
+		{
+			"certificate and key are valid",
+			func() error { return checkCertificate(certsDir) },
+		},
+	}
+
+	if fs, ok := backend.(*filesystem); ok {
+		checks = append(checks, doctorCheck{
+			"hash tables directory is writable",
+			func() error { return checkWritable(fs.hashTablesDir) },
+		})
+	}
+
+	if clientCA, ok := args["--client-ca"].(string); ok {
+		checks = append(checks, doctorCheck{
+			"client CA can be loaded",
+			func() error {
+				_, err := loadCertificatePool(clientCA)
+				return err
+			},
+		})
+	}
+
+	failed := 0
+	for _, check := range checks {
+		err := check.run()
+		if err != nil {
+			fmt.Printf("[FAIL] %s: %s\n", check.name, err)
+			failed++
+			continue
+		}
+
+		fmt.Printf("[ OK ] %s\n", check.name)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d checks failed", failed, len(checks))
+	}
+
+	return nil
+}
+
+func checkCertificate(certsDir string) error {
+	var (
+		certFile = filepath.Join(certsDir, "cert.pem")
+		keyFile  = filepath.Join(certsDir, "key.pem")
+	)
+
+	stat, err := os.Stat(keyFile)
+	if err != nil {
+		return err
+	}
+
+	if stat.Mode()&0077 != 0 {
+		return fmt.Errorf(
+			"key file %s is too open: %s "+
+				"(should be accessible only by owner)",
+			keyFile, stat.Mode(),
+		)
+	}
+
+	pair, err := loadCertificatePair(certFile, keyFile)
+	if err != nil {
+		return err
+	}
+
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return hierr.Errorf(
+			err, "can't parse certificate %s", certFile,
+		)
+	}
+
+	if time.Now().After(cert.NotAfter) {
+		return fmt.Errorf(
+			"certificate %s expired at %s", certFile, cert.NotAfter,
+		)
+	}
+
+	return nil
+}
+
+func checkWritable(dir string) error {
+	file, err := ioutil.TempFile(dir, ".doctor")
+	if err != nil {
+		return err
+	}
+
+	err = file.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Remove(file.Name())
+}
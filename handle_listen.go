@@ -1,34 +1,177 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"crypto/hmac"
 	"crypto/sha256"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io/ioutil"
 	"log"
 	"math/big"
 	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
+	"syscall"
+	"text/template"
 	"time"
 
+	"github.com/quic-go/quic-go/http3"
 	"github.com/reconquest/hierr-go"
+	"google.golang.org/grpc"
 )
 
 const (
 	passwordChangeSaltAmount = 10
 )
 
+const (
+	nextExhaustedWrap  = "wrap"
+	nextExhaustedDeny  = "deny"
+	nextExhaustedStick = "stick"
+)
+
+const (
+	notFoundBodyEmpty = "empty"
+	notFoundBodyPlain = "plain"
+	notFoundBodyJSON  = "json"
+)
+
+const (
+	keepAliveOn  = "on"
+	keepAliveOff = "off"
+)
+
+// Encodings of served record, which can be requested using encoding query
+// parameter.
+const (
+	recordEncodingRaw    = "raw"
+	recordEncodingBase64 = "base64"
+)
+
+// Fields of record, which can be requested using field query parameter.
+const (
+	recordFieldFull = "full"
+	recordFieldHash = "hash"
+)
+
 type Server struct {
 	backend Backend
 	hashTTL time.Duration
+	reserve bool
+
+	// nextDepth is amount of alternate hash entries, which are served to
+	// recent client, nextExhausted specifies what to serve afterwards.
+	nextDepth     int
+	nextExhausted string
+
+	// exports caches compressed bundles of exported hash tables.
+	exports *exportCache
+
+	// exposeIndex enables X-Shadowd-Index header with index of served entry
+	// for clients authenticated by admin certificate.
+	exposeIndex bool
+
+	// notFoundBody is format of body, which is sent for unknown tokens.
+	notFoundBody string
+
+	metrics *metrics
+
+	// served buffers counts of served entries until they are flushed to
+	// backend.
+	served *servedCounter
+
+	// redactTokens hides identity of clients in admin responses and index
+	// trace.
+	redactTokens bool
+
+	// traceIndex enables logging of hash entry index computation.
+	traceIndex bool
+
+	// recentKeyTemplate produces key, by which recent clients are tracked.
+	recentKeyTemplate *template.Template
+
+	// logNext enables logging of alternate entries served to recent
+	// clients.
+	logNext bool
+
+	// defaultToken is served instead of unknown tokens, if specified.
+	defaultToken string
+
+	// indexSeed is mixed into input of hash entry index, so deployments
+	// serving the same hash table serve different entries to the same client.
+	indexSeed string
+
+	// hmacKey is used for signing served records, records are not signed if
+	// it is empty.
+	hmacKey []byte
+
+	generations     map[string]*generation
+	generationsLock *sync.Mutex
+
+	// allowEmptyPassword allows generation service to generate hash tables
+	// for empty password.
+	allowEmptyPassword bool
+
+	// events delivers served hash entries to admin clients tailing them.
+	events *issuanceEvents
+
+	// serveLimiter bounds total amount of entries served per minute.
+	serveLimiter *serveLimiter
+
+	// retryAfter is range of Retry-After values sent when limit is
+	// exceeded.
+	retryAfter retryAfterRange
+
+	// recentLimit bounds amount of recent clients tracked per token.
+	recentLimit *recentClientsLimit
+
+	// replicator sends recent client events to standby server, it is nil
+	// if replication is not configured.
+	replicator *recentReplicator
+
+	// authorizer is asked before serving every hash table entry.
+	authorizer Authorizer
+
+	// tokenFromCert makes token to be taken from client certificate, token
+	// requested in path should be empty or match it.
+	tokenFromCert bool
+
+	// servedTokens limits tokens served by listener, other tokens are
+	// responded as unknown even if they exist in backend.
+	servedTokens servedTokens
 }
 
 func (server *Server) HandleTokens(
-	writer http.ResponseWriter, request *http.Request,
+	writer http.ResponseWriter, request *http.Request, token string,
 ) {
-	// no need to validate token because net/http package will validate request
-	// uri and remove '../' statements.
-	token := strings.TrimPrefix(request.URL.Path, "/t/")
+	if server.tokenFromCert {
+		certToken, ok := getCertificateToken(request)
+		if !ok || (token != "" && token != certToken) {
+			log.Printf(
+				"client %s is not allowed to request '%s'",
+				getClientAddress(request), token,
+			)
+			writer.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		token = certToken
+	}
+
+	if !isTokenPrefix(token) && !server.servedTokens.allows(token) {
+		server.writeNotFound(writer, request, token)
+		return
+	}
 
 	switch request.Method {
 	case "GET":
@@ -45,7 +188,7 @@ func (server *Server) handleHashRetrieve(
 	request *http.Request,
 	token string,
 ) {
-	if strings.HasSuffix(token, "/") || token == "" {
+	if isTokenPrefix(token) {
 		tokens, err := server.backend.GetTokens(token)
 		if err != nil {
 			log.Println(
@@ -56,7 +199,7 @@ func (server *Server) handleHashRetrieve(
 			)
 
 			if err == ErrNotFound {
-				writer.WriteHeader(http.StatusNotFound)
+				server.writeNotFound(writer, request, token)
 			} else {
 				writer.WriteHeader(http.StatusInternalServerError)
 			}
@@ -64,6 +207,27 @@ func (server *Server) handleHashRetrieve(
 			return
 		}
 
+		if request.URL.Query().Get("all") != "1" || !isAdmin(request) {
+			tokens = filterHiddenTokens(tokens)
+		}
+
+		tokens = server.servedTokens.filter(token, tokens)
+
+		if since := request.URL.Query().Get("since"); since != "" {
+			tokens, err = server.filterModifiedTokens(token, tokens, since)
+			if err != nil {
+				log.Println(err)
+
+				if _, ok := err.(usageError); ok {
+					writer.WriteHeader(http.StatusBadRequest)
+				} else {
+					writer.WriteHeader(http.StatusInternalServerError)
+				}
+
+				return
+			}
+		}
+
 		if len(tokens) == 0 {
 			writer.WriteHeader(http.StatusNoContent)
 			return
@@ -77,10 +241,43 @@ func (server *Server) handleHashRetrieve(
 		return
 	}
 
+	encoding := request.URL.Query().Get("encoding")
+	switch encoding {
+	case "":
+		encoding = recordEncodingRaw
+	case recordEncodingRaw, recordEncodingBase64:
+	default:
+		log.Printf("got request with unknown encoding '%s'", encoding)
+		writer.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	field := request.URL.Query().Get("field")
+	switch field {
+	case "":
+		field = recordFieldFull
+	case recordFieldFull, recordFieldHash:
+	default:
+		log.Printf("got request with unknown field '%s'", field)
+		writer.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
 	tableSize, err := server.backend.GetTableSize(token)
+	if err == ErrNotFound && server.defaultToken != "" &&
+		server.servedTokens.allows(server.defaultToken) {
+		log.Printf(
+			"hash table %s is not found, serving default token %s",
+			token, server.defaultToken,
+		)
+
+		token = server.defaultToken
+		tableSize, err = server.backend.GetTableSize(token)
+	}
+
 	if err != nil {
 		if err == ErrNotFound {
-			writer.WriteHeader(http.StatusNotFound)
+			server.writeNotFound(writer, request, token)
 		} else {
 			log.Println(err)
 			writer.WriteHeader(http.StatusInternalServerError)
@@ -88,40 +285,514 @@ func (server *Server) handleHashRetrieve(
 		return
 	}
 
-	remote := request.RemoteAddr[:strings.LastIndex(request.RemoteAddr, ":")]
-	remote = remote + "-" + token
+	subject := newAuthorizationSubject(request, token)
+
+	allowed, err := server.authorizer.Authorize(subject)
+	if err != nil {
+		log.Println(hierr.Errorf(err, "can't authorize request for %s", token))
+		writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if !allowed {
+		client := subject.Client
+		if server.redactTokens {
+			client = redact(client)
+		}
+
+		log.Printf("serving %s to %s is denied by authorizer", token, client)
+		writer.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	info, err := server.backend.GetTokenInfo(token)
+	if err != nil {
+		log.Println(
+			hierr.Errorf(err, "can't get metadata for %s", token),
+		)
+	} else {
+		if info.Sunset != nil {
+			if time.Now().After(*info.Sunset) {
+				log.Printf("hash table %s is not served after sunset", token)
+				writer.WriteHeader(http.StatusGone)
+				return
+			}
+
+			writer.Header().Set(
+				"Sunset", info.Sunset.UTC().Format(http.TimeFormat),
+			)
+		}
+
+		if info.Banner != "" {
+			writer.Header().Set("X-Shadowd-Notice", info.Banner)
+		}
+
+		if info.RecordMaxAge > 0 {
+			writer.Header().Set(
+				"X-Shadowd-Max-Age", strconv.FormatInt(info.RecordMaxAge, 10),
+			)
+		}
+	}
+
+	limited := time.Now()
+	if !server.serveLimiter.allow(limited) {
+		log.Printf("global serve limit exceeded, %s is not served", token)
+
+		server.metrics.inc(metricServeLimitExceeded, token)
+
+		writer.Header().Set("Retry-After", server.retryAfter.get())
+		writer.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	// slot of global limit is taken before entry is selected, so concurrent
+	// requests can't exceed limit, but only written records are counted
+	served := false
+	defer func() {
+		if !served {
+			server.serveLimiter.cancel(limited)
+		}
+	}()
+
+	var (
+		noRotate = info != nil && info.NoRotate
+		number   int64
+	)
+
+	// shared token has single entry for all clients, so there is nothing
+	// to select and recent clients are not tracked
+	if info == nil || !info.Shared {
+		number, err = server.selectIndex(request, token, tableSize, noRotate)
+	}
+	if err != nil {
+		log.Println(err)
+
+		if err == errNextExhausted {
+			writer.WriteHeader(http.StatusServiceUnavailable)
+		} else {
+			writer.WriteHeader(http.StatusInternalServerError)
+		}
+
+		return
+	}
+
+	// entry is reserved only when it's actually served, so expired entries
+	// skipped while looking for unexpired one are left unreserved
+	var reserve func(index int64) (bool, error)
+	if server.reserve && (info == nil || !info.Shared) {
+		reserve = func(index int64) (bool, error) {
+			return server.reserveIndex(token, index)
+		}
+	}
+
+	record, number, err := getUnexpiredHash(
+		server.backend, token, number, tableSize, reserve,
+	)
+	if err == errTableExpired {
+		log.Println(hierr.Errorf(err, "can't serve %s", token))
+		writer.WriteHeader(http.StatusGone)
+		return
+	}
+	if err == errTableExhausted {
+		log.Println(hierr.Errorf(err, "can't serve %s", token))
+		writer.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	if err != nil {
+		writer.Write([]byte(err.Error()))
+		writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if server.exposeIndex && isAdmin(request) {
+		writer.Header().Set("X-Shadowd-Index", strconv.FormatInt(number, 10))
+	}
+
+	if field == recordFieldHash {
+		parsed, err := parseRecord(record)
+		if err != nil {
+			log.Println(
+				hierr.Errorf(err, "can't parse record of %s", token),
+			)
+			writer.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		record = parsed.hash
+	}
+
+	if encoding == recordEncodingBase64 {
+		record = base64.StdEncoding.EncodeToString([]byte(record))
+		writer.Header().Set("X-Shadowd-Encoding", recordEncodingBase64)
+	}
+
+	if len(server.hmacKey) > 0 {
+		writer.Header().Set("X-Shadowd-HMAC", signRecord(server.hmacKey, record))
+	}
+
+	writer.Write([]byte(record))
+
+	served = true
+
+	server.metrics.inc(metricHashesServed, token)
+
+	server.served.add(token)
+
+	client := getClientAddress(request)
+	if server.redactTokens {
+		client = redact(client)
+	}
+
+	server.events.publish(issuanceEvent{
+		Token:  token,
+		Client: client,
+		Index:  number,
+		Time:   time.Now(),
+	})
+}
+
+// signRecord returns hex encoded HMAC-SHA256 of record.
+func signRecord(key []byte, record string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(record))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// isTokenPrefix returns true if tokens with specified prefix should be
+// listed instead of serving hash table entry.
+func isTokenPrefix(token string) bool {
+	return strings.HasSuffix(token, "/") || token == ""
+}
+
+// filterHiddenTokens removes tokens starting with dot, which are internal
+// tokens and should not be listed unless explicitly requested.
+func filterHiddenTokens(tokens []string) []string {
+	visible := []string{}
+	for _, token := range tokens {
+		if !strings.HasPrefix(token, ".") {
+			visible = append(visible, token)
+		}
+	}
+
+	return visible
+}
+
+// filterModifiedTokens leaves only tokens listed under specified prefix,
+// which have been modified after since time (RFC 3339).
+func (server *Server) filterModifiedTokens(
+	prefix string, tokens []string, since string,
+) ([]string, error) {
+	sinceTime, err := time.Parse(time.RFC3339, since)
+	if err != nil {
+		return nil, usageError{
+			hierr.Errorf(err, "invalid since time '%s'", since),
+		}
+	}
+
+	modified, err := server.backend.GetTokensModifiedSince(prefix, sinceTime)
+	if err != nil {
+		return nil, hierr.Errorf(err, "can't get modified tokens")
+	}
+
+	isModified := map[string]bool{}
+	for _, token := range modified {
+		isModified[token] = true
+	}
+
+	filtered := []string{}
+	for _, token := range tokens {
+		if isModified[token] {
+			filtered = append(filtered, token)
+		}
+	}
+
+	return filtered, nil
+}
+
+func (server *Server) writeNotFound(
+	writer http.ResponseWriter, request *http.Request, token string,
+) {
+	switch server.notFoundBody {
+	case notFoundBodyPlain:
+		writer.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		writer.WriteHeader(http.StatusNotFound)
+		fmt.Fprintln(writer, translate(request, messageTokenNotFound, token))
+
+	case notFoundBodyJSON:
+		writer.Header().Set("Content-Type", "application/json")
+		writer.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(writer).Encode(map[string]string{
+			"error":   "not found",
+			"message": translate(request, messageTokenNotFound, token),
+			"token":   token,
+		})
+
+	default:
+		writer.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// selectIndex computes index of hash table entry which should be served for
+// requesting client, if noRotate is set, client is always served the same
+// entry.
+func (server *Server) selectIndex(
+	request *http.Request, token string, tableSize int64, noRotate bool,
+) (int64, error) {
+	input := newHashInput(
+		request, server.indexSeed, token, hashPurposeServe, server.hashTTL,
+	)
+
+	// stable clients and clients of tokens, which should not be rotated,
+	// are pinned to the same entry, so index doesn't depend on time slot
+	// and alternate entries are never served to them
+	stable := noRotate || isStableRequested(request)
+	if stable {
+		input.slot = 0
+	}
+
+	remote, err := server.getRecentKey(request, input)
+	if err != nil {
+		return 0, err
+	}
+
+	var (
+		recent   bool
+		modifier int
+	)
+
+	if !stable {
+		recent, modifier, err = server.trackRecentClient(
+			token, remote, input.client,
+		)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	derivation := deriveIndex(input, tableSize, modifier)
+	if server.traceIndex {
+		server.logIndexDerivation(remote, recent, derivation)
+	}
+
+	return derivation.number, nil
+}
+
+// trackRecentClient marks client as recent and returns modifier of hash
+// entry index, which is non-zero for repeated requests of recent client.
+func (server *Server) trackRecentClient(
+	token string, remote string, client string,
+) (bool, int, error) {
+	// in case of client requested shadow entry not too long ago,
+	// we should send different entry on further invocations
+	recent, err := server.backend.IsRecentClient(remote)
+	if err != nil {
+		return false, 0, err
+	}
+
+	modifier := 0
+	if recent {
+		requests, err := server.backend.AddRecentClientRequest(remote)
+		if err != nil {
+			return false, 0, err
+		}
+
+		server.replicator.publish(
+			recentEvent{Type: recentEventRequest, Remote: remote},
+		)
+
+		modifier, err = server.getNextModifier(requests)
+		if err != nil {
+			return false, 0, err
+		}
+
+		server.metrics.inc(metricNextEntriesServed, token)
+
+		if server.logNext {
+			if server.redactTokens {
+				client = redact(client)
+			}
+
+			log.Printf(
+				"client %s has requested %s again, serving alternate entry",
+				client, token,
+			)
+		}
+	} else if server.recentLimit.track(token, remote, time.Now()) {
+		err = server.backend.AddRecentClient(remote)
+		if err != nil {
+			return false, 0, err
+		}
+
+		server.replicator.publish(
+			recentEvent{Type: recentEventAdd, Remote: remote},
+		)
+	} else {
+		server.metrics.inc(metricRecentClientsUntracked, token)
+	}
+
+	return recent, modifier, nil
+}
+
+// isStableRequested returns true if client asks for stable hash entry via
+// X-Shadowd-Stable header.
+func isStableRequested(request *http.Request) bool {
+	stable, _ := strconv.ParseBool(request.Header.Get("X-Shadowd-Stable"))
+	return stable
+}
+
+// unexpiredHashBatch is amount of records, which are read at once while
+// looking for unexpired record after expired one.
+const unexpiredHashBatch = 256
+
+// getUnexpiredHash returns the first record starting from specified number,
+// which has not expired yet, along with its number. If reserve is not nil,
+// unexpired records are also reserved by it and records, which can't be
+// reserved, are skipped too; errTableExhausted is returned when there are
+// unexpired records, but none of them can be reserved.
+func getUnexpiredHash(
+	backend Backend, token string, number int64, tableSize int64,
+	reserve func(index int64) (bool, error),
+) (string, int64, error) {
+	var (
+		now   = time.Now()
+		taken = false
+	)
+
+	isServable := func(index int64, expiry time.Time) (bool, error) {
+		if !expiry.IsZero() && now.After(expiry) {
+			return false, nil
+		}
+
+		if reserve == nil {
+			return true, nil
+		}
+
+		servable, err := reserve(index)
+		if err != nil {
+			return false, err
+		}
+
+		if !servable {
+			taken = true
+		}
+
+		return servable, nil
+	}
+
+	// selected record is usually unexpired, so it's read alone and can be
+	// taken from cache
+	record, expiry, err := backend.GetHashWithExpiry(token, number)
+	if err != nil {
+		return "", 0, err
+	}
+
+	servable, err := isServable(number, expiry)
+	if err != nil {
+		return "", 0, err
+	}
+
+	if servable {
+		return record, number, nil
+	}
+
+	for offset := int64(1); offset < tableSize; {
+		start := (number + offset) % tableSize
+
+		// batch doesn't wrap around the end of hash table and doesn't
+		// reach selected record again
+		count := int64(unexpiredHashBatch)
+		if count > tableSize-start {
+			count = tableSize - start
+		}
+		if count > tableSize-offset {
+			count = tableSize - offset
+		}
+
+		records, expiries, err := backend.GetHashesWithExpiry(
+			token, start, count,
+		)
+		if err != nil {
+			return "", 0, err
+		}
+
+		// hash table has been replaced by shorter one
+		if len(records) == 0 {
+			break
+		}
+
+		for i, record := range records {
+			index := start + int64(i)
+
+			servable, err := isServable(index, expiries[i])
+			if err != nil {
+				return "", 0, err
+			}
+
+			if servable {
+				return record, index, nil
+			}
+		}
+
+		offset += int64(len(records))
+	}
+
+	if taken {
+		return "", 0, errTableExhausted
+	}
+
+	return "", 0, errTableExpired
+}
+
+// logIndexDerivation logs all values used for computing index of hash entry
+// served to client.
+func (server *Server) logIndexDerivation(
+	remote string, recent bool, derivation indexDerivation,
+) {
+	if server.redactTokens {
+		remote = redact(remote)
+	}
+
+	log.Printf(
+		"trace index: remote=%s recent=%t slot=%d bytes=%x modifier=%d "+
+			"index=%d max=%d number=%d",
+		remote, recent, derivation.slot, derivation.bytes,
+		derivation.modifier, derivation.index, derivation.modMax,
+		derivation.number,
+	)
+}
+
+// getNextModifier returns modifier of hash entry index for specified
+// repeated request of recent client: client gets next alternate entry on
+// every repeated request until configured depth is reached.
+func (server *Server) getNextModifier(requests int) (int, error) {
+	if requests <= server.nextDepth {
+		return requests, nil
+	}
+
+	switch server.nextExhausted {
+	case nextExhaustedWrap:
+		return requests % (server.nextDepth + 1), nil
 
-	// in case of client requested shadow entry not too long ago,
-	// we should send different entry on further invocations
-	recent, err := server.backend.IsRecentClient(remote)
-	if err != nil {
-		log.Println(err)
-		writer.WriteHeader(http.StatusInternalServerError)
-		return
-	}
+	case nextExhaustedDeny:
+		return 0, errNextExhausted
 
-	modifier := 1
-	if !recent {
-		modifier = 0
-		err = server.backend.AddRecentClient(remote)
-		if err != nil {
-			log.Println(err)
-			writer.WriteHeader(http.StatusInternalServerError)
-			return
-		}
+	default:
+		return server.nextDepth, nil
 	}
+}
 
-	record, err := server.backend.GetHash(
-		token,
-		hashNumber(remote, tableSize, server.hashTTL, modifier),
-	)
+// reserveIndex reserves specified hash table entry, so no other client will
+// get it, false is returned when entry is already reserved.
+func (server *Server) reserveIndex(token string, index int64) (bool, error) {
+	reserved, err := server.backend.ReserveIndex(token, index)
 	if err != nil {
-		writer.Write([]byte(err.Error()))
-		writer.WriteHeader(http.StatusInternalServerError)
-		return
+		return false, hierr.Errorf(
+			err, "can't reserve entry %d of %s", index, token,
+		)
 	}
 
-	writer.Write([]byte(record))
+	return reserved, nil
 }
 
 func (server *Server) handlePasswordChange(
@@ -132,7 +803,7 @@ func (server *Server) handlePasswordChange(
 	tableSize, err := server.backend.GetTableSize(token)
 	if err != nil {
 		if err == ErrNotFound {
-			writer.WriteHeader(http.StatusNotFound)
+			server.writeNotFound(writer, request, token)
 		} else {
 			log.Println(err)
 			writer.WriteHeader(http.StatusInternalServerError)
@@ -141,15 +812,16 @@ func (server *Server) handlePasswordChange(
 		return
 	}
 
-	remote := request.RemoteAddr[:strings.LastIndex(request.RemoteAddr, ":")]
-	remote += "-" + token + "-salt-"
+	input := newHashInput(
+		request, server.indexSeed, token, hashPurposeSalt, server.hashTTL,
+	)
 
 	salts := []string{}
 	hashes := []string{}
 	for i := 0; i < passwordChangeSaltAmount; i++ {
 		hash, err := server.backend.GetHash(
 			token,
-			hashNumber(remote, tableSize, server.hashTTL, i),
+			hashNumber(input, tableSize, i),
 		)
 		if err != nil {
 			log.Println(err)
@@ -203,9 +875,17 @@ func (server *Server) handlePasswordChange(
 		token,
 	)
 
-	table := []string{}
-	for i := 0; i < int(tableSize); i++ {
-		table = append(table, generateSHA512(password))
+	table, err := generateHashTable(
+		generateSHA512, password, int(tableSize), nil,
+	)
+	if err != nil {
+		log.Println(
+			hierr.Errorf(
+				err, "can't generate hash table for %s", token,
+			),
+		)
+		writer.WriteHeader(http.StatusInternalServerError)
+		return
 	}
 
 	err = server.backend.SetHashTable(token, table)
@@ -219,21 +899,43 @@ func (server *Server) handlePasswordChange(
 		return
 	}
 
+	err = updateTokenInfo(server.backend, token, func(info *tokenInfo) {
+		info.Verifier = ""
+	})
+	if err != nil {
+		log.Println(err)
+	}
+
 	log.Printf(
 		"hash table %s with %d items successfully created",
 		token, tableSize,
 	)
 }
 
-func hashNumber(
-	source string, max int64, ttl time.Duration, modifier int,
-) int64 {
-	hash := sha256.Sum256([]byte(
-		fmt.Sprintf(
-			"%s%d",
-			source, time.Now().Unix()/int64(ttl/time.Second),
-		),
-	))
+func hashNumber(input hashInput, max int64, modifier int) int64 {
+	return deriveIndex(input, max, modifier).number
+}
+
+// indexDerivation contains intermediate values of hash entry index
+// computation, which are logged for debugging with --trace-index.
+type indexDerivation struct {
+	slot     int64
+	bytes    []byte
+	modifier int
+	index    int64
+	modMax   int64
+	number   int64
+}
+
+// deriveIndex computes index of hash entry in table of specified size from
+// hash of input, modifier selects alternate entry.
+func deriveIndex(input hashInput, max int64, modifier int) indexDerivation {
+	derivation := indexDerivation{
+		slot:     input.slot,
+		modifier: modifier,
+	}
+
+	hash := sha256.Sum256(input.bytes())
 
 	var (
 		hashMaxLength int64 = 1
@@ -247,6 +949,8 @@ func hashNumber(
 
 		hashMaxLength <<= 8
 		hashIndex += hashMaxLength * int64(hashByte)
+
+		derivation.bytes = append(derivation.bytes, hashByte)
 	}
 
 	hashIndex += int64(modifier)
@@ -256,11 +960,13 @@ func hashNumber(
 		modMax = max - 1
 	}
 
-	number := big.NewInt(0).Mod(
+	derivation.index = hashIndex
+	derivation.modMax = modMax
+	derivation.number = big.NewInt(0).Mod(
 		big.NewInt(hashIndex), big.NewInt(modMax),
 	).Int64()
 
-	return number
+	return derivation
 }
 
 func handleListen(
@@ -268,15 +974,460 @@ func handleListen(
 	args map[string]interface{},
 	hashTTL time.Duration,
 ) error {
-	wood := &Server{
+	if path, ok := args["--messages"].(string); ok {
+		err := loadMessages(path)
+		if err != nil {
+			return usageError{err}
+		}
+	}
+
+	wood, err := newServer(backend, args, hashTTL)
+	if err != nil {
+		return err
+	}
+
+	pruneInterval, err := time.ParseDuration(args["--prune-interval"].(string))
+	if err != nil || pruneInterval <= 0 {
+		return usageError{
+			fmt.Errorf("invalid prune interval: %s", args["--prune-interval"]),
+		}
+	}
+
+	metricsTextfileInterval, err := time.ParseDuration(
+		args["--metrics-textfile-interval"].(string),
+	)
+	if err != nil || metricsTextfileInterval <= 0 {
+		return usageError{
+			fmt.Errorf(
+				"invalid metrics textfile interval: %s",
+				args["--metrics-textfile-interval"],
+			),
+		}
+	}
+
+	servedFlushInterval, err := time.ParseDuration(
+		args["--served-flush-interval"].(string),
+	)
+	if err != nil || servedFlushInterval <= 0 {
+		return usageError{
+			fmt.Errorf(
+				"invalid served flush interval: %s",
+				args["--served-flush-interval"],
+			),
+		}
+	}
+
+	certFile, keyFile, err := ensureCertificate(backend, args)
+	if err != nil {
+		return err
+	}
+
+	tlsConfig, err := getServerTLSConfig(args)
+	if err != nil {
+		return err
+	}
+
+	certificate, err := loadServerCertificate(certFile, keyFile, args)
+	if err != nil {
+		return err
+	}
+
+	tlsConfig.Certificates = []tls.Certificate{certificate}
+
+	switch args["--tls-log"].(string) {
+	case tlsLogOff, tlsLogErrors:
+	case tlsLogVerbose:
+		logTLSHandshakes(tlsConfig)
+	default:
+		return usageError{
+			fmt.Errorf("unknown TLS log mode: %s", args["--tls-log"]),
+		}
+	}
+
+	var handler http.Handler = wood.getMux()
+
+	var pprofServer *http.Server
+	if address, ok := args["--pprof-listen"].(string); ok {
+		pprofServer, err = startPprof(address)
+		if err != nil {
+			return usageError{err}
+		}
+	}
+
+	var quicServer *http3.Server
+	if address, ok := args["--listen-http3"].(string); ok {
+		quicServer = startHTTP3(address, handler, tlsConfig)
+		handler = advertiseHTTP3(quicServer, handler)
+	}
+
+	var grpcServer *grpc.Server
+	if address, ok := args["--listen-grpc"].(string); ok {
+		grpcServer, err = startGRPC(
+			address, wood, tlsConfig, args["--proxy-protocol"].(bool),
+		)
+		if err != nil {
+			return usageError{err}
+		}
+	}
+
+	server := &http.Server{
+		Addr:      args["--listen"].(string),
+		Handler:   handler,
+		TLSConfig: tlsConfig,
+		ErrorLog:  newServerErrorLog(args["--tls-log"].(string)),
+	}
+
+	switch args["--keep-alive"].(string) {
+	case keepAliveOn:
+	case keepAliveOff:
+		server.SetKeepAlivesEnabled(false)
+	default:
+		return usageError{
+			fmt.Errorf(
+				"unknown keep-alive mode: %s", args["--keep-alive"],
+			),
+		}
+	}
+
+	// tailing admin clients are connected until server is stopped
+	server.RegisterOnShutdown(wood.events.close)
+
+	stop := make(chan struct{})
+	defer close(stop)
+
+	if target, ok := args["--event-sink"].(string); ok {
+		sink, err := newEventSink(target)
+		if err != nil {
+			return usageError{err}
+		}
+
+		defer sink.Close()
+
+		go forwardIssuanceEvents(wood.events, sink, wood.exposeIndex)
+	}
+
+	go wood.pruneRecentClients(pruneInterval, stop)
+
+	if wood.replicator != nil {
+		go wood.replicator.run(stop)
+	}
+	go wood.served.flushPeriodically(servedFlushInterval, stop)
+
+	if dir, ok := args["--provision-dir"].(string); ok {
+		provisioner, err := newProvisioner(wood.backend, dir)
+		if err != nil {
+			return usageError{err}
+		}
+
+		go provisioner.run(stop)
+	}
+
+	if path, ok := args["--metrics-textfile"].(string); ok {
+		go wood.metrics.writeTextfilePeriodically(
+			path, metricsTextfileInterval, stop,
+		)
+	}
+
+	shutdown := make(chan struct{})
+
+	go func() {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+
+		<-signals
+
+		log.Println("shutting down")
+
+		if quicServer != nil {
+			err := quicServer.Close()
+			if err != nil {
+				log.Println(err)
+			}
+		}
+
+		// pending gRPC requests are finished, so their served entries are
+		// counted
+		if grpcServer != nil {
+			grpcServer.GracefulStop()
+		}
+
+		if pprofServer != nil {
+			err := pprofServer.Close()
+			if err != nil {
+				log.Println(err)
+			}
+		}
+
+		err := server.Shutdown(context.Background())
+		if err != nil {
+			log.Println(err)
+		}
+
+		close(shutdown)
+	}()
+
+	logEffectiveSettings(backend, args, hashTTL)
+
+	log.Println("starting listening on", args["--listen"].(string))
+
+	err = listenAndServeTLS(
+		server, tlsConfig, args["--proxy-protocol"].(bool),
+	)
+	if err == http.ErrServerClosed {
+		// wait for active requests, so their served entries are counted
+		<-shutdown
+
+		wood.served.flush()
+
+		return nil
+	}
+
+	return err
+}
+
+// pruneRecentClients removes expired recent clients every specified
+// interval until stop is closed.
+func (server *Server) pruneRecentClients(
+	interval time.Duration, stop <-chan struct{},
+) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		pruned, err := server.backend.PruneRecentClients()
+		if err != nil {
+			log.Println(hierr.Errorf(err, "can't prune recent clients"))
+			continue
+		}
+
+		if pruned > 0 {
+			log.Printf("pruned %d expired recent clients", pruned)
+		}
+
+		server.metrics.add(metricRecentClientsPruned, int64(pruned))
+	}
+}
+
+func newServer(
+	backend Backend,
+	args map[string]interface{},
+	hashTTL time.Duration,
+) (*Server, error) {
+	var (
+		notFoundBody  = args["--not-found-body"].(string)
+		nextExhausted = args["--next-exhausted"].(string)
+	)
+
+	switch notFoundBody {
+	case notFoundBodyEmpty, notFoundBodyPlain, notFoundBodyJSON:
+	default:
+		return nil, usageError{
+			fmt.Errorf("unknown 404 body format: %s", notFoundBody),
+		}
+	}
+
+	switch nextExhausted {
+	case nextExhaustedWrap, nextExhaustedDeny, nextExhaustedStick:
+	default:
+		return nil, usageError{
+			fmt.Errorf("unknown next exhaustion mode: %s", nextExhausted),
+		}
+	}
+
+	nextDepth, err := strconv.Atoi(args["--next-depth"].(string))
+	if err != nil || nextDepth < 0 {
+		return nil, usageError{
+			fmt.Errorf("invalid next depth: %s", args["--next-depth"]),
+		}
+	}
+
+	server := &Server{
 		backend: backend,
 		hashTTL: hashTTL,
+		reserve: args["--reserve"].(bool),
+
+		nextDepth:     nextDepth,
+		nextExhausted: nextExhausted,
+
+		exposeIndex: args["--expose-index"].(bool),
+
+		notFoundBody: notFoundBody,
+
+		metrics: newMetrics(args["--metrics-per-token"].(bool)),
+
+		served: newServedCounter(backend),
+
+		redactTokens: args["--redact-tokens"].(bool),
+
+		traceIndex: args["--trace-index"].(bool),
+		logNext:    args["--log-next"].(bool),
+
+		events: newIssuanceEvents(),
+
+		exports: newExportCache(),
+
+		tokenFromCert: args["--token-from-cert"].(bool),
+
+		authorizer: noopAuthorizer{},
+	}
+
+	if url, ok := args["--authorize-url"].(string); ok {
+		server.authorizer = newWebhookAuthorizer(url)
+	}
+
+	if address, ok := args["--replicate-recent"].(string); ok {
+		clientCert, _ := args["--replicate-cert"].(string)
+		clientKey, _ := args["--replicate-key"].(string)
+		if clientCert == "" || clientKey == "" {
+			return nil, usageError{
+				errors.New(
+					"--replicate-recent requires --replicate-cert " +
+						"and --replicate-key",
+				),
+			}
+		}
+
+		client, err := newPinnedClient(
+			clientCert, clientKey,
+			filepath.Join(args["--certs"].(string), "cert.pem"),
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		server.replicator = newRecentReplicator(address, client)
+	}
+
+	if _, ok := args["--client-ca"].(string); server.tokenFromCert && !ok {
+		return nil, usageError{
+			errors.New("--token-from-cert requires --client-ca"),
+		}
+	}
+
+	serveLimit, err := strconv.Atoi(args["--global-serve-limit"].(string))
+	if err != nil || serveLimit < 0 {
+		return nil, usageError{
+			fmt.Errorf(
+				"invalid global serve limit: %s", args["--global-serve-limit"],
+			),
+		}
+	}
+
+	server.serveLimiter = newServeLimiter(serveLimit, serveLimitWindow)
+
+	recentLimit, err := strconv.Atoi(
+		args["--recent-clients-per-token"].(string),
+	)
+	if err != nil || recentLimit < 0 {
+		return nil, usageError{
+			fmt.Errorf(
+				"invalid limit of recent clients: %s",
+				args["--recent-clients-per-token"],
+			),
+		}
+	}
+
+	server.recentLimit = newRecentClientsLimit(recentLimit, hashTTL)
+
+	server.retryAfter, err = parseRetryAfterRange(
+		args["--retry-after"].(string),
+	)
+	if err != nil {
+		return nil, usageError{err}
+	}
+
+	cacheSize, err := strconv.Atoi(args["--cache-size"].(string))
+	if err != nil || cacheSize < 0 {
+		return nil, usageError{
+			fmt.Errorf("invalid cache size: %s", args["--cache-size"]),
+		}
+	}
+
+	cacheTTL, err := time.ParseDuration(args["--cache-ttl"].(string))
+	if err != nil || cacheTTL <= 0 {
+		return nil, usageError{
+			fmt.Errorf("invalid cache TTL: %s", args["--cache-ttl"]),
+		}
+	}
+
+	if cacheSize > 0 {
+		server.backend = newCachingBackend(
+			backend, cacheTTL, cacheSize, server.metrics,
+		)
+	}
+
+	server.recentKeyTemplate, err = parseRecentKeyTemplate(
+		args["--recent-key-template"].(string),
+	)
+	if err != nil {
+		return nil, usageError{err}
+	}
+
+	if token, ok := args["--default-token"].(string); ok {
+		err := validateToken(token)
+		if err != nil {
+			return nil, usageError{err}
+		}
+
+		server.defaultToken = token
+	}
+
+	if value, ok := args["--serve-tokens"].(string); ok {
+		server.servedTokens, err = parseServedTokens(value)
+		if err != nil {
+			return nil, usageError{err}
+		}
+	}
+
+	server.indexSeed, err = readIndexSeed(args)
+	if err != nil {
+		return nil, err
+	}
+
+	if path, ok := args["--hmac-key-file"].(string); ok {
+		key, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, hierr.Errorf(err, "can't read HMAC key file")
+		}
+
+		server.hmacKey = bytes.TrimRight(key, "\r\n")
+		if len(server.hmacKey) == 0 {
+			return nil, usageError{
+				fmt.Errorf("HMAC key file %s is empty", path),
+			}
+		}
 	}
 
-	http.HandleFunc("/v/", wood.HandleValidate)
-	http.HandleFunc("/t/", wood.HandleTokens)
-	http.HandleFunc("/ssh/", wood.HandleSSH)
+	return server, nil
+}
+
+func (server *Server) getMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v/", handleToken("/v/", server.HandleValidate))
+	mux.HandleFunc("/t/", handleToken("/t/", server.HandleTokens))
+	mux.HandleFunc("/ssh/", handleToken("/ssh/", server.HandleSSH))
+	mux.Handle("/metrics", server.metrics)
+	mux.HandleFunc("/admin/recent", server.HandleRecentClientDelete)
+	mux.HandleFunc("/admin/recent/", server.HandleRecentClients)
+	mux.HandleFunc("/admin/tail", server.HandleTail)
+	mux.HandleFunc("/admin/replicate/recent", server.HandleRecentReplicate)
+	mux.HandleFunc(
+		"/admin/export/", handleToken("/admin/export/", server.HandleExport),
+	)
+
+	return mux
+}
 
+func ensureCertificate(
+	backend Backend,
+	args map[string]interface{},
+) (string, string, error) {
 	var (
 		certFile = filepath.Join(args["--certs"].(string), "cert.pem")
 		keyFile  = filepath.Join(args["--certs"].(string), "key.pem")
@@ -296,13 +1447,9 @@ func handleListen(
 
 		err := handleCertificateGenerate(backend, args)
 		if err != nil {
-			return err
+			return "", "", err
 		}
 	}
 
-	log.Println("starting listening on", args["--listen"].(string))
-
-	return http.ListenAndServeTLS(
-		args["--listen"].(string), certFile, keyFile, nil,
-	)
+	return certFile, keyFile, nil
 }
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestFilesystem returns filesystem backend in temporary directory.
+func newTestFilesystem(t testing.TB) *filesystem {
+	dir := t.TempDir()
+
+	return &filesystem{
+		hashTablesDir: filepath.Join(dir, "tables"),
+		metaDir:       filepath.Join(dir, "meta"),
+		hashTTL:       time.Hour,
+		clients:       map[string]time.Time{},
+		clientsLock:   &sync.Mutex{},
+
+		reservations:     map[string]map[int64]bool{},
+		reservationsLock: &sync.Mutex{},
+
+		servedLock: &sync.Mutex{},
+	}
+}
+
+// setExpiringTable stores hash table of specified size, which entries are
+// expired except of specified ones.
+func setExpiringTable(
+	t *testing.T, backend Backend, token string, size int, unexpired ...int,
+) {
+	var (
+		table    = []string{}
+		expiries = []time.Time{}
+	)
+
+	for i := 0; i < size; i++ {
+		table = append(table, fmt.Sprintf("$5$salt%04d$hash", i))
+		expiries = append(expiries, time.Now().Add(-time.Hour))
+	}
+
+	for _, i := range unexpired {
+		expiries[i] = time.Time{}
+	}
+
+	err := backend.SetHashTableWithExpiry(token, table, expiries)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGetUnexpiredHashSkipsExpiredBatches(t *testing.T) {
+	backend := newTestFilesystem(t)
+
+	setExpiringTable(t, backend, "pool/token", 1000, 10, 700)
+
+	for _, test := range []struct {
+		number int64
+		index  int64
+	}{
+		{10, 10},
+		{11, 700},
+		{600, 700},
+		{701, 10},
+		{5, 10},
+	} {
+		record, index, err := getUnexpiredHash(
+			backend, "pool/token", test.number, 1000, nil,
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if index != test.index {
+			t.Fatalf(
+				"entry %d is served from %d instead of %d",
+				index, test.number, test.index,
+			)
+		}
+
+		if record != fmt.Sprintf("$5$salt%04d$hash", test.index) {
+			t.Fatalf("record %s is served as entry %d", record, index)
+		}
+	}
+
+	setExpiringTable(t, backend, "pool/expired", 300)
+
+	_, _, err := getUnexpiredHash(backend, "pool/expired", 299, 300, nil)
+	if err != errTableExpired {
+		t.Fatalf("expected expired table, got %v", err)
+	}
+}
+
+func TestGetUnexpiredHashReservesOnlyServedEntry(t *testing.T) {
+	backend := newTestFilesystem(t)
+
+	setExpiringTable(t, backend, "pool/token", 4, 1, 3)
+
+	reserve := func(index int64) (bool, error) {
+		return backend.ReserveIndex("pool/token", index)
+	}
+
+	for _, expected := range []int64{1, 3} {
+		_, index, err := getUnexpiredHash(
+			backend, "pool/token", 0, 4, reserve,
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if index != expected {
+			t.Fatalf("entry %d is served instead of %d", index, expected)
+		}
+	}
+
+	_, _, err := getUnexpiredHash(backend, "pool/token", 0, 4, reserve)
+	if err != errTableExhausted {
+		t.Fatalf("expected exhausted table, got %v", err)
+	}
+
+	// expired entries have been skipped without reserving them
+	for _, index := range []int64{0, 2} {
+		reserved, err := backend.ReserveIndex("pool/token", index)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !reserved {
+			t.Fatalf("expired entry %d has been reserved", index)
+		}
+	}
+}
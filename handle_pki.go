@@ -0,0 +1,313 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/reconquest/hierr-go"
+)
+
+const (
+	pkiCertFile   = "ca.pem"
+	pkiKeyFile    = "ca-key.pem"
+	pkiClientsDir = "clients"
+)
+
+func handlePKIInit(args map[string]interface{}) error {
+	var (
+		certsDir        = args["--certs"].(string)
+		rsaBlockSizeRaw = args["--bytes"].(string)
+		validTill       = args["--till"].(string)
+	)
+
+	rsaBlockSize, err := strconv.Atoi(rsaBlockSizeRaw)
+	if err != nil {
+		return usageError{hierr.Errorf(err, "can't parse key length")}
+	}
+
+	invalidAfter, err := time.Parse(certDateLayout, validTill)
+	if err != nil {
+		return usageError{hierr.Errorf(err, "can't parse certificate date")}
+	}
+
+	err = os.MkdirAll(filepath.Join(certsDir, pkiClientsDir), 0700)
+	if err != nil {
+		return hierr.Errorf(
+			err, "can't create certificates directory",
+		)
+	}
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, rsaBlockSize)
+	if err != nil {
+		return fmt.Errorf("failed to generate private key: %s", err)
+	}
+
+	serialNumber, err := generateSerialNumber()
+	if err != nil {
+		return err
+	}
+
+	cert := x509.Certificate{
+		IsCA: true,
+
+		SerialNumber: serialNumber,
+
+		NotBefore: time.Now(),
+		NotAfter:  invalidAfter,
+
+		BasicConstraintsValid: true,
+		KeyUsage: x509.KeyUsageDigitalSignature |
+			x509.KeyUsageCertSign |
+			x509.KeyUsageCRLSign,
+
+		Subject: pkix.Name{
+			CommonName: "shadowd CA",
+		},
+	}
+
+	certData, err := x509.CreateCertificate(
+		rand.Reader, &cert, &cert, &privateKey.PublicKey, privateKey,
+	)
+	if err != nil {
+		return hierr.Errorf(
+			err, "can't create CA certificate",
+		)
+	}
+
+	ca, err := x509.ParseCertificate(certData)
+	if err != nil {
+		return hierr.Errorf(
+			err, "can't parse created CA certificate",
+		)
+	}
+
+	err = writeCertificate(filepath.Join(certsDir, pkiCertFile), certData)
+	if err != nil {
+		return err
+	}
+
+	err = writePrivateKey(filepath.Join(certsDir, pkiKeyFile), privateKey)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf(
+		"CA certificate stored in %s.\n", filepath.Join(certsDir, pkiCertFile),
+	)
+
+	resigned, err := resignClientCertificates(
+		certsDir, ca, privateKey, invalidAfter,
+	)
+	if err != nil {
+		return hierr.Errorf(
+			err, "can't re-sign client certificates",
+		)
+	}
+
+	if resigned > 0 {
+		fmt.Printf("%d client certificates re-signed.\n", resigned)
+	}
+
+	return nil
+}
+
+func handlePKIIssue(args map[string]interface{}) error {
+	var (
+		certsDir        = args["--certs"].(string)
+		rsaBlockSizeRaw = args["--bytes"].(string)
+		validTill       = args["--till"].(string)
+		name            = args["--cn"].(string)
+	)
+
+	if name == "" || strings.Contains(name, "/") {
+		return usageError{
+			errors.New("common name should not be empty or contain '/'"),
+		}
+	}
+
+	rsaBlockSize, err := strconv.Atoi(rsaBlockSizeRaw)
+	if err != nil {
+		return usageError{hierr.Errorf(err, "can't parse key length")}
+	}
+
+	invalidAfter, err := time.Parse(certDateLayout, validTill)
+	if err != nil {
+		return usageError{hierr.Errorf(err, "can't parse certificate date")}
+	}
+
+	ca, caKey, err := loadCA(certsDir)
+	if err != nil {
+		return hierr.Errorf(
+			err, "can't load CA, run 'shadowd pki init' first",
+		)
+	}
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, rsaBlockSize)
+	if err != nil {
+		return fmt.Errorf("failed to generate private key: %s", err)
+	}
+
+	certData, err := issueClientCertificate(
+		ca, caKey, pkix.Name{CommonName: name}, &privateKey.PublicKey,
+		invalidAfter,
+	)
+	if err != nil {
+		return err
+	}
+
+	var (
+		certFile = filepath.Join(certsDir, pkiClientsDir, name+".pem")
+		keyFile  = filepath.Join(certsDir, pkiClientsDir, name+".key")
+	)
+
+	err = writeCertificate(certFile, certData)
+	if err != nil {
+		return err
+	}
+
+	err = writePrivateKey(keyFile, privateKey)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf(
+		"Client certificate for %s stored in %s and %s.\n",
+		name, certFile, keyFile,
+	)
+
+	return nil
+}
+
+func issueClientCertificate(
+	ca *x509.Certificate,
+	caKey *rsa.PrivateKey,
+	subject pkix.Name,
+	publicKey interface{},
+	invalidAfter time.Time,
+) ([]byte, error) {
+	serialNumber, err := generateSerialNumber()
+	if err != nil {
+		return nil, err
+	}
+
+	cert := x509.Certificate{
+		SerialNumber: serialNumber,
+
+		NotBefore: time.Now(),
+		NotAfter:  invalidAfter,
+
+		BasicConstraintsValid: true,
+		KeyUsage: x509.KeyUsageKeyEncipherment |
+			x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+
+		Subject: subject,
+	}
+
+	certData, err := x509.CreateCertificate(
+		rand.Reader, &cert, ca, publicKey, caKey,
+	)
+	if err != nil {
+		return nil, hierr.Errorf(
+			err, "can't create client certificate",
+		)
+	}
+
+	return certData, nil
+}
+
+// resignClientCertificates issues again all client certificates stored in
+// certificates directory using specified CA, client keys are kept.
+func resignClientCertificates(
+	certsDir string,
+	ca *x509.Certificate,
+	caKey *rsa.PrivateKey,
+	invalidAfter time.Time,
+) (int, error) {
+	paths, err := filepath.Glob(filepath.Join(certsDir, pkiClientsDir, "*.pem"))
+	if err != nil {
+		return 0, err
+	}
+
+	for _, path := range paths {
+		cert, err := readCertificate(path)
+		if err != nil {
+			return 0, hierr.Errorf(
+				err, "can't read certificate %s", path,
+			)
+		}
+
+		certData, err := issueClientCertificate(
+			ca, caKey, cert.Subject, cert.PublicKey, invalidAfter,
+		)
+		if err != nil {
+			return 0, err
+		}
+
+		err = writeCertificate(path, certData)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(paths), nil
+}
+
+func loadCA(certsDir string) (*x509.Certificate, *rsa.PrivateKey, error) {
+	ca, err := readCertificate(filepath.Join(certsDir, pkiCertFile))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	data, err := readPEM(filepath.Join(certsDir, pkiKeyFile))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	caKey, err := x509.ParsePKCS1PrivateKey(data)
+	if err != nil {
+		return nil, nil, hierr.Errorf(
+			err, "can't parse CA key",
+		)
+	}
+
+	return ca, caKey, nil
+}
+
+func readCertificate(path string) (*x509.Certificate, error) {
+	data, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return x509.ParseCertificate(data)
+}
+
+func readPEM(path string) ([]byte, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, hierr.Errorf(ErrNotFound, "%s", path)
+		}
+
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found in %s", path)
+	}
+
+	return block.Bytes, nil
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/reconquest/hierr-go"
+)
+
+// handlePrune removes hash tables, which are not served anymore, and clients,
+// which are no longer recent, so it can be run from cron in addition to
+// background pruning of running server. Nothing is removed in dry run mode,
+// items which would be removed are only reported.
+func handlePrune(backend Backend, args map[string]interface{}) error {
+	dryRun := args["--dry-run"].(bool)
+
+	tokens, err := backend.GetAllTokens()
+	if err != nil {
+		return backendError{hierr.Errorf(err, "can't get tokens")}
+	}
+
+	now := time.Now()
+
+	expiredTokens := 0
+	for _, token := range tokens {
+		info, err := backend.GetTokenInfo(token)
+		if err != nil {
+			return backendError{
+				hierr.Errorf(err, "can't get metadata for %s", token),
+			}
+		}
+
+		if !info.isSunsetPassed(now) {
+			expired, err := isTableExpired(backend, token)
+			if err != nil {
+				return backendError{
+					hierr.Errorf(err, "can't check expiry of %s", token),
+				}
+			}
+
+			if !expired {
+				continue
+			}
+		}
+
+		expiredTokens++
+
+		if dryRun {
+			fmt.Printf("Hash table %s would be removed.\n", token)
+			continue
+		}
+
+		err = backend.DeleteHashTable(token)
+		if err != nil {
+			return backendError{
+				hierr.Errorf(err, "can't remove hash table %s", token),
+			}
+		}
+
+		fmt.Printf("Hash table %s removed.\n", token)
+	}
+
+	action := "removed"
+	if dryRun {
+		action = "would be removed"
+	}
+
+	// recent clients of filesystem backend live in memory of running
+	// server, so there is nothing to prune from command line
+	if _, ok := backend.(*filesystem); ok {
+		fmt.Printf("%d expired hash tables %s.\n", expiredTokens, action)
+		fmt.Println(
+			"Recent clients are kept in memory of running server " +
+				"for filesystem backend and are not pruned.",
+		)
+
+		return nil
+	}
+
+	var expiredClients int
+	if dryRun {
+		expiredClients, err = backend.CountExpiredRecentClients()
+	} else {
+		expiredClients, err = backend.PruneRecentClients()
+	}
+	if err != nil {
+		return backendError{
+			hierr.Errorf(err, "can't prune expired recent clients"),
+		}
+	}
+
+	fmt.Printf(
+		"%d expired hash tables and %d expired recent clients %s.\n",
+		expiredTokens, expiredClients, action,
+	)
+
+	return nil
+}
+
+// isTableExpired reports whether all entries of hash table have expired.
+func isTableExpired(backend Backend, token string) (bool, error) {
+	size, err := backend.GetTableSize(token)
+	if err != nil {
+		if err == ErrNotFound {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	if size == 0 {
+		return false, nil
+	}
+
+	_, _, err = getUnexpiredHash(backend, token, 0, size, nil)
+	switch err {
+	case nil:
+		return false, nil
+	case errTableExpired:
+		return true, nil
+	default:
+		return false, err
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/reconquest/hierr-go"
+)
+
+// handleRecentFlush asks running server to forget recent clients of token or
+// of all tokens, so next requests of clients are treated as first ones.
+// Recent clients are flushed through admin endpoint, because filesystem
+// backend keeps them in memory of server process.
+func handleRecentFlush(args map[string]interface{}) error {
+	address := args["<server>"].(string)
+
+	token, _ := args["--token"].(string)
+	if token != "" {
+		err := validateToken(token)
+		if err != nil {
+			return usageError{err}
+		}
+	}
+
+	client, err := newAdminClient(args)
+	if err != nil {
+		return err
+	}
+
+	endpoint := "https://" + address + "/admin/recent/" + token
+
+	request, err := http.NewRequest("DELETE", endpoint, nil)
+	if err != nil {
+		return hierr.Errorf(err, "can't create request")
+	}
+
+	response, err := client.Do(request)
+	if err != nil {
+		return hierr.Errorf(err, "can't request %s", endpoint)
+	}
+
+	defer response.Body.Close()
+
+	switch response.StatusCode {
+	case http.StatusOK:
+	case http.StatusForbidden:
+		return usageError{
+			fmt.Errorf("client certificate is not accepted by server"),
+		}
+	default:
+		return fmt.Errorf("server responded with %s", response.Status)
+	}
+
+	var result struct {
+		Flushed int `json:"flushed"`
+	}
+
+	err = json.NewDecoder(response.Body).Decode(&result)
+	if err != nil {
+		return hierr.Errorf(err, "can't decode server response")
+	}
+
+	fmt.Printf("%d recent clients flushed.\n", result.Flushed)
+
+	return nil
+}
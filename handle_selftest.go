@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/reconquest/hierr-go"
+)
+
+// handleSelftest starts server on random loopback port, requests hash for
+// specified token as usual client would do and validates received record.
+func handleSelftest(
+	backend Backend,
+	args map[string]interface{},
+	hashTTL time.Duration,
+) error {
+	token := args["<token>"].(string)
+
+	err := validateToken(token)
+	if err != nil {
+		return usageError{err}
+	}
+
+	wood, err := newServer(backend, args, hashTTL)
+	if err != nil {
+		return err
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return hierr.Errorf(
+			err, "can't listen loopback address",
+		)
+	}
+
+	server := &http.Server{
+		Handler: wood.getMux(),
+	}
+
+	go func() {
+		err := server.Serve(listener)
+		if err != nil && err != http.ErrServerClosed {
+			log.Println(err)
+		}
+	}()
+
+	defer server.Close()
+
+	url := "http://" + listener.Addr().String() + "/t/" + token
+
+	response, err := http.Get(url)
+	if err != nil {
+		return hierr.Errorf(
+			err, "selftest failed: can't request %s", url,
+		)
+	}
+
+	defer response.Body.Close()
+
+	record, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return hierr.Errorf(
+			err, "selftest failed: can't read response",
+		)
+	}
+
+	switch response.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return hierr.Errorf(
+			ErrNotFound, "selftest failed: hash table %s", token,
+		)
+	default:
+		return fmt.Errorf(
+			"selftest failed: server responded with %s", response.Status,
+		)
+	}
+
+	err = validateRecord(string(record))
+	if err != nil {
+		return hierr.Errorf(
+			err, "selftest failed: invalid record received",
+		)
+	}
+
+	fmt.Printf("Selftest passed: valid record for %s received.\n", token)
+
+	return nil
+}
@@ -0,0 +1,351 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/reconquest/hierr-go"
+)
+
+const (
+	defaultGenerateLength    = 2048
+	defaultGenerateAlgorithm = "sha256"
+
+	// idempotencyKeyTTL is time during which generation request with same
+	// Idempotency-Key header will be answered by response of first request.
+	idempotencyKeyTTL = 10 * time.Minute
+)
+
+// generation is generation request made with idempotency key, fields are
+// guarded by generationsLock of server.
+type generation struct {
+	response    *bytes.Buffer
+	done        bool
+	date        time.Time
+	fingerprint string
+}
+
+// generationReplay is copy of state of previous generation with the same
+// idempotency key.
+type generationReplay struct {
+	response []byte
+	done     bool
+
+	// matches is false if previous generation has been requested with
+	// other parameters.
+	matches bool
+}
+
+// generationWriter appends output of generation to its response under lock,
+// so response can be copied by concurrent requests with the same key.
+type generationWriter struct {
+	server     *Server
+	generation *generation
+}
+
+func (writer generationWriter) Write(data []byte) (int, error) {
+	writer.server.generationsLock.Lock()
+	defer writer.server.generationsLock.Unlock()
+
+	return writer.generation.response.Write(data)
+}
+
+func (server *Server) HandleGenerate(
+	writer http.ResponseWriter, request *http.Request,
+) {
+	if request.Method != "POST" {
+		writer.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var (
+		token     = request.FormValue("token")
+		lengthRaw = request.FormValue("length")
+		algorithm = request.FormValue("algorithm")
+		password  = request.FormValue("password")
+	)
+
+	err := validateToken(token)
+	if err != nil || token == "" {
+		log.Printf("got bad token for table generation: '%s'", token)
+		writer.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if password == "" && !server.allowEmptyPassword {
+		log.Printf("got empty password for table generation of %s", token)
+		writer.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	length := defaultGenerateLength
+	if lengthRaw != "" {
+		length, err = strconv.Atoi(lengthRaw)
+		if err != nil || length <= 0 {
+			log.Printf("got bad table length for %s: '%s'", token, lengthRaw)
+			writer.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+
+	if algorithm == "" {
+		algorithm = defaultGenerateAlgorithm
+	}
+
+	implementation := getAlgorithmImplementation(algorithm)
+	if implementation == nil {
+		log.Printf("got unknown algorithm for %s: '%s'", token, algorithm)
+		writer.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	err = probeAlgorithm(algorithm, implementation, verifyRecord)
+	if err != nil {
+		log.Println(err)
+		writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "text/plain; charset=utf-8")
+
+	var output io.Writer = writer
+
+	var current *generation
+
+	key := request.Header.Get("Idempotency-Key")
+	if key != "" {
+		var previous *generationReplay
+
+		current, previous = server.startGeneration(
+			key, getGenerationFingerprint(token, length, algorithm, password),
+		)
+		if previous != nil {
+			if !previous.matches {
+				log.Printf(
+					"generation request with key '%s' differs from "+
+						"previous request with the same key",
+					key,
+				)
+				writer.WriteHeader(http.StatusUnprocessableEntity)
+				return
+			}
+
+			if !previous.done {
+				writer.WriteHeader(http.StatusConflict)
+				return
+			}
+
+			log.Printf(
+				"generation request with key '%s' has been already served",
+				key,
+			)
+
+			writer.Write(previous.response)
+			return
+		}
+
+		output = io.MultiWriter(writer, generationWriter{server, current})
+
+		defer server.finishGeneration(key, current)
+	}
+
+	log.Printf(
+		"got request for generating hash table %s with %d items",
+		token, length,
+	)
+
+	flusher, _ := writer.(http.Flusher)
+
+	reported := -1
+	table, err := generateHashTable(
+		implementation, password, length,
+		func(percent int) {
+			if percent == reported {
+				return
+			}
+
+			reported = percent
+
+			fmt.Fprintf(output, "Generating hash table... %d%%\n", percent)
+			if flusher != nil {
+				flusher.Flush()
+			}
+		},
+	)
+
+	if err != nil {
+		log.Println(
+			hierr.Errorf(
+				err, "can't generate hash table for %s", token,
+			),
+		)
+		fmt.Fprintln(writer, "Can't generate hash table.")
+
+		server.forgetGeneration(key, current)
+		return
+	}
+
+	err = server.backend.SetHashTable(token, table)
+	if err != nil {
+		log.Println(
+			hierr.Errorf(
+				err, "can't save generated hash table for %s", token,
+			),
+		)
+		fmt.Fprintln(writer, "Can't save generated hash table.")
+
+		server.forgetGeneration(key, current)
+		return
+	}
+
+	err = updateTokenInfo(server.backend, token, func(info *tokenInfo) {
+		info.Verifier = ""
+	})
+	if err != nil {
+		log.Println(err)
+	}
+
+	log.Printf(
+		"hash table %s with %d items successfully created",
+		token, length,
+	)
+
+	fmt.Fprintf(
+		output,
+		"Hash table %s with %d items successfully created.\n",
+		token, length,
+	)
+}
+
+// startGeneration registers generation for specified idempotency key, if
+// generation with that key is already known then copy of its state taken
+// under lock will be returned as previous.
+func (server *Server) startGeneration(
+	key string, fingerprint string,
+) (current *generation, previous *generationReplay) {
+	server.generationsLock.Lock()
+	defer server.generationsLock.Unlock()
+
+	for known, started := range server.generations {
+		if time.Since(started.date) > idempotencyKeyTTL {
+			delete(server.generations, known)
+		}
+	}
+
+	if started, ok := server.generations[key]; ok {
+		return nil, &generationReplay{
+			response: append([]byte{}, started.response.Bytes()...),
+			done:     started.done,
+			matches:  started.fingerprint == fingerprint,
+		}
+	}
+
+	current = &generation{
+		response:    &bytes.Buffer{},
+		date:        time.Now(),
+		fingerprint: fingerprint,
+	}
+
+	server.generations[key] = current
+
+	return current, nil
+}
+
+// getGenerationFingerprint returns hash of generation parameters, so request
+// reusing idempotency key with other parameters can be told apart without
+// keeping password in memory.
+func getGenerationFingerprint(
+	token string, length int, algorithm string, password string,
+) string {
+	hash := sha256.New()
+	for _, value := range []string{
+		token, strconv.Itoa(length), algorithm, password,
+	} {
+		// values are length-prefixed, so their boundaries can't be moved
+		fmt.Fprintf(hash, "%d:%s", len(value), value)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// finishGeneration marks specified generation as done, generations are
+// compared by pointer, so retry registered with the same key after failed
+// generation has been forgotten is not marked instead.
+func (server *Server) finishGeneration(key string, current *generation) {
+	server.generationsLock.Lock()
+	defer server.generationsLock.Unlock()
+
+	if server.generations[key] == current {
+		current.done = true
+	}
+}
+
+// forgetGeneration removes failed generation, so request with the same key
+// can be retried.
+func (server *Server) forgetGeneration(key string, current *generation) {
+	if current == nil {
+		return
+	}
+
+	server.generationsLock.Lock()
+	defer server.generationsLock.Unlock()
+
+	if server.generations[key] == current {
+		delete(server.generations, key)
+	}
+}
+
+func handleServeGenerate(
+	backend Backend, args map[string]interface{},
+) error {
+	address := args["--serve-generate"].(string)
+
+	certFile, keyFile, err := ensureCertificate(backend, args)
+	if err != nil {
+		return err
+	}
+
+	tlsConfig, err := getServerTLSConfig(args)
+	if err != nil {
+		return err
+	}
+
+	certificate, err := loadServerCertificate(certFile, keyFile, args)
+	if err != nil {
+		return err
+	}
+
+	tlsConfig.Certificates = []tls.Certificate{certificate}
+
+	tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+
+	wood := &Server{
+		backend:         backend,
+		generations:     map[string]*generation{},
+		generationsLock: &sync.Mutex{},
+
+		allowEmptyPassword: args["--allow-empty-password"].(bool),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/admin/generate", wood.HandleGenerate)
+
+	server := &http.Server{
+		Addr:      address,
+		Handler:   mux,
+		TLSConfig: tlsConfig,
+	}
+
+	log.Println("starting generation service on", address)
+
+	return listenAndServeTLS(server, tlsConfig, false)
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"sync"
+	"testing"
+)
+
+func newGenerationServer() *Server {
+	return &Server{
+		generations:     map[string]*generation{},
+		generationsLock: &sync.Mutex{},
+	}
+}
+
+func TestFinishOfForgottenGenerationDoesNotFinishRetry(t *testing.T) {
+	server := newGenerationServer()
+
+	failed, _ := server.startGeneration("key", "fingerprint")
+	server.forgetGeneration("key", failed)
+
+	retry, previous := server.startGeneration("key", "fingerprint")
+	if previous != nil {
+		t.Fatal("forgotten generation is replayed")
+	}
+
+	// deferred finish of failed request runs after retry has started
+	server.finishGeneration("key", failed)
+
+	_, previous = server.startGeneration("key", "fingerprint")
+	if previous == nil || previous.done {
+		t.Fatal("unfinished retry is marked as done")
+	}
+
+	server.finishGeneration("key", retry)
+
+	_, previous = server.startGeneration("key", "fingerprint")
+	if previous == nil || !previous.done {
+		t.Fatal("finished retry is not marked as done")
+	}
+}
+
+func TestReplayedGenerationIsCopied(t *testing.T) {
+	server := newGenerationServer()
+
+	current, _ := server.startGeneration("key", "fingerprint")
+	generationWriter{server, current}.Write([]byte("first"))
+
+	_, previous := server.startGeneration("key", "fingerprint")
+
+	generationWriter{server, current}.Write([]byte(" second"))
+
+	if string(previous.response) != "first" {
+		t.Fatalf("replayed response is changed: %s", previous.response)
+	}
+}
+
+func TestReuseOfKeyWithOtherParametersIsNotReplayed(t *testing.T) {
+	server := newGenerationServer()
+
+	current, _ := server.startGeneration(
+		"key", getGenerationFingerprint("pool/a", 10, "sha256", "secret"),
+	)
+	server.finishGeneration("key", current)
+
+	for _, fingerprint := range []string{
+		getGenerationFingerprint("pool/b", 10, "sha256", "secret"),
+		getGenerationFingerprint("pool/a", 11, "sha256", "secret"),
+		getGenerationFingerprint("pool/a", 10, "sha512", "secret"),
+		getGenerationFingerprint("pool/a", 10, "sha256", "other"),
+	} {
+		_, previous := server.startGeneration("key", fingerprint)
+		if previous == nil || previous.matches {
+			t.Fatalf("request with other parameters matches previous one")
+		}
+	}
+
+	_, previous := server.startGeneration(
+		"key", getGenerationFingerprint("pool/a", 10, "sha256", "secret"),
+	)
+	if previous == nil || !previous.matches {
+		t.Fatal("request with the same parameters doesn't match")
+	}
+}
@@ -0,0 +1,328 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/reconquest/hierr-go"
+)
+
+// shellCommand is command of interactive shell, args don't include name of
+// command, options are command line options shell has been started with.
+type shellCommand struct {
+	usage       string
+	description string
+	run         func(
+		backend Backend, options map[string]interface{}, args []string,
+	) error
+}
+
+var shellCommands map[string]shellCommand
+
+func init() {
+	// commands are assigned in init, because help command refers to them
+	shellCommands = map[string]shellCommand{
+		"list": {
+			"list [<prefix>]", "List tokens, all tokens by default.",
+			runShellList,
+		},
+		"info": {
+			"info <token>", "Show size and metadata of hash table.",
+			runShellInfo,
+		},
+		"generate": {
+			"generate <token> [<length>] [<algo>]",
+			"Generate hash table, password is read from input.",
+			runShellGenerate,
+		},
+		"delete": {
+			"delete <token>", "Remove hash table and its metadata.",
+			runShellDelete,
+		},
+		"verify": {
+			"verify <token>",
+			"Check password read from input against stored verifier.",
+			runShellVerify,
+		},
+		"recent": {
+			"recent <token>", "List recent clients of token.",
+			runShellRecent,
+		},
+		"help": {
+			"help", "Show available commands.",
+			runShellHelp,
+		},
+	}
+}
+
+// handleShell reads commands from stdin and runs them against backend,
+// which is kept open between commands. Failed command doesn't stop shell,
+// shell exits on exit command or end of input.
+func handleShell(backend Backend, options map[string]interface{}) error {
+	terminal, err := isStdinTerminal()
+	if err != nil {
+		return err
+	}
+
+	for {
+		if terminal {
+			fmt.Print("shadowd> ")
+		}
+
+		line, err := stdin.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return hierr.Errorf(err, "can't read command")
+		}
+
+		if line == "" && err == io.EOF {
+			if terminal {
+				fmt.Println()
+			}
+
+			return nil
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		if fields[0] == "exit" || fields[0] == "quit" {
+			return nil
+		}
+
+		shellErr := runShellCommand(backend, options, fields[0], fields[1:])
+		if shellErr != nil {
+			fmt.Printf("Error: %s\n", shellErr)
+		}
+	}
+}
+
+func runShellCommand(
+	backend Backend,
+	options map[string]interface{},
+	name string,
+	args []string,
+) error {
+	command, ok := shellCommands[name]
+	if !ok {
+		return fmt.Errorf("unknown command '%s', use help", name)
+	}
+
+	return command.run(backend, options, args)
+}
+
+// getShellToken returns the only argument of command, which is token.
+func getShellToken(args []string) (string, error) {
+	if len(args) != 1 {
+		return "", errors.New("exactly one token should be specified")
+	}
+
+	return args[0], validateToken(args[0])
+}
+
+func runShellList(
+	backend Backend, options map[string]interface{}, args []string,
+) error {
+	var (
+		tokens []string
+		err    error
+	)
+
+	switch len(args) {
+	case 0:
+		tokens, err = backend.GetAllTokens()
+	case 1:
+		tokens, err = backend.GetTokens(args[0])
+	default:
+		return errors.New("only one prefix can be specified")
+	}
+
+	if err != nil {
+		return err
+	}
+
+	for _, token := range tokens {
+		fmt.Println(token)
+	}
+
+	return nil
+}
+
+func runShellInfo(
+	backend Backend, options map[string]interface{}, args []string,
+) error {
+	token, err := getShellToken(args)
+	if err != nil {
+		return err
+	}
+
+	size, err := backend.GetTableSize(token)
+	if err != nil {
+		return hierr.Errorf(err, "hash table %s", token)
+	}
+
+	info, err := backend.GetTokenInfo(token)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Length: %d\n", size)
+	fmt.Printf("Served: %d\n", info.Served)
+
+	if info.Created != nil {
+		fmt.Printf("Created: %s\n", info.Created.Format(time.RFC3339))
+	}
+
+	if info.Sunset != nil {
+		fmt.Printf("Sunset: %s\n", info.Sunset.Format(time.RFC3339))
+	}
+
+	if info.Banner != "" {
+		fmt.Printf("Banner: %s\n", info.Banner)
+	}
+
+	fmt.Printf("Rotate: %t\n", !info.NoRotate)
+	fmt.Printf("Shared: %t\n", info.Shared)
+	fmt.Printf("Verifier stored: %t\n", info.Verifier != "")
+
+	return nil
+}
+
+// runShellGenerate generates hash table the same way as -G does, so options
+// shell has been started with, like --policy or --generation-webhook, apply
+// to every generated hash table.
+func runShellGenerate(
+	backend Backend, options map[string]interface{}, args []string,
+) error {
+	if len(args) < 1 || len(args) > 3 {
+		return fmt.Errorf("usage: %s", shellCommands["generate"].usage)
+	}
+
+	generateArgs := map[string]interface{}{}
+	for key, value := range options {
+		generateArgs[key] = value
+	}
+
+	generateArgs["<token>"] = args[0]
+	generateArgs["--length"] = "2048"
+	generateArgs["--algorithm"] = "sha256"
+	generateArgs["--no-confirm"] = false
+	generateArgs["--progress"] = progressNone
+
+	if len(args) > 1 {
+		length, err := strconv.Atoi(args[1])
+		if err != nil || length <= 0 {
+			return fmt.Errorf("invalid table length: %s", args[1])
+		}
+
+		generateArgs["--length"] = args[1]
+	}
+
+	if len(args) > 2 {
+		generateArgs["--algorithm"] = args[2]
+	}
+
+	return handleTableGenerate(backend, generateArgs)
+}
+
+func runShellDelete(
+	backend Backend, options map[string]interface{}, args []string,
+) error {
+	token, err := getShellToken(args)
+	if err != nil {
+		return err
+	}
+
+	err = backend.DeleteHashTable(token)
+	if err != nil {
+		return hierr.Errorf(err, "hash table %s", token)
+	}
+
+	fmt.Printf("Hash table %s removed.\n", token)
+
+	return nil
+}
+
+func runShellVerify(
+	backend Backend, options map[string]interface{}, args []string,
+) error {
+	token, err := getShellToken(args)
+	if err != nil {
+		return err
+	}
+
+	info, err := backend.GetTokenInfo(token)
+	if err != nil {
+		return err
+	}
+
+	if info.Verifier == "" {
+		return fmt.Errorf("verifier for %s is not stored", token)
+	}
+
+	password, err := getPassword("Enter password: ")
+	if err != nil {
+		return err
+	}
+
+	matches, err := checkVerifier(info.Verifier, password)
+	if err != nil {
+		return err
+	}
+
+	if !matches {
+		return errors.New("password does not match hash table")
+	}
+
+	fmt.Printf("Password matches hash table %s.\n", token)
+
+	return nil
+}
+
+func runShellRecent(
+	backend Backend, options map[string]interface{}, args []string,
+) error {
+	token, err := getShellToken(args)
+	if err != nil {
+		return err
+	}
+
+	clients, err := backend.ListRecentClients(token)
+	if err != nil {
+		return err
+	}
+
+	for _, client := range clients {
+		fmt.Printf(
+			"%s %s\n", client.Client, client.LastSeen.Format(time.RFC3339),
+		)
+	}
+
+	return nil
+}
+
+func runShellHelp(
+	backend Backend, options map[string]interface{}, args []string,
+) error {
+	names := []string{}
+	for name := range shellCommands {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		command := shellCommands[name]
+		fmt.Printf("%s\n    %s\n", command.usage, command.description)
+	}
+
+	fmt.Printf("exit\n    Leave shell.\n")
+
+	return nil
+}
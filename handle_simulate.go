@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/reconquest/hierr-go"
+)
+
+// handleSimulate computes indexes of hash table entries, which would be
+// served to specified clients during current time slot, and reports how
+// evenly clients are distributed across hash table. Nothing is served and
+// clients are not marked as recent.
+func handleSimulate(
+	backend Backend, args map[string]interface{}, hashTTL time.Duration,
+) error {
+	var (
+		token       = args["--token"].(string)
+		clientsFile = args["--clients"].(string)
+	)
+
+	clients, err := readSimulatedClients(clientsFile)
+	if err != nil {
+		return usageError{
+			hierr.Errorf(err, "can't read clients from %s", clientsFile),
+		}
+	}
+
+	if len(clients) == 0 {
+		return usageError{
+			fmt.Errorf("no clients found in %s", clientsFile),
+		}
+	}
+
+	var size int64
+	if raw, ok := args["--size"].(string); ok {
+		size, err = strconv.ParseInt(raw, 10, 64)
+		if err != nil || size <= 0 {
+			return usageError{fmt.Errorf("invalid table size: %s", raw)}
+		}
+	} else {
+		size, err = backend.GetTableSize(token)
+		if err != nil {
+			if err == ErrNotFound {
+				return hierr.Errorf(err, "hash table %s", token)
+			}
+
+			return backendError{
+				hierr.Errorf(err, "can't get hash table %s", token),
+			}
+		}
+	}
+
+	seed, err := readIndexSeed(args)
+	if err != nil {
+		return err
+	}
+
+	info, err := backend.GetTokenInfo(token)
+	if err != nil {
+		return backendError{
+			hierr.Errorf(err, "can't get metadata of %s", token),
+		}
+	}
+
+	// clients of tokens, which should not be rotated, are served index of
+	// zero time slot, like in selectIndex
+	slot := getTimeSlot(time.Now(), hashTTL)
+	if info.NoRotate {
+		slot = 0
+	}
+
+	histogram := map[int64]int{}
+	for _, client := range clients {
+		// shared token serves the first entry to all clients
+		if info.Shared {
+			histogram[0]++
+			continue
+		}
+
+		input := hashInput{
+			seed:    seed,
+			client:  client,
+			token:   token,
+			purpose: hashPurposeServe,
+			slot:    slot,
+		}
+
+		histogram[deriveIndex(input, size, 0).number]++
+	}
+
+	printSimulation(len(clients), size, histogram)
+
+	return nil
+}
+
+// readSimulatedClients reads client addresses, one per line, empty lines
+// and lines starting with # are skipped.
+func readSimulatedClients(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	clients := []string{}
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		client := strings.TrimSpace(scanner.Text())
+		if client == "" || strings.HasPrefix(client, "#") {
+			continue
+		}
+
+		clients = append(clients, client)
+	}
+
+	return clients, scanner.Err()
+}
+
+// printSimulation prints summary of simulated distribution followed by
+// amount of clients for every used index.
+func printSimulation(clients int, size int64, histogram map[int64]int) {
+	indexes := []int64{}
+	maxCollisions := 0
+	for index, count := range histogram {
+		indexes = append(indexes, index)
+
+		if count > maxCollisions {
+			maxCollisions = count
+		}
+	}
+
+	sort.Slice(indexes, func(i, j int) bool {
+		return indexes[i] < indexes[j]
+	})
+
+	fmt.Printf("Clients: %d\n", clients)
+	fmt.Printf("Table length: %d\n", size)
+	fmt.Printf("Distinct indexes: %d\n", len(indexes))
+	fmt.Printf("Max collisions: %d\n", maxCollisions)
+	fmt.Printf(
+		"Coverage: %.2f%%\n", float64(len(indexes))/float64(size)*100,
+	)
+
+	for _, index := range indexes {
+		fmt.Printf("%d: %d\n", index, histogram[index])
+	}
+}
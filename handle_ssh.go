@@ -14,15 +14,26 @@ import (
 	"golang.org/x/crypto/ssh"
 )
 
+// HandleSSH serves public SSH keys of token, token which key file exists,
+// but contains no keys, is responded with 204 No Content, so clients can
+// distinguish it from unknown token.
 func (server *Server) HandleSSH(
-	writer http.ResponseWriter, request *http.Request,
+	writer http.ResponseWriter, request *http.Request, token string,
 ) {
-	token := strings.TrimPrefix(request.URL.Path, "/ssh/")
+	if request.Method != "GET" {
+		writer.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !server.servedTokens.allows(token) {
+		server.writeNotFound(writer, request, token)
+		return
+	}
 
 	keys, err := server.backend.GetPublicKeys(token)
 	if err != nil {
 		if err == ErrNotFound {
-			writer.WriteHeader(http.StatusNotFound)
+			server.writeNotFound(writer, request, token)
 			return
 		}
 
@@ -31,6 +42,11 @@ func (server *Server) HandleSSH(
 		return
 	}
 
+	if strings.TrimSpace(keys) == "" {
+		writer.WriteHeader(http.StatusNoContent)
+		return
+	}
+
 	_, err = writer.Write([]byte(keys))
 	if err != nil {
 		log.Println(err)
@@ -61,9 +77,9 @@ func handleSSHKeyAppend(backend Backend, args map[string]interface{}) error {
 
 	err = backend.AddPublicKey(token, key, truncate)
 	if err != nil {
-		return hierr.Errorf(
-			err, "can't add public key for %s", token,
-		)
+		return backendError{
+			hierr.Errorf(err, "can't add public key for %s", token),
+		}
 	}
 
 	fmt.Println("Added new key with comment:", comment)
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/reconquest/hierr-go"
+)
+
+// handleTableCheck checks that password, entered on stdin, has been used for
+// generating hash table of specified token.
+func handleTableCheck(backend Backend, args map[string]interface{}) error {
+	token := args["<token>"].(string)
+
+	err := validateToken(token)
+	if err != nil {
+		return usageError{err}
+	}
+
+	info, err := backend.GetTokenInfo(token)
+	if err != nil {
+		return backendError{
+			hierr.Errorf(err, "can't get metadata for %s", token),
+		}
+	}
+
+	if info.Verifier == "" {
+		return hierr.Errorf(
+			ErrNotFound,
+			"verifier for %s is not stored, use --store-verifier "+
+				"on hash table generation",
+			token,
+		)
+	}
+
+	password, err := getPassword("Enter password: ")
+	if err != nil {
+		return hierr.Errorf(
+			err, "can't get password",
+		)
+	}
+
+	matches, err := checkVerifier(info.Verifier, password)
+	if err != nil {
+		return hierr.Errorf(
+			err, "can't check password",
+		)
+	}
+
+	if !matches {
+		return errors.New("password does not match hash table")
+	}
+
+	fmt.Printf("Password matches hash table %s.\n", token)
+
+	return nil
+}
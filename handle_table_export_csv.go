@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/reconquest/hierr-go"
+)
+
+var tokensCSVHeader = []string{
+	"token", "algorithm", "length", "created", "served", "expiry",
+}
+
+// handleTokenExportCSV writes all tokens with metadata of their hash tables
+// as CSV to specified file, "-" means stdout.
+func handleTokenExportCSV(backend Backend, path string) error {
+	tokens, err := backend.GetAllTokens()
+	if err != nil {
+		return backendError{hierr.Errorf(err, "can't get tokens")}
+	}
+
+	rows := [][]string{tokensCSVHeader}
+	for _, token := range tokens {
+		row, err := getTokenCSVRow(backend, token)
+		if err != nil {
+			return backendError{err}
+		}
+
+		rows = append(rows, row)
+	}
+
+	var output io.Writer = os.Stdout
+	if path != "-" {
+		file, err := os.Create(path)
+		if err != nil {
+			return hierr.Errorf(err, "can't create file %s", path)
+		}
+
+		defer file.Close()
+
+		output = file
+	}
+
+	writer := csv.NewWriter(output)
+
+	err = writer.WriteAll(rows)
+	if err != nil {
+		return hierr.Errorf(err, "can't write CSV")
+	}
+
+	if path != "-" {
+		fmt.Printf("%d tokens exported to %s.\n", len(tokens), path)
+	}
+
+	return nil
+}
+
+func getTokenCSVRow(backend Backend, token string) ([]string, error) {
+	size, err := backend.GetTableSize(token)
+	if err != nil {
+		return nil, hierr.Errorf(err, "can't get size of hash table %s", token)
+	}
+
+	info, err := backend.GetTokenInfo(token)
+	if err != nil {
+		return nil, hierr.Errorf(err, "can't get metadata for %s", token)
+	}
+
+	algorithm := ""
+	if size > 0 {
+		record, err := backend.GetHash(token, 0)
+		if err != nil {
+			return nil, hierr.Errorf(
+				err, "can't get first record of %s", token,
+			)
+		}
+
+		parsed, err := parseRecord(record)
+		if err == nil {
+			algorithm = getPolicyAlgorithm(parsed.id)
+		}
+	}
+
+	return []string{
+		token,
+		algorithm,
+		strconv.FormatInt(size, 10),
+		formatCSVTime(info.Created),
+		strconv.FormatInt(info.Served, 10),
+		formatCSVTime(info.Sunset),
+	}, nil
+}
+
+func formatCSVTime(value *time.Time) string {
+	if value == nil {
+		return ""
+	}
+
+	return value.UTC().Format(time.RFC3339)
+}
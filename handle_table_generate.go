@@ -4,9 +4,9 @@ import (
 	"bufio"
 	"errors"
 	"fmt"
-	"math/rand"
 	"os"
 	"os/exec"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -30,7 +30,20 @@ var (
 	saltLength = 16
 )
 
-type AlgorithmImplementation func(token string) string
+const (
+	progressSpinner = "spinner"
+	progressPlain   = "plain"
+	progressNone    = "none"
+
+	// plainProgressStep is percentage step of plain progress output.
+	plainProgressStep = 10
+
+	// streamBufferSize is amount of generated records, which can wait for
+	// being stored by backend during streaming generation.
+	streamBufferSize = 1000
+)
+
+type AlgorithmImplementation func(password string) (string, error)
 
 func handleTableGenerate(backend Backend, args map[string]interface{}) error {
 	var (
@@ -39,71 +52,252 @@ func handleTableGenerate(backend Backend, args map[string]interface{}) error {
 		algorithm = args["--algorithm"].(string)
 		quiet     = args["--quiet"].(bool)
 		noconfirm = args["--no-confirm"].(bool)
+		confirm   = args["--confirm"].(bool)
+		mode      = args["--progress"].(string)
+		verify    = args["--store-verifier"].(bool)
+
+		confirmThresholdRaw = args["--confirm-threshold"].(string)
+		allowEmpty          = args["--allow-empty-password"].(bool)
+		stream              = args["--stream"].(bool)
+		noClobber           = args["--no-clobber"].(bool)
+		force               = args["--force"].(bool)
 	)
 
+	seed, deterministic := args["--deterministic-salt"].(string)
+	if deterministic {
+		if !args["--allow-deterministic-salt"].(bool) {
+			return usageError{
+				errors.New(
+					"deterministic salts are predictable and unsafe for " +
+						"production, use --allow-deterministic-salt " +
+						"for test tables",
+				),
+			}
+		}
+
+		value, err := strconv.ParseInt(seed, 10, 64)
+		if err != nil {
+			return usageError{
+				fmt.Errorf("invalid deterministic salt seed: %s", seed),
+			}
+		}
+
+		useDeterministicSalt(value)
+
+		fmt.Fprintln(
+			os.Stderr,
+			"Warning: salts are derived from seed, "+
+				"hash table is not safe for production",
+		)
+	}
+
+	var sunset *time.Time
+	if raw, ok := args["--sunset"].(string); ok {
+		date, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return usageError{hierr.Errorf(err, "can't parse sunset date")}
+		}
+
+		sunset = &date
+	}
+
 	err := validateToken(token)
 	if err != nil {
-		return err
+		return usageError{err}
+	}
+
+	var sink EventSink
+	if target, ok := args["--event-sink"].(string); ok {
+		sink, err = newEventSink(target)
+		if err != nil {
+			return usageError{err}
+		}
+	}
+
+	var policy *algorithmPolicy
+	if path, ok := args["--policy"].(string); ok {
+		policy, err = loadAlgorithmPolicy(path)
+		if err != nil {
+			return usageError{
+				hierr.Errorf(err, "can't load algorithm policy"),
+			}
+		}
+
+		policyAlgorithm := algorithm
+		if id, ok := args["--crypt-id"].(string); ok {
+			policyAlgorithm = getPolicyAlgorithm(id)
+		}
+
+		err = policy.check(token, policyAlgorithm)
+		if err != nil {
+			return usageError{err}
+		}
+	}
+
+	if quiet {
+		mode = progressNone
+	}
+
+	switch mode {
+	case progressSpinner, progressPlain, progressNone:
+	default:
+		return usageError{
+			fmt.Errorf("unknown progress mode '%s'", mode),
+		}
 	}
 
 	length, err := strconv.Atoi(lengthRaw)
+	if err != nil {
+		return usageError{hierr.Errorf(err, "can't parse table length")}
+	}
+
+	confirmThreshold, err := strconv.ParseInt(confirmThresholdRaw, 10, 64)
+	if err != nil {
+		return usageError{
+			hierr.Errorf(err, "can't parse confirmation threshold"),
+		}
+	}
+
+	err = checkTableRegenerate(
+		backend, token, confirmThreshold, confirm, noClobber && !force, quiet,
+	)
 	if err != nil {
 		return err
 	}
 
-	password, err := getPassword("Enter password: ")
+	event := generationEvent{
+		Token:     token,
+		Length:    length,
+		Algorithm: algorithm,
+		Operator:  getOperator(),
+	}
+
+	cryptID, _ := getAlgorithmID(algorithm)
+
+	implementation := getAlgorithmImplementation(algorithm)
+	if id, ok := args["--crypt-id"].(string); ok {
+		if !cryptIDPattern.MatchString(id) {
+			return usageError{fmt.Errorf("invalid crypt id '%s'", id)}
+		}
+
+		event.Algorithm = "crypt:" + id
+
+		algorithm = fmt.Sprintf("crypt id '%s'", id)
+		cryptID = id
+		implementation = getCryptImplementation(id, saltLength)
+	}
+
+	if implementation == nil {
+		err = usageError{
+			fmt.Errorf("algorithm %s is not available", algorithm),
+		}
+	} else {
+		err = probeAlgorithm(algorithm, implementation, verifyRecord)
+	}
+
 	if err != nil {
-		return hierr.Errorf(
-			err, "can't get password",
+		fallback, ok := args["--algorithm-fallback"].(string)
+		if !ok {
+			return err
+		}
+
+		implementation, err = getFallbackImplementation(
+			token, fallback, policy, err,
 		)
+		if err != nil {
+			return err
+		}
+
+		cryptID, _ = getAlgorithmID(fallback)
+		event.Algorithm = fallback
 	}
 
-	if !noconfirm {
-		proofPassword, err := getPassword("Retype password: ")
-		if err != nil {
-			return hierr.Errorf(
-				err, "can't get password confirmation",
-			)
+	if name, native := getNativeAlgorithmByID(cryptID); native != nil {
+		if native.randomSalt && deterministic {
+			return usageError{
+				fmt.Errorf(
+					"%s salts can't be derived from seed, "+
+						"--deterministic-salt can't be used with it",
+					name,
+				),
+			}
 		}
 
-		if password != proofPassword {
-			return fmt.Errorf("specified passwords do not match")
+		warnUnverifiableByHost(implementation, cryptID)
+	}
+
+	if target, ok := args["--target-os"].(string); ok {
+		err = checkTargetOS(target, cryptID)
+		if err != nil {
+			return err
 		}
 	}
 
-	implementation := getAlgorithmImplementation(algorithm)
-	if implementation == nil {
-		return errors.New("specified algorithm is not available")
+	password, err := promptPassword(!noconfirm, allowEmpty)
+	if err != nil {
+		return err
 	}
 
-	if !quiet {
+	var progress func(percent int)
+	switch mode {
+	case progressSpinner:
 		spinner.Start()
 		spinner.SetInterval(time.Millisecond * 100)
-	}
 
-	table := []string{}
-	for i := 0; i < length; i++ {
-		if !quiet {
+		progress = func(percent int) {
 			spinner.SetStatus(
-				fmt.Sprintf(
-					"Generating hash table... %d%% ",
-					(i+1)*100/length,
-				),
+				fmt.Sprintf("Generating hash table... %d%% ", percent),
 			)
 		}
 
-		table = append(table, implementation(password))
+	case progressPlain:
+		reported := 0
+		progress = func(percent int) {
+			if percent/plainProgressStep == reported/plainProgressStep {
+				return
+			}
+
+			reported = percent
+
+			fmt.Printf("Generating hash table... %d%%\n", percent)
+		}
 	}
 
-	if !quiet {
+	if stream {
+		err = generateHashTableStream(
+			backend, token, implementation, password, length, progress,
+		)
+	} else {
+		err = generateAndSetHashTable(
+			backend, token, implementation, password, length, progress,
+		)
+	}
+
+	if mode == progressSpinner {
 		spinner.Stop()
 	}
 
-	err = backend.SetHashTable(token, table)
 	if err != nil {
-		return hierr.Errorf(
-			err, "can't save generated hash table",
-		)
+		return err
+	}
+
+	verifier := ""
+	if verify {
+		verifier, err = generateVerifier(password)
+		if err != nil {
+			return hierr.Errorf(
+				err, "can't generate password verifier",
+			)
+		}
+	}
+
+	err = updateTokenInfo(backend, token, func(info *tokenInfo) {
+		info.Verifier = verifier
+		info.Sunset = sunset
+		info.Parameters = newTableParameters(cryptID, saltLength)
+	})
+	if err != nil {
+		return backendError{err}
 	}
 
 	fmt.Printf(
@@ -111,9 +305,238 @@ func handleTableGenerate(backend Backend, args map[string]interface{}) error {
 		token, length,
 	)
 
+	event.Timestamp = time.Now()
+
+	if url, ok := args["--generation-webhook"].(string); ok {
+		err = notifyGeneration(url, event)
+		if err != nil {
+			fmt.Fprintf(
+				os.Stderr, "Warning: can't notify generation webhook: %s\n",
+				err,
+			)
+		}
+	}
+
+	if sink != nil {
+		defer sink.Close()
+
+		err = sink.Publish(eventTypeGeneration, event)
+		if err != nil {
+			fmt.Fprintf(
+				os.Stderr, "Warning: can't publish generation event: %s\n",
+				err,
+			)
+		}
+	}
+
+	return nil
+}
+
+// checkTableRegenerate reports what will be affected by regeneration of
+// existing hash table and refuses to regenerate large hash tables without
+// confirmation. Existing hash tables are not regenerated at all if
+// noClobber is set.
+func checkTableRegenerate(
+	backend Backend,
+	token string,
+	threshold int64,
+	confirm bool,
+	noClobber bool,
+	quiet bool,
+) error {
+	size, err := backend.GetTableSize(token)
+	if err != nil {
+		if err == ErrNotFound {
+			return nil
+		}
+
+		return backendError{
+			hierr.Errorf(err, "can't get size of existing hash table"),
+		}
+	}
+
+	if noClobber {
+		return usageError{
+			fmt.Errorf(
+				"hash table %s already exists, use --force to overwrite it",
+				token,
+			),
+		}
+	}
+
+	clients, err := backend.GetRecentClientsCount(token)
+	if err != nil {
+		return backendError{
+			hierr.Errorf(err, "can't get recent clients count"),
+		}
+	}
+
+	if !quiet {
+		fmt.Printf(
+			"Hash table %s already exists and will be regenerated:\n"+
+				"  table size: %d\n"+
+				"  recent clients: %d\n"+
+				"  estimated affected hosts: %d\n",
+			token, size, clients, clients,
+		)
+	}
+
+	if size > threshold && !confirm {
+		return usageError{
+			fmt.Errorf(
+				"hash table %s has %d items (more than %d), "+
+					"use --confirm to regenerate it",
+				token, size, threshold,
+			),
+		}
+	}
+
 	return nil
 }
 
+func generateHashTable(
+	implementation AlgorithmImplementation,
+	password string,
+	length int,
+	progress func(percent int),
+) ([]string, error) {
+	table := make([]string, 0, length)
+	for i := 0; i < length; i++ {
+		if progress != nil {
+			progress((i + 1) * 100 / length)
+		}
+
+		record, err := implementation(password)
+		if err != nil {
+			return nil, err
+		}
+
+		table = append(table, record)
+	}
+
+	return table, nil
+}
+
+func generateAndSetHashTable(
+	backend Backend,
+	token string,
+	implementation AlgorithmImplementation,
+	password string,
+	length int,
+	progress func(percent int),
+) error {
+	table, err := generateHashTable(implementation, password, length, progress)
+	if err != nil {
+		return hierr.Errorf(
+			err, "can't generate hash table",
+		)
+	}
+
+	err = backend.SwapHashTable(token, table)
+	if err != nil {
+		return backendError{
+			hierr.Errorf(err, "can't save generated hash table"),
+		}
+	}
+
+	return nil
+}
+
+// generateHashTableStream stores records in backend while they are
+// generated, so only streamBufferSize records are held in memory.
+func generateHashTableStream(
+	backend Backend,
+	token string,
+	implementation AlgorithmImplementation,
+	password string,
+	length int,
+	progress func(percent int),
+) error {
+	var (
+		records = make(chan string, streamBufferSize)
+		errs    = make(chan error, 1)
+	)
+
+	go func() {
+		errs <- streamHashTable(
+			implementation, password, length, progress, records,
+		)
+	}()
+
+	err := backend.SetHashTableStream(token, length, records)
+
+	// generation error is the cause of incomplete stream, so it is reported
+	// instead of backend error
+	generateErr := <-errs
+	if generateErr != nil {
+		return hierr.Errorf(
+			generateErr, "can't generate hash table",
+		)
+	}
+
+	if err != nil {
+		return backendError{
+			hierr.Errorf(err, "can't save generated hash table"),
+		}
+	}
+
+	return nil
+}
+
+// streamHashTable generates records of hash table and sends them to
+// specified channel, channel is closed when generation is finished or
+// failed.
+func streamHashTable(
+	implementation AlgorithmImplementation,
+	password string,
+	length int,
+	progress func(percent int),
+	records chan<- string,
+) error {
+	defer close(records)
+
+	for i := 0; i < length; i++ {
+		if progress != nil {
+			progress((i + 1) * 100 / length)
+		}
+
+		record, err := implementation(password)
+		if err != nil {
+			return err
+		}
+
+		records <- record
+	}
+
+	return nil
+}
+
+// cryptIDPattern matches crypt(3) algorithm id optionally followed by
+// algorithm parameters, e.g. "6" or "y$j9T".
+var cryptIDPattern = regexp.MustCompile(`^[a-z0-9]+(\$[A-Za-z0-9./=,]+)?$`)
+
+// getCryptImplementation returns implementation, which passes specified
+// crypt(3) algorithm id to setting string as is, so schemes supported by
+// system libcrypt can be used without separate implementation.
+func getCryptImplementation(id string, saltLength int) AlgorithmImplementation {
+	return func(password string) (string, error) {
+		salt, err := getSalt(saltLength)
+		if err != nil {
+			return "", hierr.Errorf(
+				err, "can't get salt",
+			)
+		}
+
+		return crypt(password, fmt.Sprintf("$%s$%s", id, salt)), nil
+	}
+}
+
+// algorithmIDs contains crypt(3) ids of supported algorithms.
+var algorithmIDs = map[string]string{
+	"sha256": "5",
+	"sha512": "6",
+}
+
 func getAlgorithmImplementation(algorithm string) AlgorithmImplementation {
 	switch algorithm {
 	case "sha256":
@@ -122,26 +545,119 @@ func getAlgorithmImplementation(algorithm string) AlgorithmImplementation {
 		return generateSHA512
 	}
 
+	if native, ok := nativeAlgorithms[algorithm]; ok {
+		return native.generate
+	}
+
 	return nil
 }
 
-func generateSHA256(password string) string {
-	salt := fmt.Sprintf("$5$%s", generateSHASalt())
-	return C.GoString(C.crypt(C.CString(password), C.CString(salt)))
+func generateSHA256(password string) (string, error) {
+	salt, err := getSalt(saltLength)
+	if err != nil {
+		return "", hierr.Errorf(
+			err, "can't get salt",
+		)
+	}
+
+	return crypt(password, fmt.Sprintf("$5$%s", salt)), nil
 }
 
-func generateSHA512(password string) string {
-	salt := fmt.Sprintf("$6$%s", generateSHASalt())
-	return C.GoString(C.crypt(C.CString(password), C.CString(salt)))
+func generateSHA512(password string) (string, error) {
+	salt, err := getSalt(saltLength)
+	if err != nil {
+		return "", hierr.Errorf(
+			err, "can't get salt",
+		)
+	}
+
+	return crypt(password, fmt.Sprintf("$6$%s", salt)), nil
 }
 
-func generateSHASalt() string {
-	salt := make([]rune, saltLength)
-	for i := 0; i < saltLength; i++ {
-		salt[i] = saltSymbols[rand.Intn(len(saltSymbols))]
+// getFallbackImplementation returns implementation of fallback algorithm,
+// which is used when requested algorithm can't be used because of specified
+// reason. Fallback is always reported, so it's never used unnoticed.
+func getFallbackImplementation(
+	token string,
+	fallback string,
+	policy *algorithmPolicy,
+	reason error,
+) (AlgorithmImplementation, error) {
+	implementation := getAlgorithmImplementation(fallback)
+	if implementation == nil {
+		return nil, usageError{
+			fmt.Errorf("fallback algorithm %s is not available", fallback),
+		}
 	}
 
-	return string(salt)
+	err := probeAlgorithm(fallback, implementation, verifyRecord)
+	if err != nil {
+		return nil, err
+	}
+
+	if policy != nil {
+		err = policy.check(token, fallback)
+		if err != nil {
+			return nil, usageError{err}
+		}
+	}
+
+	fmt.Fprintf(
+		os.Stderr, "Warning: %s, falling back to %s\n", reason, fallback,
+	)
+
+	return implementation, nil
+}
+
+// probeAlgorithm generates throwaway record using specified implementation
+// and verifies it using specified verify function, so hash table, which
+// can't be verified on current host, will not be generated.
+func probeAlgorithm(
+	algorithm string,
+	implementation AlgorithmImplementation,
+	verify func(password string, record string) bool,
+) error {
+	const password = "probe"
+
+	record, err := implementation(password)
+	if err != nil {
+		return hierr.Errorf(
+			err, "can't generate probe record for %s", algorithm,
+		)
+	}
+
+	if record == "" || !verify(password, record) {
+		return fmt.Errorf("this host cannot verify %s", algorithm)
+	}
+
+	return nil
+}
+
+// warnUnverifiableByHost warns if records of natively implemented algorithm
+// can't be verified by libcrypt of current host, because records are
+// verified by libcrypt of hosts pulling them as well.
+func warnUnverifiableByHost(
+	implementation AlgorithmImplementation, cryptID string,
+) {
+	const password = "probe"
+
+	record, err := implementation(password)
+	if err == nil && crypt(password, record) == record {
+		return
+	}
+
+	fmt.Fprintf(
+		os.Stderr,
+		"Warning: libcrypt of this host cannot verify crypt id '%s', "+
+			"make sure that hosts pulling records support it\n",
+		cryptID,
+	)
+}
+
+// crypt hashes password using crypt(3) with specified setting (algorithm id,
+// optional rounds and salt).
+func crypt(password string, setting string) string {
+	return C.GoString(C.crypt(C.CString(password), C.CString(setting)))
 }
 
 func validateToken(token string) error {
@@ -154,27 +670,107 @@ func validateToken(token string) error {
 	return nil
 }
 
+// promptPassword reads password and, if confirm is set, asks to retype it.
+// Password is never retyped when it is piped, because nobody can mistype it.
+// Empty password is rejected unless allowEmpty is set.
+func promptPassword(confirm bool, allowEmpty bool) (string, error) {
+	password, err := getPassword("Enter password: ")
+	if err != nil {
+		return "", hierr.Errorf(
+			err, "can't get password",
+		)
+	}
+
+	if password == "" && !allowEmpty {
+		return "", usageError{
+			errors.New(
+				"empty password specified, " +
+					"use --allow-empty-password to allow it",
+			),
+		}
+	}
+
+	if !confirm {
+		return password, nil
+	}
+
+	terminal, err := isStdinTerminal()
+	if err != nil {
+		return "", err
+	}
+
+	if !terminal {
+		return password, nil
+	}
+
+	proofPassword, err := getPassword("Retype password: ")
+	if err != nil {
+		return "", hierr.Errorf(
+			err, "can't get password confirmation",
+		)
+	}
+
+	if password != proofPassword {
+		return "", fmt.Errorf("specified passwords do not match")
+	}
+
+	return password, nil
+}
+
+// stdin is shared between password prompts, so buffered but not yet read
+// input is not lost when password is piped.
+var stdin = bufio.NewReader(os.Stdin)
+
 func getPassword(prompt string) (string, error) {
+	fmt.Print(prompt)
+
+	terminal, err := isStdinTerminal()
+	if err != nil {
+		return "", err
+	}
+
+	// password is piped, there is no echo to disable
+	if !terminal {
+		defer fmt.Println()
+
+		return readPassword()
+	}
+
 	var (
 		sttyEchoDisable = exec.Command("stty", "-F", "/dev/tty", "-echo")
 		sttyEchoEnable  = exec.Command("stty", "-F", "/dev/tty", "echo")
 	)
 
-	fmt.Print(prompt)
+	// echo should be restored even if it has been disabled only partially
+	defer func() {
+		sttyEchoEnable.Run()
+		fmt.Println()
+	}()
 
-	err := sttyEchoDisable.Run()
+	err = sttyEchoDisable.Run()
 	if err != nil {
 		return "", hierr.Errorf(
-			err, "%q", sttyEchoDisable.Args,
+			err,
+			"no terminal available for reading password, "+
+				"pipe password to stdin instead",
 		)
 	}
 
-	defer func() {
-		sttyEchoEnable.Run()
-		fmt.Println()
-	}()
+	return readPassword()
+}
+
+func isStdinTerminal() (bool, error) {
+	stat, err := os.Stdin.Stat()
+	if err != nil {
+		return false, hierr.Errorf(
+			err, "can't stat stdin",
+		)
+	}
+
+	return stat.Mode()&os.ModeCharDevice != 0, nil
+}
 
-	stdin := bufio.NewReader(os.Stdin)
+func readPassword() (string, error) {
 	password, err := stdin.ReadString('\n')
 	if err != nil {
 		return "", hierr.Errorf(
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/reconquest/hierr-go"
+)
+
+func handleTableImport(backend Backend, args map[string]interface{}) error {
+	var (
+		token        = args["<token>"].(string)
+		lenient      = args["--lenient"].(bool)
+		shadowFormat = args["--shadow-format"].(bool)
+	)
+
+	err := validateToken(token)
+	if err != nil {
+		return usageError{err}
+	}
+
+	var (
+		table    = []string{}
+		expiries = []time.Time{}
+		expiring = false
+		invalid  = 0
+		line     = 0
+
+		// malformed lines can't be imported even with --lenient
+		malformed = 0
+	)
+
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		line++
+
+		var (
+			record string
+			expiry time.Time
+		)
+
+		if shadowFormat {
+			text := strings.TrimSpace(scanner.Text())
+			if text == "" {
+				continue
+			}
+
+			record, err = parseShadowLine(text)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: line %d: %s\n", line, err)
+
+				malformed++
+				continue
+			}
+		} else {
+			fields := strings.Fields(scanner.Text())
+			if len(fields) == 0 {
+				continue
+			}
+
+			record = fields[0]
+
+			expiry, err = parseRecordExpiry(fields[1:])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: line %d: %s\n", line, err)
+
+				malformed++
+			}
+		}
+
+		if !expiry.IsZero() {
+			expiring = true
+		}
+
+		expiries = append(expiries, expiry)
+
+		err = validateRecord(record)
+		if err != nil {
+			if lenient {
+				fmt.Fprintf(
+					os.Stderr, "Warning: line %d: %s, importing anyway\n",
+					line, err,
+				)
+			} else {
+				fmt.Fprintf(os.Stderr, "Error: line %d: %s\n", line, err)
+
+				invalid++
+			}
+		}
+
+		table = append(table, record)
+	}
+
+	err = scanner.Err()
+	if err != nil {
+		return hierr.Errorf(
+			err, "can't read stdin",
+		)
+	}
+
+	if malformed > 0 {
+		return usageError{
+			fmt.Errorf("%d malformed lines found", malformed),
+		}
+	}
+
+	if invalid > 0 {
+		return usageError{
+			fmt.Errorf(
+				"%d invalid records found, use --lenient to import them",
+				invalid,
+			),
+		}
+	}
+
+	if len(table) == 0 {
+		return usageError{errors.New("no records found on stdin")}
+	}
+
+	if !expiring {
+		expiries = nil
+	}
+
+	err = storeImportedHashTable(backend, token, table, expiries)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf(
+		"Hash table %s with %d items successfully imported.\n",
+		token, len(table),
+	)
+
+	return nil
+}
+
+// parseRecordExpiry parses optional expiry of imported record, which follows
+// record on the same line in RFC3339 format. Zero time is returned for
+// records without expiry.
+func parseRecordExpiry(fields []string) (time.Time, error) {
+	switch len(fields) {
+	case 0:
+		return time.Time{}, nil
+
+	case 1:
+		expiry, err := time.Parse(time.RFC3339, fields[0])
+		if err != nil {
+			return time.Time{}, hierr.Errorf(err, "can't parse expiry")
+		}
+
+		return expiry, nil
+	}
+
+	return time.Time{}, errors.New(
+		"line should contain record and optional expiry only",
+	)
+}
+
+// parseShadowLine returns crypt record from line of /etc/shadow file, which
+// is the second of colon separated fields:
+//
+//	<user>:<record>:<last change>:...
+func parseShadowLine(line string) (string, error) {
+	fields := strings.Split(line, ":")
+	if len(fields) < 2 {
+		return "", errors.New(
+			"line should contain colon separated user and record",
+		)
+	}
+
+	return fields[1], nil
+}
+
+// storeImportedHashTable stores hash table, which records have been hashed
+// elsewhere, so stored password verifier is not valid anymore. Entries
+// expire at specified times if expiries are not nil.
+func storeImportedHashTable(
+	backend Backend, token string, table []string, expiries []time.Time,
+) error {
+	var err error
+	if expiries != nil {
+		err = backend.SetHashTableWithExpiry(token, table, expiries)
+	} else {
+		err = backend.SetHashTable(token, table)
+	}
+
+	if err != nil {
+		return backendError{
+			hierr.Errorf(err, "can't save imported hash table"),
+		}
+	}
+
+	err = updateTokenInfo(backend, token, func(info *tokenInfo) {
+		info.Verifier = ""
+	})
+	if err != nil {
+		return backendError{err}
+	}
+
+	return nil
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/reconquest/hierr-go"
+)
+
+type lowStockToken struct {
+	Token  string `json:"token"`
+	Served int64  `json:"served"`
+	Size   int64  `json:"size"`
+}
+
+// handleTableLowStock lists tokens, which have served larger part of hash
+// table entries than specified threshold, so they should be regenerated.
+func handleTableLowStock(backend Backend, args map[string]interface{}) error {
+	var (
+		thresholdRaw = args["--threshold"].(string)
+		asJSON       = args["--json"].(bool)
+	)
+
+	threshold, err := strconv.ParseFloat(thresholdRaw, 64)
+	if err != nil {
+		return usageError{hierr.Errorf(err, "can't parse threshold")}
+	}
+
+	if threshold < 0 || threshold > 1 {
+		return usageError{errors.New("threshold should be from 0 to 1")}
+	}
+
+	tokens, err := backend.GetAllTokens()
+	if err != nil {
+		return backendError{hierr.Errorf(err, "can't get tokens")}
+	}
+
+	lowStock := []lowStockToken{}
+	for _, token := range tokens {
+		size, err := backend.GetTableSize(token)
+		if err != nil {
+			return backendError{
+				hierr.Errorf(err, "can't get size of hash table %s", token),
+			}
+		}
+
+		info, err := backend.GetTokenInfo(token)
+		if err != nil {
+			return backendError{
+				hierr.Errorf(err, "can't get metadata for %s", token),
+			}
+		}
+
+		if size == 0 || float64(info.Served)/float64(size) <= threshold {
+			continue
+		}
+
+		lowStock = append(lowStock, lowStockToken{
+			Token:  token,
+			Served: info.Served,
+			Size:   size,
+		})
+	}
+
+	if asJSON {
+		return json.NewEncoder(os.Stdout).Encode(lowStock)
+	}
+
+	for _, token := range lowStock {
+		fmt.Printf(
+			"%s: %d of %d entries served\n",
+			token.Token, token.Served, token.Size,
+		)
+	}
+
+	return nil
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/reconquest/hierr-go"
+)
+
+// handleTableRekey regenerates hash table of specified token with another
+// algorithm, size, salt length and rounds of existing hash table are kept.
+func handleTableRekey(backend Backend, args map[string]interface{}) error {
+	var (
+		token     = args["<token>"].(string)
+		algorithm = args["--algorithm"].(string)
+		noconfirm = args["--no-confirm"].(bool)
+
+		allowEmpty = args["--allow-empty-password"].(bool)
+	)
+
+	err := validateToken(token)
+	if err != nil {
+		return usageError{err}
+	}
+
+	id, ok := args["--crypt-id"].(string)
+	if !ok {
+		id, ok = getAlgorithmID(algorithm)
+		if !ok {
+			return usageError{
+				fmt.Errorf("specified algorithm is not available"),
+			}
+		}
+	}
+
+	if !cryptIDPattern.MatchString(id) {
+		return usageError{fmt.Errorf("invalid crypt id '%s'", id)}
+	}
+
+	_, err = backend.GetTableSize(token)
+	if err != nil {
+		if err == ErrNotFound {
+			return hierr.Errorf(err, "hash table %s", token)
+		}
+
+		return backendError{
+			hierr.Errorf(err, "can't get hash table %s", token),
+		}
+	}
+
+	parameters, err := getTableParameters(backend, token)
+	if err != nil {
+		return backendError{err}
+	}
+
+	rekeyed := newTableParameters(id, parameters.SaltLength)
+	if rekeyed.Rounds == "" && isSHACryptID(rekeyed.CryptID) &&
+		isSHACryptID(parameters.CryptID) {
+		rekeyed.Rounds = parameters.Rounds
+	}
+
+	if path, ok := args["--policy"].(string); ok {
+		policy, err := loadAlgorithmPolicy(path)
+		if err != nil {
+			return usageError{
+				hierr.Errorf(err, "can't load algorithm policy"),
+			}
+		}
+
+		err = policy.check(token, getPolicyAlgorithm(rekeyed.CryptID))
+		if err != nil {
+			return usageError{err}
+		}
+	}
+
+	password, err := promptPassword(!noconfirm, allowEmpty)
+	if err != nil {
+		return err
+	}
+
+	err = regenerateHashTable(backend, token, password, rekeyed)
+	if err != nil {
+		return hierr.Errorf(err, "can't rekey hash table %s", token)
+	}
+
+	fmt.Printf(
+		"Hash table %s successfully rekeyed to crypt id '%s'.\n",
+		token, rekeyed.getID(),
+	)
+
+	return nil
+}
+
+// isSHACryptID reports whether specified crypt(3) algorithm id belongs to
+// SHA-crypt family, which shares meaning of rounds parameter.
+func isSHACryptID(id string) bool {
+	for _, known := range algorithmIDs {
+		if id == known {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/reconquest/hierr-go"
+)
+
+// handleRotateAll regenerates hash tables of all tokens with specified
+// prefix, keeping their size and algorithm. Rotation continues when some
+// token fails, failures are reported at the end.
+func handleRotateAll(backend Backend, args map[string]interface{}) error {
+	var (
+		prefix    = args["<prefix>"].(string)
+		perToken  = args["--per-token"].(bool)
+		noconfirm = args["--no-confirm"].(bool)
+
+		allowEmpty = args["--allow-empty-password"].(bool)
+	)
+
+	err := validateToken(prefix)
+	if err != nil {
+		return usageError{err}
+	}
+
+	allTokens, err := backend.GetAllTokens()
+	if err != nil {
+		return backendError{hierr.Errorf(err, "can't get tokens")}
+	}
+
+	tokens := []string{}
+	for _, token := range allTokens {
+		if strings.HasPrefix(token, prefix) {
+			tokens = append(tokens, token)
+		}
+	}
+
+	if len(tokens) == 0 {
+		return hierr.Errorf(ErrNotFound, "no tokens with prefix '%s'", prefix)
+	}
+
+	password := ""
+	if !perToken {
+		password, err = promptPassword(!noconfirm, allowEmpty)
+		if err != nil {
+			return err
+		}
+	}
+
+	failed := 0
+	for _, token := range tokens {
+		if perToken {
+			fmt.Printf("Rotating %s.\n", token)
+
+			password, err = promptPassword(!noconfirm, allowEmpty)
+			if err != nil {
+				return err
+			}
+		}
+
+		err := rotateHashTable(backend, token, password)
+		if err != nil {
+			fmt.Printf("%s: failed: %s\n", token, err)
+			failed++
+			continue
+		}
+
+		fmt.Printf("%s: rotated\n", token)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d tokens failed to rotate", failed, len(tokens))
+	}
+
+	return nil
+}
+
+// rotateHashTable generates new hash table for specified token with the same
+// size and algorithm parameters as existing one.
+func rotateHashTable(backend Backend, token string, password string) error {
+	parameters, err := getTableParameters(backend, token)
+	if err != nil {
+		return err
+	}
+
+	return regenerateHashTable(backend, token, password, parameters)
+}
+
+// regenerateHashTable generates new hash table for specified token with the
+// same size as existing one using specified algorithm parameters, which are
+// stored along with hash table.
+func regenerateHashTable(
+	backend Backend,
+	token string,
+	password string,
+	parameters *tableParameters,
+) error {
+	size, err := backend.GetTableSize(token)
+	if err != nil {
+		return hierr.Errorf(err, "can't get hash table size")
+	}
+
+	id := parameters.getID()
+
+	implementation := getCryptImplementation(id, parameters.SaltLength)
+	if _, native := getNativeAlgorithmByID(id); native != nil {
+		implementation = native.generate
+	}
+
+	err = probeAlgorithm(
+		fmt.Sprintf("crypt id '%s'", id), implementation, verifyRecord,
+	)
+	if err != nil {
+		return err
+	}
+
+	table, err := generateHashTable(implementation, password, int(size), nil)
+	if err != nil {
+		return hierr.Errorf(err, "can't generate hash table")
+	}
+
+	err = backend.SwapHashTable(token, table)
+	if err != nil {
+		return hierr.Errorf(err, "can't save generated hash table")
+	}
+
+	return updateTokenInfo(backend, token, func(info *tokenInfo) {
+		info.Verifier = ""
+		info.Parameters = parameters
+	})
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/reconquest/hierr-go"
+)
+
+// handleTableSet changes metadata of hash table for specified token.
+func handleTableSet(backend Backend, args map[string]interface{}) error {
+	token := args["<token>"].(string)
+
+	err := validateToken(token)
+	if err != nil {
+		return usageError{err}
+	}
+
+	var (
+		banner, setBanner = args["--banner"].(string)
+		maxAgeRaw, setAge = args["--record-max-age"].(string)
+		noRotate          = args["--no-rotate"].(bool)
+		rotate            = args["--rotate"].(bool)
+		shared            = args["--shared"].(bool)
+		unshared          = args["--unshared"].(bool)
+	)
+
+	if !setBanner && !setAge && !noRotate && !rotate && !shared && !unshared {
+		return usageError{
+			errors.New(
+				"nothing to set, use --banner, --record-max-age, " +
+					"--no-rotate, --rotate, --shared or --unshared",
+			),
+		}
+	}
+
+	// banner is sent in HTTP header, so it should be a single line.
+	if strings.IndexFunc(banner, unicode.IsControl) != -1 {
+		return usageError{
+			errors.New("banner should not contain control characters"),
+		}
+	}
+
+	var maxAge time.Duration
+	if setAge {
+		maxAge, err = time.ParseDuration(maxAgeRaw)
+		if err != nil || maxAge < 0 {
+			return usageError{
+				fmt.Errorf("invalid record max age: %s", maxAgeRaw),
+			}
+		}
+	}
+
+	_, err = backend.GetTableSize(token)
+	if err != nil {
+		if err == ErrNotFound {
+			return hierr.Errorf(err, "hash table %s", token)
+		}
+
+		return backendError{
+			hierr.Errorf(err, "can't get hash table %s", token),
+		}
+	}
+
+	err = updateTokenInfo(backend, token, func(info *tokenInfo) {
+		if setBanner {
+			info.Banner = banner
+		}
+
+		if setAge {
+			info.RecordMaxAge = int64(maxAge / time.Second)
+		}
+
+		if noRotate || rotate {
+			info.NoRotate = noRotate
+		}
+
+		if shared || unshared {
+			info.Shared = shared
+		}
+	})
+	if err != nil {
+		return backendError{err}
+	}
+
+	fmt.Printf("Metadata of hash table %s updated.\n", token)
+
+	return nil
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/reconquest/hierr-go"
+)
+
+// handleTail connects to admin endpoint of running server and prints
+// issuance events until server closes connection.
+func handleTail(args map[string]interface{}) error {
+	address := args["<server>"].(string)
+
+	client, err := newAdminClient(args)
+	if err != nil {
+		return err
+	}
+
+	query := url.Values{}
+	if token, ok := args["--token"].(string); ok {
+		query.Set("token", token)
+	}
+
+	if remote, ok := args["--client"].(string); ok {
+		query.Set("client", remote)
+	}
+
+	endpoint := "https://" + address + "/admin/tail?" + query.Encode()
+
+	response, err := client.Get(endpoint)
+	if err != nil {
+		return hierr.Errorf(err, "can't request %s", endpoint)
+	}
+
+	defer response.Body.Close()
+
+	switch response.StatusCode {
+	case http.StatusOK:
+	case http.StatusForbidden:
+		return usageError{
+			fmt.Errorf("client certificate is not accepted by server"),
+		}
+	default:
+		return fmt.Errorf("server responded with %s", response.Status)
+	}
+
+	decoder := json.NewDecoder(response.Body)
+	for {
+		var event issuanceEvent
+
+		err := decoder.Decode(&event)
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+
+			return hierr.Errorf(err, "can't read issuance event")
+		}
+
+		fmt.Printf(
+			"%s %s %s %d\n",
+			event.Time.Format(time.RFC3339), event.Token, event.Client,
+			event.Index,
+		)
+	}
+}
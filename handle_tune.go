@@ -0,0 +1,130 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/reconquest/hierr-go"
+)
+
+const (
+	// tuneSamples is amount of hashes computed for measuring time of rounds
+	// value.
+	tuneSamples = 3
+
+	// tunePrecision is acceptable relative deviation from target time.
+	tunePrecision = 0.05
+)
+
+func handleTune(args map[string]interface{}) error {
+	var (
+		algorithm = args["--algorithm"].(string)
+		targetRaw = args["--target"].(string)
+	)
+
+	id, ok := algorithmIDs[algorithm]
+	if !ok {
+		return usageError{errors.New("specified algorithm is not available")}
+	}
+
+	target, err := time.ParseDuration(targetRaw)
+	if err != nil {
+		return usageError{hierr.Errorf(err, "can't parse target time")}
+	}
+
+	if target <= 0 {
+		return usageError{errors.New("target time should be positive")}
+	}
+
+	rounds, elapsed, err := tuneRounds(id, target)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf(
+		"Recommended rounds for %s: %d (%s per hash).\n",
+		algorithm, rounds, elapsed,
+	)
+
+	return nil
+}
+
+// tuneRounds finds rounds value, which hashing takes specified target time:
+// rounds are doubled until hashing takes longer than target and then binary
+// search is used between last two values.
+func tuneRounds(id string, target time.Duration) (int, time.Duration, error) {
+	var (
+		lower = recordRoundsMin
+		upper = recordRoundsMin
+	)
+
+	elapsed, err := measureRounds(id, upper)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	for elapsed < target && upper < recordRoundsMax {
+		lower = upper
+
+		upper *= 2
+		if upper > recordRoundsMax {
+			upper = recordRoundsMax
+		}
+
+		elapsed, err = measureRounds(id, upper)
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+
+	if elapsed <= target {
+		return upper, elapsed, nil
+	}
+
+	rounds := upper
+	for upper-lower > 1 {
+		if isCloseDuration(elapsed, target) {
+			break
+		}
+
+		rounds = lower + (upper-lower)/2
+
+		elapsed, err = measureRounds(id, rounds)
+		if err != nil {
+			return 0, 0, err
+		}
+
+		if elapsed < target {
+			lower = rounds
+		} else {
+			upper = rounds
+		}
+	}
+
+	return rounds, elapsed, nil
+}
+
+func measureRounds(id string, rounds int) (time.Duration, error) {
+	salt, err := getSalt(saltLength)
+	if err != nil {
+		return 0, hierr.Errorf(
+			err, "can't get salt",
+		)
+	}
+
+	setting := fmt.Sprintf("$%s$rounds=%d$%s", id, rounds, salt)
+
+	started := time.Now()
+	for i := 0; i < tuneSamples; i++ {
+		crypt("password", setting)
+	}
+
+	return time.Since(started) / tuneSamples, nil
+}
+
+func isCloseDuration(value time.Duration, target time.Duration) bool {
+	deviation := float64(value-target) / float64(target)
+
+	return deviation > -tunePrecision && deviation < tunePrecision
+}
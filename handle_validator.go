@@ -7,9 +7,8 @@ import (
 )
 
 func (server *Server) HandleValidate(
-	response http.ResponseWriter, request *http.Request,
+	response http.ResponseWriter, request *http.Request, path string,
 ) {
-	path := strings.TrimPrefix(request.URL.Path, "/v/")
 	path = strings.TrimRight(path, "/")
 
 	slash := strings.LastIndex(path, "/")
@@ -28,14 +27,29 @@ func (server *Server) HandleValidate(
 		hash, token,
 	)
 
-	exists, err := server.backend.IsHashExists(token, hash)
+	if !server.isTokenAllowedByCertificate(request, token) {
+		log.Printf(
+			"client %s is not allowed to validate '%s'",
+			getClientAddress(request), token,
+		)
+		response.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	if !server.servedTokens.allows(token) {
+		log.Printf("token '%s' is not served by this listener", token)
+		response.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	valid, err := server.isHashValid(token, hash)
 	if err != nil {
 		log.Println(err)
 		response.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
-	if exists {
+	if valid {
 		response.WriteHeader(http.StatusOK)
 		return
 	}
@@ -43,3 +57,21 @@ func (server *Server) HandleValidate(
 	log.Printf("hash '%s' does not exists for '%s' token", hash, token)
 	response.WriteHeader(http.StatusNotFound)
 }
+
+// isHashValid reports whether hash exists in hash table of token, result is
+// counted in validation metrics.
+func (server *Server) isHashValid(token string, hash string) (bool, error) {
+	exists, err := server.backend.IsHashExists(token, hash)
+	if err != nil {
+		return false, err
+	}
+
+	result := "invalid"
+	if exists {
+		result = "valid"
+	}
+
+	server.metrics.inc(metricHashValidations, token, "result", result)
+
+	return exists, nil
+}
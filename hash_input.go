@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/reconquest/hierr-go"
+)
+
+const (
+	// hashPurposeServe is purpose of index of hash entry served to client.
+	hashPurposeServe = ""
+
+	// hashPurposeSalt is purpose of indexes of hash entries, which salts are
+	// sent to client for password change.
+	hashPurposeSalt = "-salt-"
+)
+
+// hashInput contains all values, which determine index of hash entry for
+// client. Values are hashed in fixed order:
+//
+//	<client>-<token><purpose><slot>
+//
+// so the same client gets the same entry of the same token during time slot.
+// If deployment seed is configured, it is prepended as <seed>-, so
+// deployments with different seeds serve different entries to the same
+// client.
+// Alternate entries served to recent clients do not change hash input, they
+// are selected by index modifier (see deriveIndex).
+type hashInput struct {
+	// seed is secret of deployment, empty if not configured.
+	seed string
+
+	// client is address of client without port.
+	client string
+	token  string
+
+	// purpose separates indexes computed for different purposes, so salts
+	// for password change do not reveal served entry.
+	purpose string
+
+	// slot is number of time slot of hash TTL length since unix epoch.
+	slot int64
+}
+
+func newHashInput(
+	request *http.Request,
+	seed string,
+	token string,
+	purpose string,
+	ttl time.Duration,
+) hashInput {
+	return hashInput{
+		seed:    seed,
+		client:  getClientAddress(request),
+		token:   token,
+		purpose: purpose,
+		slot:    getTimeSlot(time.Now(), ttl),
+	}
+}
+
+// getTimeSlot returns number of time slot of specified hash TTL length,
+// which contains specified time.
+func getTimeSlot(now time.Time, ttl time.Duration) int64 {
+	return now.Unix() / int64(ttl/time.Second)
+}
+
+// getClientAddress returns address of client, which has made request,
+// without port.
+func getClientAddress(request *http.Request) string {
+	return request.RemoteAddr[:strings.LastIndex(request.RemoteAddr, ":")]
+}
+
+func (input hashInput) bytes() []byte {
+	prefix := ""
+	if input.seed != "" {
+		prefix = input.seed + "-"
+	}
+
+	return []byte(
+		prefix + input.client + "-" + input.token + input.purpose +
+			strconv.FormatInt(input.slot, 10),
+	)
+}
+
+// readIndexSeed reads deployment seed from file specified by
+// --index-seed-file, empty seed is returned if option is not specified.
+func readIndexSeed(args map[string]interface{}) (string, error) {
+	path, ok := args["--index-seed-file"].(string)
+	if !ok {
+		return "", nil
+	}
+
+	seed, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", hierr.Errorf(err, "can't read index seed file")
+	}
+
+	seed = bytes.TrimRight(seed, "\r\n")
+	if len(seed) == 0 {
+		return "", usageError{
+			fmt.Errorf("index seed file %s is empty", path),
+		}
+	}
+
+	return string(seed), nil
+}
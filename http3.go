@@ -0,0 +1,48 @@
+package main
+
+import (
+	"crypto/tls"
+	"log"
+	"net/http"
+
+	"github.com/quic-go/quic-go/http3"
+	"github.com/reconquest/hierr-go"
+)
+
+// startHTTP3 starts serving specified handler over QUIC on specified
+// address in background.
+func startHTTP3(
+	address string, handler http.Handler, tlsConfig *tls.Config,
+) *http3.Server {
+	server := &http3.Server{
+		Addr:      address,
+		Handler:   handler,
+		TLSConfig: http3.ConfigureTLSConfig(tlsConfig),
+	}
+
+	go func() {
+		log.Println("starting HTTP/3 listening on", address)
+
+		err := server.ListenAndServe()
+		if err != nil && err != http.ErrServerClosed {
+			log.Println(hierr.Errorf(err, "can't serve HTTP/3"))
+		}
+	}()
+
+	return server
+}
+
+// advertiseHTTP3 adds Alt-Svc header to responses of specified handler, so
+// clients can switch to HTTP/3.
+func advertiseHTTP3(server *http3.Server, handler http.Handler) http.Handler {
+	return http.HandlerFunc(
+		func(writer http.ResponseWriter, request *http.Request) {
+			err := server.SetQUICHeaders(writer.Header())
+			if err != nil {
+				log.Println(hierr.Errorf(err, "can't set Alt-Svc header"))
+			}
+
+			handler.ServeHTTP(writer, request)
+		},
+	)
+}
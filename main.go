@@ -2,10 +2,12 @@ package main
 
 import (
 	"errors"
+	"fmt"
 	"log"
 	"math/rand"
 	"net"
 	"os"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -18,10 +20,34 @@ var version = `3.0`
 var usage = `shadowd, secure login distribution service
 
 Usage:
-  shadowd [options] -L <address> [-s <time>]
+  shadowd [options] -L <address> [-s <time>] [--client-ca <path>]
   shadowd [options] -G <token> [-n <size>] [-a <algo>]
   shadowd [options] -C [-h <host>...] [-i <ip>...] [-d <date>] [-b <length>]
   shadowd [options] -K <token> [-r]
+  shadowd [options] table import <token> [--lenient] [--shadow-format]
+  shadowd [options] table check <token>
+  shadowd [options] table set <token> [--banner <text>]
+          [--record-max-age <time>] [--no-rotate | --rotate]
+          [--shared | --unshared]
+  shadowd [options] table rotate <prefix> [--per-token]
+  shadowd [options] table rekey <token> [-a <algo>]
+  shadowd [options] table low-stock [--threshold <ratio>] [--json]
+  shadowd [options] table export-csv <file>
+  shadowd [options] prune [-s <time>] [--dry-run]
+  shadowd [options] simulate --token <token> --clients <file>
+          [--size <size>] [-s <time>]
+  shadowd [options] shell
+  shadowd [options] selftest <token>
+  shadowd [options] tune [-a <algo>] [--target <time>]
+  shadowd [options] doctor [--client-ca <path>]
+  shadowd [options] cert verify
+  shadowd [options] recent flush <server> --client-cert <path>
+          --client-key <path> [--token <token>]
+  shadowd [options] tail <server> --client-cert <path> --client-key <path>
+          [--token <token>] [--client <address>]
+  shadowd [options] pki init [-b <length>] [-d <date>]
+  shadowd [options] pki issue --cn <name> [-b <length>] [-d <date>]
+  shadowd [options] --serve-generate <address> --client-ca <path>
   shadowd --help
   shadowd --version
 
@@ -29,8 +55,53 @@ Options:
   -G --generate            Generate and store hash-table for specified <token>.
                             Password will be read from stdin.
     -n --length <size>     Generate hash-table of specified length [default: 2048].
-    -a --algorithm <algo>  Use specified algorithm [default: sha256].
-    --no-confirm           Do not prompt confirmation for password.
+    -a --algorithm <algo>  Use specified algorithm: sha256, sha512, bcrypt or
+                            argon2id [default: sha256].
+    --crypt-id <id>        Use specified crypt(3) algorithm id with optional
+                            parameters instead of --algorithm, e.g. 6 or
+                            y$j9T, id should be supported by system libcrypt.
+    --algorithm-fallback <algo>
+                           Use specified algorithm if requested algorithm or
+                            crypt id can't be used on this host, warning is
+                            printed when fallback is used.
+    --no-confirm           Do not prompt confirmation for password, password
+                            is never confirmed when it is piped to stdin.
+    --stream               Store records while they are generated instead of
+                            holding whole hash-table in memory.
+    --sunset <date>        Stop serving hash-table after specified date in
+                            RFC3339 format, clients are notified about it by
+                            Sunset header.
+    --target-os <os>       Warn if records can't be verified by libc of
+                            specified OS, e.g. rhel7, debian11 or openbsd.
+    --allow-empty-password
+                           Allow generating hash-table for empty password.
+    --deterministic-salt <seed>
+                           Derive salts from specified integer seed, so the
+                            same password and seed produce identical
+                            hash-table. TEST ONLY, salts are predictable.
+    --allow-deterministic-salt
+                           Acknowledge that hash-table generated from salt
+                            seed is unsafe for production.
+    --store-verifier       Store argon2 hash of password in hash-table
+                            metadata, so password can be checked later using
+                            'table check' command.
+    --progress <mode>      Show generation progress using spinner, as plain
+                            lines on every 10% or do not show it at all
+                            (spinner, plain or none) [default: spinner].
+    --confirm              Confirm regeneration of existing hash-table which
+                            is larger than confirmation threshold.
+    --generation-webhook <url>
+                           POST JSON with token, length, algorithm, operator
+                            and timestamp to specified URL after hash-table
+                            is generated.
+    --policy <path>        Restrict algorithms allowed for tokens using
+                            policy file.
+    --no-clobber           Refuse to regenerate existing hash-table.
+    --force                Regenerate existing hash-table despite
+                            --no-clobber.
+    --confirm-threshold <size>
+                           Require confirmation for regeneration of hash-tables
+                            larger than specified size [default: 10000].
   -C --certificate         Generate certificate pair for authenticating via HTTPS.
     -b --bytes <length>    Generate rsa key of specified length [default: 2048].
     -h --host <host>       Set specified host as trusted [default: $CERT_HOST].
@@ -38,37 +109,265 @@ Options:
     -d --till <date>       Set time certificate valid till [default: $CERT_VALID].
   -L --listen <address>    Listen specified IP and port [default: :443].
     -s --ttl <time>        Use specified time duration as hash TTL [default: 24h].
+    --no-session-resumption
+                           Do not allow clients to resume TLS sessions using
+                            session tickets.
+    --ticket-key-rotation <time>
+                           Rotate TLS session ticket keys with specified
+                            interval [default: 1h].
+    --prune-interval <time>
+                           Remove expired recent clients with specified
+                            interval [default: 1m].
+    --served-flush-interval <time>
+                           Write counts of served hash entries to backend with
+                            specified interval, counts are also written on
+                            shutdown [default: 10s].
+    --cache-size <n>       Cache specified amount of hash-table sizes and
+                            records read from backend, 0 disables cache
+                            [default: 0].
+    --cache-ttl <time>     Keep values in cache for specified time, hash-tables
+                            regenerated by other processes are served after
+                            that time [default: 10s].
+    --proxy-protocol       Expect PROXY protocol v1 or v2 header sent by load
+                            balancer on every connection and use client
+                            address from it.
+    --provision-dir <dir>  Watch specified dir for files with already hashed
+                            records and store them as hash-tables of tokens
+                            named by relative paths of files.
+    --global-serve-limit <n>
+                           Serve no more than specified amount of entries of
+                            all tokens per minute, 503 is returned above
+                            limit, 0 disables limit [default: 0].
+    --retry-after <range>  Send random amount of seconds from specified range
+                            in Retry-After header when serve limit is
+                            exceeded, so denied clients don't retry all at
+                            once [default: 30-90].
+    --keep-alive <mode>    Keep client connections open between requests (on
+                            or off), responses are sent with 'Connection:
+                            close' when turned off [default: on].
+    --tls-log <mode>       Log failed TLS handshakes including rejected client
+                            certificates (errors), also completed handshakes
+                            with client certificate subject (verbose) or
+                            nothing (off) [default: errors].
+    --pool-limit <n>       Open no more than specified amount of connections
+                            to every server of remote backend, requests wait
+                            for free connection when all are in use, 0 uses
+                            default of backend driver [default: 0].
+    --recent-clients-per-token <n>
+                           Track no more than specified amount of distinct
+                            recent clients of every token, clients above
+                            limit are served, but not tracked, 0 disables
+                            limit [default: 0].
+    --pprof-listen <address>
+                           Serve net/http/pprof profiles over plain HTTP on
+                            specified address, e.g. 127.0.0.1:6060, profiles
+                            are never served on main listener.
+    --authorize-url <url>  Ask specified URL whether hash entry of token can be
+                            served to client, request details are posted
+                            as JSON, 2xx allows serving and 403 denies it.
+    --replicate-recent <address>
+                           Replicate recent clients to warm standby server
+                            listening on specified address, best-effort,
+                            standby should use same server certificate.
+    --replicate-cert <path>
+                           Use specified client certificate for replication.
+    --replicate-key <path> Use specified key of replication certificate.
+    --reserve              Reserve served hash entries, so each entry will be
+                            served only to one client until hash-table is
+                            regenerated.
+    --not-found-body <format>
+                           Send body with 404 responses for unknown tokens
+                            (empty, plain or json) [default: empty].
+    --metrics-per-token    Add token label to metrics exported on /metrics,
+                            amount of metrics will grow with amount of tokens.
+    --metrics-textfile <path>
+                           Write metrics to specified file for textfile
+                            collector of node_exporter.
+    --metrics-textfile-interval <time>
+                           Write metrics file with specified interval
+                            [default: 15s].
+    --redact-tokens        Hide identity of clients in responses of admin
+                            endpoints and in index trace.
+    --recent-key-template <template>
+                           Track recent clients by key produced by specified
+                            Go template, .Client, .Token and .Header fields
+                            are available, template should end with token
+                            after dash [default: {{.Client}}-{{.Token}}].
+    --log-next             Log every alternate hash entry served to client,
+                            which requests hash again before TTL expiration.
+    --trace-index          Log all values used for computing index of served
+                            hash entry, useful for debugging.
+    --messages <path>      Load translations of messages from specified JSON
+                            file, language is chosen using Accept-Language
+                            header.
+    --next-depth <n>       Serve up to specified amount of alternate hash
+                            entries to client, which requests hash again
+                            before TTL expiration [default: 1].
+    --next-exhausted <mode>
+                           Serve primary entry again, deny request or keep
+                            serving the last alternate entry when client
+                            exceeds next depth (wrap, deny or stick)
+                            [default: stick].
+    --default-token <token>
+                           Serve hash-table of specified token for unknown
+                            tokens instead of 404.
+    --hmac-key-file <path>
+                           Send HMAC-SHA256 of served record keyed by contents
+                            of specified file in X-Shadowd-HMAC header.
+    --serve-tokens <patterns>
+                           Serve only tokens matching any of specified comma
+                            separated glob patterns (e.g. "dmz/*,public"),
+                            other tokens are responded as unknown.
+    --index-seed-file <path>
+                           Mix contents of specified file into input of hash
+                            entry index, so deployments serving the same
+                            hash-table serve different entries to the same
+                            client.
+    --token-from-cert      Take token from client certificate with common name
+                            "token:<token>" signed by client CA, requested
+                            token should be empty or match it.
+    --expose-index         Send index of served hash entry in X-Shadowd-Index
+                            header to clients authenticated by certificate
+                            signed by client CA.
   -K --key                 Wait for SSH-key to be entered on stdin and append it to file,
                             determined from <token>.
     -r --truncate          Truncate file for specified token, do not append.
+  selftest                 Start server on random loopback port, request hash
+                            for specified <token> and validate it.
+  tune                     Find crypt rounds value for specified algorithm,
+                            which hashing takes specified time.
+    --target <time>        Use specified hashing time [default: 250ms].
+  doctor                   Check configuration, certificates and backend and
+                            report found problems.
+  tail                     Print hash entries served by shadowd listening on
+                            specified <server> as they are served, client
+                            certificate signed by client CA is required.
+    --client-cert <path>   Use specified client certificate.
+    --client-key <path>    Use specified key of client certificate.
+    --token <token>        Print only entries of specified token.
+    --client <address>     Print only entries served to specified client.
+  recent flush             Make shadowd listening on specified <server> forget
+                            recent clients of all tokens or of token
+                            specified via --token, client certificate signed
+                            by client CA is required.
+  cert verify              Check that certificate and key from certificates
+                            directory match each other.
+  pki init                 Create CA for issuing client certificates, existing
+                            CA will be replaced and issued client certificates
+                            will be re-signed.
+  pki issue                Issue client certificate signed by CA.
+    --cn <name>            Use specified common name for client certificate.
+  table check              Check that password entered on stdin has been used
+                            for generating hash-table of specified <token>.
+  table set                Change metadata of hash-table for specified <token>.
+    --banner <text>        Send specified message to clients in
+                            X-Shadowd-Notice header, empty message removes
+                            it.
+    --record-max-age <time>
+                           Tell clients to keep served record for specified
+                            time before pulling new one using
+                            X-Shadowd-Max-Age header, 0 removes it.
+    --no-rotate            Pin every client to its own entry regardless of
+                            time slot, so password is never changed by
+                            rotation.
+    --rotate               Rotate served entries again.
+    --shared               Serve the first entry to all clients, so every
+                            host gets the same record of shared account.
+    --unshared             Serve entries to clients separately again.
+  table rotate             Regenerate hash-tables of all tokens with specified
+                            <prefix> keeping their size and algorithm.
+    --per-token            Prompt for new password for every token.
+  table rekey              Regenerate hash-table of specified <token> with
+                            algorithm specified by --algorithm or --crypt-id
+                            keeping its size, salt length and rounds.
+  table low-stock          List tokens, which have served more entries of
+                            hash-table than specified part of its size.
+    --threshold <ratio>    Use specified part of hash-table size [default: 0.8].
+    --json                 Output tokens as JSON.
+  table export-csv         Write token, algorithm, length, creation time,
+                            amount of served entries and sunset date of all
+                            hash-tables as CSV to specified <file>, - means
+                            stdout.
+  table import             Read hash-table records for specified <token> from
+                            stdin and store them, records are validated
+                            before storing. Record can be followed by RFC3339
+                            time, after which it is not served.
+    --lenient              Warn about invalid records instead of rejecting
+                            them.
+    --shadow-format        Read lines in /etc/shadow format and import
+                            record from the second colon separated field.
+  prune                    Remove hash-tables, which sunset date has passed or
+                            which all entries have expired, and clients,
+                            which are no longer recent (mongodb only).
+    --dry-run              Report what would be removed without removing it.
+  simulate                 Report distribution of hash-table entries, which
+                            would be served to clients listed in specified
+                            file during current time slot, one address per
+                            line, index seed file of server should be
+                            specified if it is used.
+    --clients <file>       Read client addresses from specified file.
+    --size <size>          Simulate hash-table of specified length instead of
+                            stored one.
+  shell                    Read commands managing hash-tables from stdin and
+                            run them against backend, use help command for
+                            list of commands.
+  --serve-generate <address>
+                           Listen specified IP and port for hash-table
+                            generation requests on POST /admin/generate,
+                            client certificate is required.
   -t --tables <dir>        Use specified dir for storing and reading hash-tables
                             [default: /var/shadowd/ht/].
   -c --certs <dir>         Use specified dir for storing and reading certificates
                             [default: /var/shadowd/cert/].
+  --tls-chain <path>       Append intermediate certificates from specified file
+                            to served certificate chain.
+  --listen-http3 <address>
+                           Also serve over HTTP/3 (QUIC) on specified UDP
+                            address and advertise it in Alt-Svc header.
+  --listen-grpc <address>  Also serve records, tokens and validation over gRPC
+                            on specified TCP address, see shadowd.proto.
+  --client-ca <path>       Verify client certificates using specified CA,
+                            clients with verified certificate are admins.
   -k --keys <dir>          Use specified dir for reading public SSH keys.
                             [default: /var/shadowd/ssh/].
+  -m --meta <dir>          Use specified dir for storing and reading hash-tables
+                            metadata [default: /var/shadowd/meta/].
   -f --config <path>       Use specified configuration file.
+  --event-sink <url>       Publish events about generated hash-tables and
+                            served entries to specified http, https, nats or
+                            file URL, e.g. nats://127.0.0.1:4222/subject.
   -q --quiet               Quiet mode, be less chatty.
   --help                   Show this screen.
   --version                Show program version.
-`
 
-var ErrNotFound = errors.New("not found")
+Exit codes:
+  0  Success.
+  1  Unclassified failure.
+  2  Invalid arguments or configuration.
+  3  Requested object is not found.
+  4  Backend can't store or read data.
+`
 
 func init() {
 	rand.Seed(time.Now().UTC().UnixNano())
 }
 
 func main() {
-	args, _ := docopt.Parse(
-		replaceDefaults(usage), nil, true, "shadowd "+version, false,
+	args, err := docopt.Parse(
+		replaceDefaults(usage), nil, true, "shadowd "+version, false, false,
 	)
+	if err != nil {
+		os.Exit(ExitUsage)
+	}
+
+	// help or version has been requested and printed
+	if args == nil {
+		os.Exit(ExitSuccess)
+	}
 
 	hashTTL, err := time.ParseDuration(args["--ttl"].(string))
 	if err != nil {
-		hierr.Fatalf(
-			err, "can't parse ttl time",
-		)
+		fatalf(usageError{err}, "can't parse ttl time")
 	}
 
 	var (
@@ -80,9 +379,7 @@ func main() {
 	if path, ok := args["--config"].(string); ok {
 		config, err := getConfig(path)
 		if err != nil {
-			hierr.Fatalf(
-				err, "can't parse configuration file",
-			)
+			fatalf(usageError{err}, "can't parse configuration file")
 		}
 
 		backendUse = config.Backend.Use
@@ -94,28 +391,48 @@ func main() {
 		backend = &filesystem{
 			hashTablesDir: args["--tables"].(string),
 			sshKeysDir:    args["--keys"].(string),
+			metaDir:       args["--meta"].(string),
 			hashTTL:       hashTTL,
 			clients:       map[string]time.Time{},
 			clientsLock:   &sync.Mutex{},
+
+			reservations:     map[string]map[int64]bool{},
+			reservationsLock: &sync.Mutex{},
+
+			servedLock: &sync.Mutex{},
 		}
 	case "mongodb":
+		poolLimit, err := strconv.Atoi(args["--pool-limit"].(string))
+		if err != nil || poolLimit < 0 {
+			fatalf(
+				usageError{fmt.Errorf("%s", args["--pool-limit"])},
+				"invalid connection pool limit",
+			)
+		}
+
 		backend = &mongodb{
-			dsn:     backendDSN,
-			hashTTL: hashTTL,
+			dsn:       backendDSN,
+			hashTTL:   hashTTL,
+			poolLimit: poolLimit,
 		}
 
 	default:
-		hierr.Fatalf(
-			errors.New(backendUse), "unknown backend",
-		)
+		fatalf(usageError{errors.New(backendUse)}, "unknown backend")
 
 	}
 
+	if args["doctor"].(bool) {
+		err = handleDoctor(backend, args)
+		if err != nil {
+			fatalf(err, "configuration is broken")
+		}
+
+		return
+	}
+
 	err = backend.Init()
 	if err != nil {
-		hierr.Fatalf(
-			err, "can't initialize shadowd backend",
-		)
+		fatalf(backendError{err}, "can't initialize shadowd backend")
 	}
 
 	switch {
@@ -128,15 +445,75 @@ func main() {
 	case args["--certificate"]:
 		err = handleCertificateGenerate(backend, args)
 
+	case args["selftest"].(bool):
+		err = handleSelftest(backend, args, hashTTL)
+
+	case args["tune"].(bool):
+		err = handleTune(args)
+
+	case args["cert"].(bool) && args["verify"].(bool):
+		err = handleCertificateVerify(args)
+
+	case args["recent"].(bool) && args["flush"].(bool):
+		err = handleRecentFlush(args)
+
+	case args["tail"].(bool):
+		err = handleTail(args)
+
+	case args["pki"].(bool) && args["init"].(bool):
+		err = handlePKIInit(args)
+
+	case args["pki"].(bool) && args["issue"].(bool):
+		err = handlePKIIssue(args)
+
+	case args["table"].(bool) && args["check"].(bool):
+		err = handleTableCheck(backend, args)
+
+	case args["table"].(bool) && args["set"].(bool):
+		err = handleTableSet(backend, args)
+
+	case args["table"].(bool) && args["rotate"].(bool):
+		err = handleRotateAll(backend, args)
+
+	case args["table"].(bool) && args["rekey"].(bool):
+		err = handleTableRekey(backend, args)
+
+	case args["table"].(bool) && args["low-stock"].(bool):
+		err = handleTableLowStock(backend, args)
+
+	case args["table"].(bool) && args["export-csv"].(bool):
+		err = handleTokenExportCSV(backend, args["<file>"].(string))
+
+	case args["table"].(bool) && args["import"].(bool):
+		err = handleTableImport(backend, args)
+
+	case args["prune"].(bool):
+		err = handlePrune(backend, args)
+
+	case args["simulate"].(bool):
+		err = handleSimulate(backend, args, hashTTL)
+
+	case args["shell"].(bool):
+		err = handleShell(backend, args)
+
+	case args["--serve-generate"] != nil:
+		err = handleServeGenerate(backend, args)
+
 	default:
 		err = handleListen(backend, args, hashTTL)
 	}
 
 	if err != nil {
-		log.Fatal(err)
+		log.Println(err)
+		os.Exit(getExitCode(err))
 	}
 }
 
+func fatalf(err error, message string, args ...interface{}) {
+	fmt.Fprintln(os.Stderr, hierr.Errorf(err, message, args...))
+	os.Exit(getExitCode(err))
+}
+
 func replaceDefaultCertHost(usage string) string {
 	hostname, err := os.Hostname()
 	if err != nil {
@@ -153,7 +530,7 @@ func replaceDefaultCertAddr(usage string) string {
 func replaceDefaultCertValidTill(usage string) string {
 	return strings.Replace(
 		usage, "$CERT_VALID",
-		time.Now().AddDate(1, 0, 0).Format("2006-02-01"),
+		time.Now().AddDate(1, 0, 0).Format(certDateLayout),
 		-1,
 	)
 }
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/reconquest/hierr-go"
+)
+
+const (
+	defaultLanguage = "en"
+
+	messageTokenNotFound = "token-not-found"
+)
+
+// messageCatalog contains formats of human-readable messages by language and
+// message key, machine-readable codes are never translated.
+type messageCatalog map[string]map[string]string
+
+var messages = messageCatalog{
+	"en": {
+		messageTokenNotFound: "token %s not found",
+	},
+	"de": {
+		messageTokenNotFound: "Token %s nicht gefunden",
+	},
+	"ru": {
+		messageTokenNotFound: "токен %s не найден",
+	},
+}
+
+// loadMessages reads catalog from specified JSON file and merges it with
+// built-in one, so messages can be translated to other languages without
+// rebuilding.
+func loadMessages(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return hierr.Errorf(
+			err, "can't read messages file %s", path,
+		)
+	}
+
+	catalog := messageCatalog{}
+	err = json.Unmarshal(data, &catalog)
+	if err != nil {
+		return hierr.Errorf(
+			err, "can't decode messages file %s", path,
+		)
+	}
+
+	for language, formats := range catalog {
+		language = strings.ToLower(language)
+
+		if messages[language] == nil {
+			messages[language] = map[string]string{}
+		}
+
+		for key, format := range formats {
+			messages[language][key] = format
+		}
+	}
+
+	return nil
+}
+
+// translate formats message with specified key using language preferred by
+// client in Accept-Language header, english is used by default.
+func translate(
+	request *http.Request, key string, args ...interface{},
+) string {
+	for _, language := range getAcceptedLanguages(request) {
+		format, ok := messages[language][key]
+		if ok {
+			return fmt.Sprintf(format, args...)
+		}
+	}
+
+	return fmt.Sprintf(messages[defaultLanguage][key], args...)
+}
+
+// getAcceptedLanguages returns languages from Accept-Language header ordered
+// by preference, region is stripped, so "de-AT" is treated as "de".
+func getAcceptedLanguages(request *http.Request) []string {
+	type accepted struct {
+		language string
+		quality  float64
+	}
+
+	header := request.Header.Get("Accept-Language")
+
+	languages := []accepted{}
+	for _, item := range strings.Split(header, ",") {
+		parts := strings.Split(strings.TrimSpace(item), ";")
+
+		language := strings.ToLower(strings.TrimSpace(parts[0]))
+		if language == "" || language == "*" {
+			continue
+		}
+
+		language = strings.SplitN(language, "-", 2)[0]
+
+		quality := 1.0
+		for _, parameter := range parts[1:] {
+			parameter = strings.TrimSpace(parameter)
+			if !strings.HasPrefix(parameter, "q=") {
+				continue
+			}
+
+			value, err := strconv.ParseFloat(
+				strings.TrimPrefix(parameter, "q="), 64,
+			)
+			if err == nil {
+				quality = value
+			}
+		}
+
+		languages = append(languages, accepted{language, quality})
+	}
+
+	sort.SliceStable(languages, func(i, j int) bool {
+		return languages[i].quality > languages[j].quality
+	})
+
+	result := []string{}
+	for _, language := range languages {
+		result = append(result, language.language)
+	}
+
+	return result
+}
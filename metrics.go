@@ -0,0 +1,226 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/reconquest/hierr-go"
+)
+
+const (
+	metricHashesServed           = "shadowd_hashes_served_total"
+	metricHashValidations        = "shadowd_hash_validations_total"
+	metricRecentClientsPruned    = "shadowd_recent_clients_pruned_total"
+	metricNextEntriesServed      = "shadowd_next_entries_served_total"
+	metricCacheHits              = "shadowd_cache_hits_total"
+	metricCacheMisses            = "shadowd_cache_misses_total"
+	metricServeLimitExceeded     = "shadowd_serve_limit_exceeded_total"
+	metricRecentClientsUntracked = "shadowd_recent_clients_untracked_total"
+)
+
+var metricsHelp = map[string]string{
+	metricHashesServed:        "Amount of hash table entries served to clients.",
+	metricHashValidations:     "Amount of hash validation requests by result.",
+	metricRecentClientsPruned: "Amount of expired recent clients removed.",
+	metricNextEntriesServed: "Amount of alternate entries served to " +
+		"recent clients.",
+	metricCacheHits:   "Amount of backend reads served from cache.",
+	metricCacheMisses: "Amount of backend reads not found in cache.",
+	metricServeLimitExceeded: "Amount of requests denied because of " +
+		"global serve limit.",
+	metricRecentClientsUntracked: "Amount of recent clients not tracked " +
+		"because of limit of recent clients per token.",
+}
+
+var metricsLabelEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	`"`, `\"`,
+	"\n", `\n`,
+)
+
+// metrics contains counters, which are exported in Prometheus text format.
+// Token label is added only if perToken is set, because amount of tokens is
+// not bounded.
+type metrics struct {
+	perToken bool
+
+	counters map[string]map[string]int64
+	lock     *sync.Mutex
+}
+
+func newMetrics(perToken bool) *metrics {
+	return &metrics{
+		perToken: perToken,
+		counters: map[string]map[string]int64{},
+		lock:     &sync.Mutex{},
+	}
+}
+
+// inc increments counter with specified name for specified token, labels
+// should be specified as name and value pairs.
+func (metrics *metrics) inc(name string, token string, labels ...string) {
+	if metrics == nil {
+		return
+	}
+
+	if metrics.perToken {
+		labels = append(labels, "token", token)
+	}
+
+	metrics.add(name, 1, labels...)
+}
+
+// add adds specified value to counter, which is not related to any token.
+func (metrics *metrics) add(name string, value int64, labels ...string) {
+	if metrics == nil {
+		return
+	}
+
+	pairs := []string{}
+	for i := 0; i+1 < len(labels); i += 2 {
+		pairs = append(
+			pairs,
+			fmt.Sprintf(
+				`%s="%s"`, labels[i], metricsLabelEscaper.Replace(labels[i+1]),
+			),
+		)
+	}
+
+	series := ""
+	if len(pairs) > 0 {
+		series = "{" + strings.Join(pairs, ",") + "}"
+	}
+
+	metrics.lock.Lock()
+	defer metrics.lock.Unlock()
+
+	if metrics.counters[name] == nil {
+		metrics.counters[name] = map[string]int64{}
+	}
+
+	metrics.counters[name][series] += value
+}
+
+func (metrics *metrics) ServeHTTP(
+	writer http.ResponseWriter, request *http.Request,
+) {
+	writer.Header().Set("Content-Type", "text/plain; version=0.0.4")
+
+	err := metrics.write(writer)
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+// writeTextfile writes metrics to specified file, file is replaced
+// atomically, so readers never get partially written metrics.
+func (metrics *metrics) writeTextfile(path string) error {
+	file, err := ioutil.TempFile(filepath.Dir(path), ".metrics")
+	if err != nil {
+		return hierr.Errorf(
+			err, "can't create temporary file for metrics",
+		)
+	}
+
+	defer os.Remove(file.Name())
+
+	err = metrics.write(file)
+	if err != nil {
+		file.Close()
+		return hierr.Errorf(
+			err, "can't write metrics to %s", file.Name(),
+		)
+	}
+
+	err = file.Chmod(0644)
+	if err != nil {
+		file.Close()
+		return hierr.Errorf(
+			err, "can't change mode of %s", file.Name(),
+		)
+	}
+
+	err = file.Close()
+	if err != nil {
+		return hierr.Errorf(
+			err, "can't close %s", file.Name(),
+		)
+	}
+
+	err = os.Rename(file.Name(), path)
+	if err != nil {
+		return hierr.Errorf(
+			err, "can't replace %s", path,
+		)
+	}
+
+	return nil
+}
+
+// writeTextfilePeriodically writes metrics to specified file every specified
+// interval until stop is closed.
+func (metrics *metrics) writeTextfilePeriodically(
+	path string, interval time.Duration, stop <-chan struct{},
+) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		err := metrics.writeTextfile(path)
+		if err != nil {
+			log.Println(err)
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (metrics *metrics) write(writer io.Writer) error {
+	metrics.lock.Lock()
+	defer metrics.lock.Unlock()
+
+	names := []string{}
+	for name := range metrics.counters {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	buffer := &bytes.Buffer{}
+	for _, name := range names {
+		fmt.Fprintf(buffer, "# HELP %s %s\n", name, metricsHelp[name])
+		fmt.Fprintf(buffer, "# TYPE %s counter\n", name)
+
+		series := []string{}
+		for labels := range metrics.counters[name] {
+			series = append(series, labels)
+		}
+
+		sort.Strings(series)
+
+		for _, labels := range series {
+			fmt.Fprintf(
+				buffer, "%s%s %d\n",
+				name, labels, metrics.counters[name][labels],
+			)
+		}
+	}
+
+	_, err := buffer.WriteTo(writer)
+
+	return err
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"fmt"
 	"log"
 	"regexp"
 	"sort"
@@ -21,8 +22,23 @@ type mongodb struct {
 	shadows  *mgo.Collection
 	keys     *mgo.Collection
 	clients  *mgo.Collection
+
+	reservations *mgo.Collection
+	tokens       *mgo.Collection
+
+	// pendingShadows contains records of hash tables, which are being
+	// streamed, until all records are received.
+	pendingShadows *mgo.Collection
+
+	// poolLimit limits amount of sockets opened by session to every server,
+	// including sockets in use, 0 means default limit of driver.
+	poolLimit int
 }
 
+// mongodbInsertBatchSize is amount of records inserted by single query
+// during streaming of hash table.
+const mongodbInsertBatchSize = 1000
+
 func (db *mongodb) GetPublicKeys(token string) (string, error) {
 	var docs []map[string]interface{}
 	err := db.keys.Find(bson.M{"token": token}).All(&docs)
@@ -32,6 +48,10 @@ func (db *mongodb) GetPublicKeys(token string) (string, error) {
 		)
 	}
 
+	if len(docs) == 0 {
+		return "", ErrNotFound
+	}
+
 	keys := []string{}
 	for _, doc := range docs {
 		keys = append(keys, doc["key"].(string))
@@ -63,25 +83,304 @@ func (db *mongodb) AddPublicKey(
 }
 
 func (db *mongodb) SetHashTable(token string, table []string) error {
-	_, err := db.shadows.RemoveAll(bson.M{"token": token})
+	return db.SwapHashTable(token, table)
+}
+
+// SwapHashTable inserts records of new hash table as separate generation and
+// then switches token to it, so readers, which select records by current
+// generation, never see partially inserted hash table.
+func (db *mongodb) SwapHashTable(token string, table []string) error {
+	return db.swapHashTable(token, table, nil)
+}
+
+// swapHashTable inserts records of new generation with specified expiries,
+// which can be nil if records never expire, and switches token to it.
+func (db *mongodb) swapHashTable(
+	token string, table []string, expiries []time.Time,
+) error {
+	generation := bson.NewObjectId()
+
+	docs := []interface{}{}
+	for number, hash := range table {
+		doc := bson.M{
+			"token":      token,
+			"hash":       hash,
+			"generation": generation,
+		}
+
+		if expiries != nil && !expiries[number].IsZero() {
+			doc["expiry"] = expiries[number]
+		}
+
+		docs = append(docs, doc)
+
+		if len(docs) == mongodbInsertBatchSize {
+			err := db.shadows.Insert(docs...)
+			if err != nil {
+				db.shadows.RemoveAll(bson.M{"generation": generation})
+
+				return hierr.Errorf(
+					err, "can't insert table hash to database",
+				)
+			}
+
+			docs = []interface{}{}
+		}
+	}
+
+	if len(docs) > 0 {
+		err := db.shadows.Insert(docs...)
+		if err != nil {
+			db.shadows.RemoveAll(bson.M{"generation": generation})
+
+			return hierr.Errorf(
+				err, "can't insert table hash to database",
+			)
+		}
+	}
+
+	return db.switchGeneration(token, generation)
+}
+
+// switchGeneration makes specified generation of records current for token.
+// Records of previous generation are kept until next switch, so readers,
+// which have selected previous generation just before switch, still find
+// them, older records are removed.
+func (db *mongodb) switchGeneration(
+	token string, generation bson.ObjectId,
+) error {
+	var previous struct {
+		Generation bson.ObjectId `bson:"generation,omitempty"`
+	}
+
+	err := db.tokens.Find(bson.M{"token": token}).One(&previous)
+	if err != nil && err != mgo.ErrNotFound {
+		return hierr.Errorf(
+			err, "can't get current generation of hash table",
+		)
+	}
+
+	now := time.Now()
+
+	_, err = db.tokens.Upsert(
+		bson.M{"token": token},
+		bson.M{
+			"$set": bson.M{
+				"generation": generation,
+				"served":     0,
+				"created":    now,
+				"modified":   now,
+			},
+			"$unset": bson.M{"parameters": ""},
+		},
+	)
 	if err != nil {
 		return hierr.Errorf(
-			err, "can't remove existing hash table",
+			err, "can't switch hash table generation",
 		)
 	}
 
+	_, err = db.reservations.RemoveAll(bson.M{"token": token})
+	if err != nil {
+		return hierr.Errorf(
+			err, "can't remove reservations of existing hash table",
+		)
+	}
+
+	// records stored before generations were introduced have no generation,
+	// they are previous generation if token has no generation yet and are
+	// removed by $nin otherwise
+	stale := bson.M{"$exists": true, "$ne": generation}
+	if previous.Generation != "" {
+		stale = bson.M{"$nin": []bson.ObjectId{generation, previous.Generation}}
+	}
+
+	_, err = db.shadows.RemoveAll(bson.M{"token": token, "generation": stale})
+	if err != nil {
+		return hierr.Errorf(
+			err, "can't remove stale hash table records",
+		)
+	}
+
+	return nil
+}
+
+func (db *mongodb) DeleteHashTable(token string) error {
+	info, err := db.shadows.RemoveAll(bson.M{"token": token})
+	if err != nil {
+		return hierr.Errorf(
+			err, "can't remove hash table records from database",
+		)
+	}
+
+	_, err = db.tokens.RemoveAll(bson.M{"token": token})
+	if err != nil {
+		return hierr.Errorf(
+			err, "can't remove token metadata from database",
+		)
+	}
+
+	_, err = db.reservations.RemoveAll(bson.M{"token": token})
+	if err != nil {
+		return hierr.Errorf(
+			err, "can't remove reservations of hash table",
+		)
+	}
+
+	if info.Removed == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// getTableSelector returns query, which selects records of current
+// generation of hash table.
+func (db *mongodb) getTableSelector(token string) (bson.M, error) {
+	var current struct {
+		Generation bson.ObjectId `bson:"generation,omitempty"`
+	}
+
+	err := db.tokens.Find(bson.M{"token": token}).One(&current)
+	if err != nil && err != mgo.ErrNotFound {
+		return nil, hierr.Errorf(
+			err, "can't get current generation of hash table",
+		)
+	}
+
+	if current.Generation == "" {
+		return bson.M{
+			"token":      token,
+			"generation": bson.M{"$exists": false},
+		}, nil
+	}
+
+	return bson.M{"token": token, "generation": current.Generation}, nil
+}
+
+func (db *mongodb) SetHashTableStream(
+	token string, size int, records <-chan string,
+) error {
+	defer drainRecords(records)
+
+	// records are collected in separate collection, so existing table is
+	// served until new one is complete
+	generation := bson.NewObjectId()
+
+	defer db.pendingShadows.RemoveAll(bson.M{"generation": generation})
+
+	count := 0
 	docs := []interface{}{}
-	for _, hash := range table {
+	for record := range records {
 		docs = append(docs, bson.M{
-			"token": token,
-			"hash":  hash,
+			"generation": generation,
+			"hash":       record,
 		})
+
+		count++
+
+		if len(docs) == mongodbInsertBatchSize {
+			err := db.pendingShadows.Insert(docs...)
+			if err != nil {
+				return hierr.Errorf(
+					err, "can't insert pending table hashes to database",
+				)
+			}
+
+			docs = []interface{}{}
+		}
+	}
+
+	if len(docs) > 0 {
+		err := db.pendingShadows.Insert(docs...)
+		if err != nil {
+			return hierr.Errorf(
+				err, "can't insert pending table hashes to database",
+			)
+		}
+	}
+
+	if count != size {
+		return fmt.Errorf(
+			"hash table stream ended after %d of %d records", count, size,
+		)
 	}
 
-	err = db.shadows.Insert(docs...)
+	var pending struct {
+		Hash string `bson:"hash"`
+	}
+
+	iterator := db.pendingShadows.Find(
+		bson.M{"generation": generation},
+	).Sort("_id").Iter()
+
+	docs = []interface{}{}
+	for iterator.Next(&pending) {
+		docs = append(docs, bson.M{
+			"token":      token,
+			"hash":       pending.Hash,
+			"generation": generation,
+		})
+
+		if len(docs) == mongodbInsertBatchSize {
+			err := db.shadows.Insert(docs...)
+			if err != nil {
+				iterator.Close()
+				db.shadows.RemoveAll(bson.M{"generation": generation})
+
+				return hierr.Errorf(
+					err, "can't insert table hash to database",
+				)
+			}
+
+			docs = []interface{}{}
+		}
+	}
+
+	err := iterator.Close()
 	if err != nil {
+		db.shadows.RemoveAll(bson.M{"generation": generation})
+
 		return hierr.Errorf(
-			err, "can't insert table hash to database",
+			err, "can't read pending table hashes from database",
+		)
+	}
+
+	if len(docs) > 0 {
+		err := db.shadows.Insert(docs...)
+		if err != nil {
+			db.shadows.RemoveAll(bson.M{"generation": generation})
+
+			return hierr.Errorf(
+				err, "can't insert table hash to database",
+			)
+		}
+	}
+
+	return db.switchGeneration(token, generation)
+}
+
+func (db *mongodb) SetHashTableWithExpiry(
+	token string, table []string, expiries []time.Time,
+) error {
+	if len(expiries) != len(table) {
+		return fmt.Errorf(
+			"amount of expiries (%d) doesn't match table size (%d)",
+			len(expiries), len(table),
+		)
+	}
+
+	return db.swapHashTable(token, table, expiries)
+}
+
+func (db *mongodb) AddServed(token string, delta int64) error {
+	_, err := db.tokens.Upsert(
+		bson.M{"token": token},
+		bson.M{"$inc": bson.M{"served": delta}},
+	)
+	if err != nil {
+		return hierr.Errorf(
+			err, "can't increase served counter",
 		)
 	}
 
@@ -89,8 +388,15 @@ func (db *mongodb) SetHashTable(token string, table []string) error {
 }
 
 func (db *mongodb) IsHashExists(token string, hash string) (bool, error) {
+	selector, err := db.getTableSelector(token)
+	if err != nil {
+		return false, err
+	}
+
+	selector["hash"] = hash
+
 	var doc map[string]interface{}
-	err := db.shadows.Find(bson.M{"token": token, "hash": hash}).One(&doc)
+	err = db.shadows.Find(selector).One(&doc)
 	if err != nil {
 		if err == mgo.ErrNotFound {
 			return false, nil
@@ -103,19 +409,118 @@ func (db *mongodb) IsHashExists(token string, hash string) (bool, error) {
 }
 
 func (db *mongodb) GetHash(token string, number int64) (string, error) {
-	var doc map[string]interface{}
-	err := db.shadows.Find(
-		bson.M{"token": token},
-	).Skip(int(number - int64(1))).Limit(1).One(&doc)
+	record, _, err := db.GetHashWithExpiry(token, number)
+
+	return record, err
+}
+
+func (db *mongodb) GetHashWithExpiry(
+	token string, number int64,
+) (string, time.Time, error) {
+	selector, err := db.getTableSelector(token)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	// records are numbered from zero in order of insertion, ids of
+	// inserted records are increasing
+	var doc struct {
+		Hash   string    `bson:"hash"`
+		Expiry time.Time `bson:"expiry,omitempty"`
+	}
+
+	err = db.shadows.Find(
+		selector,
+	).Sort("_id").Skip(int(number)).Limit(1).One(&doc)
 	if err != nil {
 		if err == mgo.ErrNotFound {
-			return "", ErrNotFound
+			return "", time.Time{}, ErrNotFound
+		}
+
+		return "", time.Time{}, err
+	}
+
+	return doc.Hash, doc.Expiry, nil
+}
+
+func (db *mongodb) GetHashesWithExpiry(
+	token string, number int64, count int64,
+) ([]string, []time.Time, error) {
+	selector, err := db.getTableSelector(token)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var doc struct {
+		Hash   string    `bson:"hash"`
+		Expiry time.Time `bson:"expiry,omitempty"`
+	}
+
+	records := []string{}
+	expiries := []time.Time{}
+
+	iter := db.shadows.Find(
+		selector,
+	).Sort("_id").Skip(int(number)).Limit(int(count)).Iter()
+	for iter.Next(&doc) {
+		records = append(records, doc.Hash)
+		expiries = append(expiries, doc.Expiry)
+
+		// expiry is omitted in records, which never expire, so it would
+		// be kept from previous record otherwise
+		doc.Expiry = time.Time{}
+	}
+
+	err = iter.Close()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return records, expiries, nil
+}
+
+func (db *mongodb) GetHashTable(token string) ([]string, error) {
+	selector, err := db.getTableSelector(token)
+	if err != nil {
+		return nil, err
+	}
+
+	records := []string{}
+
+	var doc struct {
+		Hash string `bson:"hash"`
+	}
+
+	iter := db.shadows.Find(selector).Sort("_id").Iter()
+	for iter.Next(&doc) {
+		records = append(records, doc.Hash)
+	}
+
+	err = iter.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(records) == 0 {
+		return nil, ErrNotFound
+	}
+
+	return records, nil
+}
+
+func (db *mongodb) ReserveIndex(token string, index int64) (bool, error) {
+	err := db.reservations.Insert(bson.M{"token": token, "index": index})
+	if err != nil {
+		if mgo.IsDup(err) {
+			return false, nil
 		}
 
-		return "", err
+		return false, hierr.Errorf(
+			err, "can't reserve hash table entry",
+		)
 	}
 
-	return doc["hash"].(string), nil
+	return true, nil
 }
 
 func (db *mongodb) IsRecentClient(identifier string) (bool, error) {
@@ -133,10 +538,17 @@ func (db *mongodb) IsRecentClient(identifier string) (bool, error) {
 }
 
 func (db *mongodb) AddRecentClient(identifier string) error {
-	err := db.clients.Insert(
-		bson.M{"client": identifier, "create_date": time.Now().Unix()},
+	_, err := db.clients.Upsert(
+		bson.M{"client": identifier},
+		bson.M{"$set": bson.M{"create_date": time.Now().Unix()}},
 	)
 	if err != nil {
+		// concurrent upsert of the same client has won the race, client is
+		// already added.
+		if mgo.IsDup(err) {
+			return nil
+		}
+
 		return hierr.Errorf(
 			err, "can't add recent client to database",
 		)
@@ -145,8 +557,117 @@ func (db *mongodb) AddRecentClient(identifier string) error {
 	return nil
 }
 
+func (db *mongodb) AddRecentClientRequest(identifier string) (int, error) {
+	var doc struct {
+		Requests int `bson:"requests"`
+	}
+
+	_, err := db.clients.Find(bson.M{"client": identifier}).Apply(
+		mgo.Change{
+			Update:    bson.M{"$inc": bson.M{"requests": 1}},
+			ReturnNew: true,
+		},
+		&doc,
+	)
+	if err != nil {
+		// client has expired right after it has been checked
+		if err == mgo.ErrNotFound {
+			return 1, nil
+		}
+
+		return 0, hierr.Errorf(
+			err, "can't count recent client request in database",
+		)
+	}
+
+	return doc.Requests, nil
+}
+
+func (db *mongodb) DeleteRecentClient(remote string) error {
+	info, err := db.clients.RemoveAll(
+		bson.M{
+			"client": bson.M{"$regex": "^" + regexp.QuoteMeta(remote) + "-"},
+		},
+	)
+	if err != nil {
+		return hierr.Errorf(
+			err, "can't remove recent client from database",
+		)
+	}
+
+	if info.Removed == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (db *mongodb) FlushRecentClients(token string) (int, error) {
+	query := bson.M{}
+	if token != "" {
+		query["client"] = bson.M{"$regex": "-" + regexp.QuoteMeta(token) + "$"}
+	}
+
+	info, err := db.clients.RemoveAll(query)
+	if err != nil {
+		return 0, hierr.Errorf(
+			err, "can't remove recent clients from database",
+		)
+	}
+
+	return info.Removed, nil
+}
+
+func (db *mongodb) GetRecentClientsCount(token string) (int, error) {
+	count, err := db.clients.Find(
+		bson.M{
+			"client": bson.M{"$regex": "-" + regexp.QuoteMeta(token) + "$"},
+		},
+	).Count()
+	if err != nil {
+		return 0, hierr.Errorf(
+			err, "can't obtain recent clients count from database",
+		)
+	}
+
+	return count, nil
+}
+
+func (db *mongodb) ListRecentClients(token string) ([]RecentClient, error) {
+	var docs []struct {
+		Client     string `bson:"client"`
+		CreateDate int64  `bson:"create_date"`
+	}
+
+	err := db.clients.Find(
+		bson.M{
+			"client": bson.M{"$regex": "-" + regexp.QuoteMeta(token) + "$"},
+		},
+	).Sort("client").All(&docs)
+	if err != nil {
+		return nil, hierr.Errorf(
+			err, "can't obtain recent clients from database",
+		)
+	}
+
+	clients := []RecentClient{}
+	for _, doc := range docs {
+		clients = append(clients, RecentClient{
+			Client:   strings.TrimSuffix(doc.Client, "-"+token),
+			LastSeen: time.Unix(doc.CreateDate, 0),
+		})
+	}
+
+	return clients, nil
+}
+
 func (db *mongodb) GetTableSize(token string) (int64, error) {
-	count, err := db.shadows.Find(bson.M{"token": token}).Count()
+	selector, err := db.getTableSelector(token)
+	if err != nil {
+		return 0, err
+	}
+
+	count, err := db.shadows.Find(selector).Count()
 	if err != nil {
 		return 0, hierr.Errorf(
 			err, "can't obtain table size from database",
@@ -160,6 +681,20 @@ func (db *mongodb) GetTableSize(token string) (int64, error) {
 	return int64(count), nil
 }
 
+func (db *mongodb) GetAllTokens() ([]string, error) {
+	var tokens []string
+	err := db.shadows.Find(nil).Distinct("token", &tokens)
+	if err != nil {
+		return nil, hierr.Errorf(
+			err, "can't obtain tokens from database",
+		)
+	}
+
+	sort.Strings(tokens)
+
+	return tokens, nil
+}
+
 func (db *mongodb) GetTokens(prefix string) ([]string, error) {
 	var docs []string
 	err := db.shadows.Find(
@@ -173,6 +708,12 @@ func (db *mongodb) GetTokens(prefix string) ([]string, error) {
 		)
 	}
 
+	// tokens namespaces are not stored separately, so prefix exists only
+	// while there are tokens with that prefix.
+	if len(docs) == 0 && prefix != "" {
+		return nil, ErrNotFound
+	}
+
 	for i, doc := range docs {
 		docs[i] = strings.TrimPrefix(doc, prefix)
 	}
@@ -182,6 +723,96 @@ func (db *mongodb) GetTokens(prefix string) ([]string, error) {
 	return docs, nil
 }
 
+func (db *mongodb) GetTokensModifiedSince(
+	prefix string, since time.Time,
+) ([]string, error) {
+	var tokens []string
+	err := db.tokens.Find(
+		bson.M{
+			"token": bson.M{
+				"$regex": "^" + regexp.QuoteMeta(prefix) + ".*",
+			},
+			"modified": bson.M{"$gt": since},
+		},
+	).Distinct("token", &tokens)
+	if err != nil {
+		return nil, hierr.Errorf(
+			err, "can't obtain modified tokens from database",
+		)
+	}
+
+	for i, token := range tokens {
+		tokens[i] = strings.TrimPrefix(token, prefix)
+	}
+
+	sort.Strings(tokens)
+
+	return tokens, nil
+}
+
+func (db *mongodb) GetTokenInfo(token string) (*tokenInfo, error) {
+	info := &tokenInfo{}
+	err := db.tokens.Find(bson.M{"token": token}).One(info)
+	if err != nil {
+		if err == mgo.ErrNotFound {
+			return info, nil
+		}
+
+		return nil, hierr.Errorf(
+			err, "can't obtain token metadata from database",
+		)
+	}
+
+	return info, nil
+}
+
+func (db *mongodb) SetTokenInfo(token string, info *tokenInfo) error {
+	// document is replaced instead of using $set, so emptied fields are
+	// removed.
+	data, err := bson.Marshal(info)
+	if err != nil {
+		return hierr.Errorf(
+			err, "can't encode token metadata",
+		)
+	}
+
+	document := bson.M{}
+	err = bson.Unmarshal(data, &document)
+	if err != nil {
+		return hierr.Errorf(
+			err, "can't encode token metadata",
+		)
+	}
+
+	document["token"] = token
+
+	// generation isn't part of metadata, but document is replaced, so it
+	// should be kept, otherwise current hash table is not found
+	var current struct {
+		Generation bson.ObjectId `bson:"generation,omitempty"`
+	}
+
+	err = db.tokens.Find(bson.M{"token": token}).One(&current)
+	if err != nil && err != mgo.ErrNotFound {
+		return hierr.Errorf(
+			err, "can't get current generation of hash table",
+		)
+	}
+
+	if current.Generation != "" {
+		document["generation"] = current.Generation
+	}
+
+	_, err = db.tokens.Upsert(bson.M{"token": token}, document)
+	if err != nil {
+		return hierr.Errorf(
+			err, "can't save token metadata to database",
+		)
+	}
+
+	return nil
+}
+
 func (db *mongodb) Init() error {
 	err := db.connect()
 	if err != nil {
@@ -190,12 +821,6 @@ func (db *mongodb) Init() error {
 		)
 	}
 
-	go func() {
-		for range time.Tick(time.Minute) {
-			db.cleanupRecentClients()
-		}
-	}()
-
 	go func() {
 		for range time.Tick(time.Second * 5) {
 			db.ensureConnection()
@@ -213,10 +838,89 @@ func (db *mongodb) connect() error {
 
 	db.session = session
 
+	if db.poolLimit > 0 {
+		db.session.SetPoolLimit(db.poolLimit)
+	}
+
 	db.database = db.session.DB("")
 	db.shadows = db.database.C("shadows")
 	db.keys = db.database.C("keys")
 	db.clients = db.database.C("clients")
+	db.reservations = db.database.C("reservations")
+	db.tokens = db.database.C("tokens")
+	db.pendingShadows = db.database.C("pending_shadows")
+
+	err = db.reservations.EnsureIndex(mgo.Index{
+		Key:    []string{"token", "index"},
+		Unique: true,
+	})
+	if err != nil {
+		return hierr.Errorf(
+			err, "can't create reservations index",
+		)
+	}
+
+	err = db.shadows.EnsureIndex(mgo.Index{
+		Key: []string{"token", "generation", "_id"},
+	})
+	if err != nil {
+		return hierr.Errorf(
+			err, "can't create hash table records index",
+		)
+	}
+
+	clientsIndex := mgo.Index{
+		Key:    []string{"client"},
+		Unique: true,
+	}
+
+	err = db.clients.EnsureIndex(clientsIndex)
+	if mgo.IsDup(err) {
+		// clients added before index has been introduced can be duplicated
+		err = db.removeDuplicateClients()
+		if err != nil {
+			return err
+		}
+
+		err = db.clients.EnsureIndex(clientsIndex)
+	}
+	if err != nil {
+		return hierr.Errorf(
+			err, "can't create clients index",
+		)
+	}
+
+	return nil
+}
+
+// removeDuplicateClients leaves only the most recently added record of every
+// recent client.
+func (db *mongodb) removeDuplicateClients() error {
+	var duplicates []struct {
+		IDs []bson.ObjectId `bson:"ids"`
+	}
+
+	err := db.clients.Pipe([]bson.M{
+		{"$sort": bson.M{"create_date": -1}},
+		{"$group": bson.M{
+			"_id":   "$client",
+			"ids":   bson.M{"$push": "$_id"},
+			"count": bson.M{"$sum": 1},
+		}},
+		{"$match": bson.M{"count": bson.M{"$gt": 1}}},
+	}).All(&duplicates)
+	if err != nil {
+		return hierr.Errorf(err, "can't find duplicate recent clients")
+	}
+
+	for _, duplicate := range duplicates {
+		_, err = db.clients.RemoveAll(
+			bson.M{"_id": bson.M{"$in": duplicate.IDs[1:]}},
+		)
+		if err != nil {
+			return hierr.Errorf(err, "can't remove duplicate recent clients")
+		}
+	}
 
 	return nil
 }
@@ -241,17 +945,34 @@ func (db *mongodb) ensureConnection() {
 	log.Println("database connection established")
 }
 
-func (db *mongodb) cleanupRecentClients() {
-	_, err := db.clients.RemoveAll(
-		bson.M{
-			"create_date": bson.M{
-				"$lt": time.Now().Unix() - int64(db.hashTTL),
-			},
-		},
-	)
+func (db *mongodb) PruneRecentClients() (int, error) {
+	info, err := db.clients.RemoveAll(db.getExpiredClientsSelector())
 	if err != nil {
-		log.Println(
-			hierr.Errorf(err, "can't cleanup recent clients"),
+		return 0, hierr.Errorf(
+			err, "can't remove expired recent clients from database",
 		)
 	}
+
+	return info.Removed, nil
+}
+
+func (db *mongodb) CountExpiredRecentClients() (int, error) {
+	count, err := db.clients.Find(db.getExpiredClientsSelector()).Count()
+	if err != nil {
+		return 0, hierr.Errorf(
+			err, "can't count expired recent clients in database",
+		)
+	}
+
+	return count, nil
+}
+
+// getExpiredClientsSelector returns query, which selects clients, which are
+// no longer recent.
+func (db *mongodb) getExpiredClientsSelector() bson.M {
+	return bson.M{
+		"create_date": bson.M{
+			"$lt": time.Now().Unix() - int64(db.hashTTL/time.Second),
+		},
+	}
 }
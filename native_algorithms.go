@@ -0,0 +1,160 @@
+package main
+
+import (
+	"errors"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// bcryptCost is cost of bcrypt records, it's default cost of OpenBSD.
+const bcryptCost = 10
+
+// nativeAlgorithm is algorithm, which is implemented in Go instead of
+// system libcrypt, so it's available on every host running shadowd.
+type nativeAlgorithm struct {
+	// cryptID is algorithm id, which prefixes generated records.
+	cryptID  string
+	generate AlgorithmImplementation
+	verify   func(password string, record string) (bool, error)
+
+	// validate checks format of record without hashing password.
+	validate func(record string) error
+
+	// randomSalt is set if algorithm generates salts itself instead of
+	// taking them from salt provider, so its records can't be reproduced
+	// using --deterministic-salt.
+	randomSalt bool
+}
+
+var nativeAlgorithms = map[string]nativeAlgorithm{
+	"bcrypt": {
+		cryptID:    "2b",
+		generate:   generateBcrypt,
+		verify:     verifyBcrypt,
+		validate:   validateBcrypt,
+		randomSalt: true,
+	},
+
+	// argon2id records use the same PHC string format and parameters as
+	// password verifiers.
+	"argon2id": {
+		cryptID:  "argon2id",
+		generate: generateArgon2id,
+		verify:   verifyArgon2id,
+		validate: validateArgon2id,
+	},
+}
+
+// getAlgorithmID returns crypt id of specified algorithm, which is
+// implemented either by system libcrypt or natively.
+func getAlgorithmID(algorithm string) (string, bool) {
+	if id, ok := algorithmIDs[algorithm]; ok {
+		return id, true
+	}
+
+	if native, ok := nativeAlgorithms[algorithm]; ok {
+		return native.cryptID, true
+	}
+
+	return "", false
+}
+
+// getNativeAlgorithmByID returns native algorithm with specified crypt id.
+func getNativeAlgorithmByID(id string) (string, *nativeAlgorithm) {
+	for name, native := range nativeAlgorithms {
+		if native.cryptID == id {
+			return name, &native
+		}
+	}
+
+	return "", nil
+}
+
+// getRecordNativeAlgorithm returns native algorithm, which has produced
+// specified record, nil is returned for records of libcrypt algorithms.
+func getRecordNativeAlgorithm(record string) *nativeAlgorithm {
+	if !strings.HasPrefix(record, "$") {
+		return nil
+	}
+
+	_, native := getNativeAlgorithmByID(strings.SplitN(record[1:], "$", 2)[0])
+
+	return native
+}
+
+// verifyRecord checks that record is hash of specified password, natively
+// implemented algorithms are verified by shadowd itself, other records are
+// verified by system libcrypt.
+func verifyRecord(password string, record string) bool {
+	if native := getRecordNativeAlgorithm(record); native != nil {
+		matches, err := native.verify(password, record)
+		return err == nil && matches
+	}
+
+	return crypt(password, record) == record
+}
+
+// generateBcrypt returns bcrypt record of password. Go implementation
+// produces $2a$ records, which are the same as $2b$ records, because it
+// doesn't have length overflow bug fixed by $2b$, so records are marked
+// as $2b$ understood by all current libcrypts. Salt is generated by Go
+// implementation, it can't be passed to it.
+func generateBcrypt(password string) (string, error) {
+	record, err := bcrypt.GenerateFromPassword([]byte(password), bcryptCost)
+	if err != nil {
+		return "", err
+	}
+
+	return "$2b$" + strings.TrimPrefix(string(record), "$2a$"), nil
+}
+
+func verifyBcrypt(password string, record string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(record), []byte(password))
+	switch err {
+	case nil:
+		return true, nil
+	case bcrypt.ErrMismatchedHashAndPassword:
+		return false, nil
+	default:
+		return false, err
+	}
+}
+
+// validateBcrypt checks that record is $2b$<cost>$<salt><hash> record with
+// 22 symbols of salt and 31 symbols of hash.
+func validateBcrypt(record string) error {
+	const bcryptRecordLength = 60
+
+	if len(record) != bcryptRecordLength {
+		return errors.New("bcrypt record should be 60 symbols long")
+	}
+
+	_, err := bcrypt.Cost([]byte(record))
+	if err != nil {
+		return err
+	}
+
+	return validateRecordAlphabet(record[len("$2b$10$"):])
+}
+
+// generateArgon2id returns argon2id record of password, salt is taken from
+// salt provider like salts of libcrypt records.
+func generateArgon2id(password string) (string, error) {
+	salt, err := getSalt(saltLength)
+	if err != nil {
+		return "", err
+	}
+
+	return hashArgon2id(password, []byte(salt)), nil
+}
+
+func verifyArgon2id(password string, record string) (bool, error) {
+	return checkVerifier(record, password)
+}
+
+func validateArgon2id(record string) error {
+	_, err := parseVerifier(record)
+
+	return err
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"unicode"
+)
+
+// tokenHandler handles request for token, which is extracted from request
+// path.
+type tokenHandler func(
+	writer http.ResponseWriter, request *http.Request, token string,
+)
+
+// handleToken strips specified prefix from request path and validates the
+// rest, so all handlers get token parsed the same way and malformed paths
+// are rejected with 400 before handler runs.
+func handleToken(prefix string, handler tokenHandler) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		token := strings.TrimPrefix(request.URL.Path, prefix)
+
+		err := validateTokenPath(token)
+		if err != nil {
+			log.Printf("got bad request %q: %s", request.URL.Path, err)
+			writer.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		handler(writer, request, token)
+	}
+}
+
+// validateTokenPath checks token path, trailing slash is allowed, because it
+// is used for listing tokens.
+func validateTokenPath(token string) error {
+	if strings.IndexFunc(token, unicode.IsControl) != -1 {
+		return errors.New("path contains control characters")
+	}
+
+	if strings.HasPrefix(token, "/") {
+		return errors.New("path should not start with slash")
+	}
+
+	segments := strings.Split(strings.TrimSuffix(token, "/"), "/")
+	for _, segment := range segments {
+		if segment == "" && len(segments) > 1 {
+			return errors.New("path contains empty segment")
+		}
+
+		if segment == "." || segment == ".." {
+			return errors.New("path contains relative segment")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/kovetskiy/ko"
+	"github.com/reconquest/hierr-go"
+)
+
+// algorithmPolicy restricts algorithms, which can be used for generation of
+// hash tables of tokens matching patterns. The first matching rule is
+// applied, tokens which match no rule can use any algorithm.
+type algorithmPolicy struct {
+	Rules []algorithmRule `toml:"algorithms"`
+}
+
+type algorithmRule struct {
+	// Pattern is regular expression, which is matched against token.
+	Pattern string `toml:"pattern" required:"true"`
+
+	// Allow contains names of allowed algorithms (sha256, sha512) or crypt
+	// ids prefixed with "crypt:", e.g. "crypt:y".
+	Allow []string `toml:"allow" required:"true"`
+
+	pattern *regexp.Regexp
+}
+
+func loadAlgorithmPolicy(path string) (*algorithmPolicy, error) {
+	policy := &algorithmPolicy{}
+	err := ko.Load(path, policy)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range policy.Rules {
+		rule := &policy.Rules[i]
+
+		rule.pattern, err = regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, hierr.Errorf(
+				err, "can't compile pattern '%s'", rule.Pattern,
+			)
+		}
+	}
+
+	return policy, nil
+}
+
+// check returns error if specified algorithm is not allowed for token.
+func (policy *algorithmPolicy) check(token string, algorithm string) error {
+	for _, rule := range policy.Rules {
+		if !rule.pattern.MatchString(token) {
+			continue
+		}
+
+		for _, allowed := range rule.Allow {
+			if allowed == algorithm {
+				return nil
+			}
+		}
+
+		return fmt.Errorf(
+			"algorithm %s is not allowed for token %s by policy, "+
+				"allowed algorithms: %s",
+			algorithm, token, strings.Join(rule.Allow, ", "),
+		)
+	}
+
+	return nil
+}
+
+// getPolicyAlgorithm returns name of algorithm for specified crypt id, which
+// is used in algorithm policy.
+func getPolicyAlgorithm(cryptID string) string {
+	id := strings.SplitN(cryptID, "$", 2)[0]
+
+	for name, knownID := range algorithmIDs {
+		if knownID == id {
+			return name
+		}
+	}
+
+	if name, native := getNativeAlgorithmByID(id); native != nil {
+		return name
+	}
+
+	return "crypt:" + id
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"log"
+	"net"
+	"net/http"
+	"net/http/pprof"
+
+	"github.com/reconquest/hierr-go"
+)
+
+// startPprof serves net/http/pprof handlers over plain HTTP on separate
+// listener, handlers are never registered on main TLS listener.
+func startPprof(address string) (*http.Server, error) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, hierr.Errorf(err, "can't listen %s for pprof", address)
+	}
+
+	if !isLoopbackAddress(listener.Addr()) {
+		log.Printf(
+			"warning: pprof listener %s is not bound to loopback address, "+
+				"profiles are available without authentication",
+			listener.Addr(),
+		)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	server := &http.Server{Handler: mux}
+
+	go func() {
+		log.Println("starting pprof listening on", listener.Addr())
+
+		err := server.Serve(listener)
+		if err != nil && err != http.ErrServerClosed {
+			log.Println(hierr.Errorf(err, "can't serve pprof"))
+		}
+	}()
+
+	return server, nil
+}
+
+func isLoopbackAddress(address net.Addr) bool {
+	tcp, ok := address.(*net.TCPAddr)
+
+	return ok && tcp.IP.IsLoopback()
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// stubbedCrypt verifies only records, which match password.
+func stubbedCrypt(password string, record string) bool {
+	return record == "stub:"+password
+}
+
+func TestProbeAcceptsVerifiableAlgorithm(t *testing.T) {
+	implementation := func(password string) (string, error) {
+		return "stub:" + password, nil
+	}
+
+	err := probeAlgorithm("stub", implementation, stubbedCrypt)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestProbeRejectsUnverifiableAlgorithm(t *testing.T) {
+	generated := 0
+	implementation := func(password string) (string, error) {
+		generated++
+		return "$y$unverifiable", nil
+	}
+
+	err := probeAlgorithm("yescrypt", implementation, stubbedCrypt)
+	if err == nil {
+		t.Fatal("record, which can't be verified, is accepted")
+	}
+
+	if err.Error() != "this host cannot verify yescrypt" {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if generated != 1 {
+		t.Fatalf("%d records generated instead of one", generated)
+	}
+}
+
+func TestProbeRejectsEmptyRecord(t *testing.T) {
+	implementation := func(password string) (string, error) {
+		return "", nil
+	}
+
+	// crypt, which fails, returns empty record, which matches empty record
+	// from implementation
+	verify := func(password string, record string) bool {
+		return true
+	}
+
+	err := probeAlgorithm("stub", implementation, verify)
+	if err == nil {
+		t.Fatal("empty record is accepted")
+	}
+}
+
+func TestProbeReportsGenerationError(t *testing.T) {
+	implementation := func(password string) (string, error) {
+		return "", errors.New("no entropy")
+	}
+
+	err := probeAlgorithm("stub", implementation, stubbedCrypt)
+	if err == nil || !strings.Contains(err.Error(), "no entropy") {
+		t.Fatalf("generation error is not reported: %v", err)
+	}
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/reconquest/hierr-go"
+)
+
+// provisionDebounce is time, during which changes of definition file are
+// collected before hash table is updated, so editors and git checkouts
+// writing file several times trigger only one update.
+const provisionDebounce = time.Second
+
+// provisioner watches directory with hash table definition files and stores
+// hash tables when files are created or changed. Token is path of file
+// relative to directory, file contains already hashed records, one per line.
+type provisioner struct {
+	backend Backend
+	dir     string
+	watcher *fsnotify.Watcher
+
+	// pending contains definition files, which should be provisioned after
+	// debounce time.
+	pending map[string]struct{}
+}
+
+func newProvisioner(backend Backend, dir string) (*provisioner, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, hierr.Errorf(err, "can't create file watcher")
+	}
+
+	provisioner := &provisioner{
+		backend: backend,
+		dir:     dir,
+		watcher: watcher,
+		pending: map[string]struct{}{},
+	}
+
+	err = provisioner.watch(dir)
+	if err != nil {
+		watcher.Close()
+
+		return nil, hierr.Errorf(err, "can't watch directory %s", dir)
+	}
+
+	return provisioner, nil
+}
+
+// watch adds specified directory and all its subdirectories to watcher,
+// definition files found in them are marked as pending.
+func (provisioner *provisioner) watch(root string) error {
+	return filepath.Walk(
+		root,
+		func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if info.IsDir() {
+				return provisioner.watcher.Add(path)
+			}
+
+			provisioner.pending[path] = struct{}{}
+
+			return nil
+		},
+	)
+}
+
+// run provisions definition files found on start and then changed ones
+// until stop is closed.
+func (provisioner *provisioner) run(stop <-chan struct{}) {
+	defer provisioner.watcher.Close()
+
+	flush := time.After(0)
+
+	for {
+		select {
+		case <-stop:
+			return
+
+		case event, ok := <-provisioner.watcher.Events:
+			if !ok {
+				return
+			}
+
+			if event.Op&(fsnotify.Create|fsnotify.Write) == 0 {
+				continue
+			}
+
+			info, err := os.Stat(event.Name)
+			if err != nil {
+				continue
+			}
+
+			if info.IsDir() {
+				err = provisioner.watch(event.Name)
+				if err != nil {
+					log.Println(hierr.Errorf(
+						err, "can't watch directory %s", event.Name,
+					))
+				}
+			} else {
+				provisioner.pending[event.Name] = struct{}{}
+			}
+
+			flush = time.After(provisionDebounce)
+
+		case err, ok := <-provisioner.watcher.Errors:
+			if !ok {
+				return
+			}
+
+			log.Println(hierr.Errorf(err, "can't watch provision directory"))
+
+		case <-flush:
+			for path := range provisioner.pending {
+				err := provisioner.provision(path)
+				if err != nil {
+					log.Println(hierr.Errorf(
+						err, "can't provision hash table from %s", path,
+					))
+				}
+			}
+
+			provisioner.pending = map[string]struct{}{}
+		}
+	}
+}
+
+// provision stores hash table defined by specified file, hidden and backup
+// files are skipped.
+func (provisioner *provisioner) provision(path string) error {
+	name := filepath.Base(path)
+	if strings.HasPrefix(name, ".") || strings.HasSuffix(name, "~") {
+		return nil
+	}
+
+	relative, err := filepath.Rel(provisioner.dir, path)
+	if err != nil {
+		return err
+	}
+
+	token := filepath.ToSlash(relative)
+
+	err = validateToken(token)
+	if err != nil {
+		return err
+	}
+
+	table, err := readDefinitionFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+
+		return err
+	}
+
+	if len(table) == 0 {
+		return errors.New("no records found")
+	}
+
+	err = storeImportedHashTable(provisioner.backend, token, table, nil)
+	if err != nil {
+		return err
+	}
+
+	log.Printf(
+		"hash table %s with %d items has been provisioned from %s",
+		token, len(table), path,
+	)
+
+	return nil
+}
+
+// readDefinitionFile reads records from definition file, all records should
+// be valid, so broken file doesn't replace served hash table.
+func readDefinitionFile(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	var (
+		table = []string{}
+		line  = 0
+	)
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line++
+
+		record := strings.TrimSpace(scanner.Text())
+		if record == "" {
+			continue
+		}
+
+		err := validateRecord(record)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", line, err)
+		}
+
+		table = append(table, record)
+	}
+
+	return table, scanner.Err()
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/reconquest/hierr-go"
+)
+
+const (
+	// proxyHeaderTimeout is time given to load balancer for sending PROXY
+	// protocol header after connection is accepted.
+	proxyHeaderTimeout = 10 * time.Second
+
+	// proxyV1MaxLength is maximum length of PROXY protocol v1 header
+	// including CRLF.
+	proxyV1MaxLength = 107
+
+	proxyV2CommandLocal = 0x0
+	proxyV2CommandProxy = 0x1
+
+	proxyV2FamilyInet  = 0x1
+	proxyV2FamilyInet6 = 0x2
+)
+
+var proxyV2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+// proxyListener accepts connections, which start with PROXY protocol v1 or
+// v2 header sent by load balancer, remote address of such connections is
+// address of client taken from header.
+type proxyListener struct {
+	net.Listener
+}
+
+func (listener proxyListener) Accept() (net.Conn, error) {
+	conn, err := listener.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+
+	return &proxyConn{
+		Conn:   conn,
+		reader: bufio.NewReader(conn),
+		once:   &sync.Once{},
+	}, nil
+}
+
+// proxyConn reads PROXY protocol header on first use, so slow load balancer
+// doesn't block accepting of other connections.
+type proxyConn struct {
+	net.Conn
+
+	reader *bufio.Reader
+	remote net.Addr
+	err    error
+	once   *sync.Once
+}
+
+func (conn *proxyConn) init() {
+	conn.once.Do(func() {
+		conn.Conn.SetReadDeadline(time.Now().Add(proxyHeaderTimeout))
+
+		conn.remote, conn.err = readProxyHeader(conn.reader)
+		if conn.err != nil {
+			conn.err = hierr.Errorf(
+				conn.err, "can't read PROXY protocol header from %s",
+				conn.Conn.RemoteAddr(),
+			)
+
+			log.Println(conn.err)
+		}
+
+		conn.Conn.SetReadDeadline(time.Time{})
+	})
+}
+
+func (conn *proxyConn) Read(data []byte) (int, error) {
+	conn.init()
+	if conn.err != nil {
+		return 0, conn.err
+	}
+
+	return conn.reader.Read(data)
+}
+
+func (conn *proxyConn) RemoteAddr() net.Addr {
+	conn.init()
+	if conn.remote != nil {
+		return conn.remote
+	}
+
+	return conn.Conn.RemoteAddr()
+}
+
+// readProxyHeader reads PROXY protocol header of v1 or v2 format and
+// returns address of client, address is nil if header doesn't contain it
+// (e.g. health checks of load balancer).
+func readProxyHeader(reader *bufio.Reader) (net.Addr, error) {
+	signature, err := reader.Peek(len(proxyV2Signature))
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case bytes.Equal(signature, proxyV2Signature):
+		return readProxyHeaderV2(reader)
+
+	case bytes.HasPrefix(signature, []byte("PROXY ")):
+		return readProxyHeaderV1(reader)
+	}
+
+	return nil, errors.New("connection doesn't start with PROXY header")
+}
+
+// readProxyHeaderV1 reads human-readable header, e.g.:
+//
+//	PROXY TCP4 203.0.113.7 192.0.2.1 56324 443\r\n
+func readProxyHeaderV1(reader *bufio.Reader) (net.Addr, error) {
+	var line []byte
+	for !bytes.HasSuffix(line, []byte("\r\n")) {
+		if len(line) >= proxyV1MaxLength {
+			return nil, errors.New("v1 header is too long")
+		}
+
+		symbol, err := reader.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+
+		line = append(line, symbol)
+	}
+
+	fields := strings.Fields(string(line))
+	if len(fields) >= 2 && fields[1] == "UNKNOWN" {
+		return nil, nil
+	}
+
+	if len(fields) != 6 {
+		return nil, fmt.Errorf("invalid v1 header: %q", line)
+	}
+
+	switch fields[1] {
+	case "TCP4", "TCP6":
+	default:
+		return nil, fmt.Errorf("unknown v1 protocol: %s", fields[1])
+	}
+
+	ip := net.ParseIP(fields[2])
+	if ip == nil {
+		return nil, fmt.Errorf("invalid v1 source address: %s", fields[2])
+	}
+
+	port, err := strconv.ParseUint(fields[4], 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("invalid v1 source port: %s", fields[4])
+	}
+
+	return &net.TCPAddr{IP: ip, Port: int(port)}, nil
+}
+
+// readProxyHeaderV2 reads binary header: signature, version and command,
+// address family, length of addresses block and addresses block itself.
+func readProxyHeaderV2(reader *bufio.Reader) (net.Addr, error) {
+	header := make([]byte, len(proxyV2Signature)+4)
+
+	_, err := io.ReadFull(reader, header)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		version = header[12] >> 4
+		command = header[12] & 0xf
+		family  = header[13] >> 4
+		length  = binary.BigEndian.Uint16(header[14:])
+	)
+
+	if version != 2 {
+		return nil, fmt.Errorf("unsupported v2 header version: %d", version)
+	}
+
+	addresses := make([]byte, length)
+
+	_, err = io.ReadFull(reader, addresses)
+	if err != nil {
+		return nil, err
+	}
+
+	switch command {
+	case proxyV2CommandLocal:
+		return nil, nil
+
+	case proxyV2CommandProxy:
+
+	default:
+		return nil, fmt.Errorf("unknown v2 command: %d", command)
+	}
+
+	var size int
+	switch family {
+	case proxyV2FamilyInet:
+		size = net.IPv4len
+
+	case proxyV2FamilyInet6:
+		size = net.IPv6len
+
+	default:
+		// unix sockets and unspecified family carry no client address
+		return nil, nil
+	}
+
+	if len(addresses) < size*2+4 {
+		return nil, fmt.Errorf(
+			"v2 addresses block is too short: %d bytes", len(addresses),
+		)
+	}
+
+	return &net.TCPAddr{
+		IP:   net.IP(addresses[:size]),
+		Port: int(binary.BigEndian.Uint16(addresses[size*2:])),
+	}, nil
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"text/template"
+
+	"github.com/reconquest/hierr-go"
+)
+
+// recentKeyFields are fields available in recent client key template.
+type recentKeyFields struct {
+	// Client is address of client without port.
+	Client string
+	Token  string
+
+	// Header contains request headers, e.g. {{.Header.Get "X-Host-Id"}}.
+	Header http.Header
+}
+
+// recentKeyTokenSuffix should end every recent key template, because recent
+// clients of token are listed, counted and removed by key suffix.
+const recentKeyTokenSuffix = "-{{.Token}}"
+
+// parseRecentKeyTemplate parses template of recent client key and checks
+// that it can be evaluated.
+func parseRecentKeyTemplate(text string) (*template.Template, error) {
+	if !strings.HasSuffix(text, recentKeyTokenSuffix) {
+		return nil, fmt.Errorf(
+			"recent key template should end with %s", recentKeyTokenSuffix,
+		)
+	}
+
+	keyTemplate, err := template.New("recent-key").
+		Option("missingkey=error").
+		Parse(text)
+	if err != nil {
+		return nil, hierr.Errorf(err, "can't parse recent key template")
+	}
+
+	key, err := executeRecentKeyTemplate(
+		keyTemplate,
+		recentKeyFields{
+			Client: "127.0.0.1",
+			Token:  "pool/token",
+			Header: http.Header{},
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if key == "" {
+		return nil, errors.New("recent key template produces empty key")
+	}
+
+	return keyTemplate, nil
+}
+
+func executeRecentKeyTemplate(
+	keyTemplate *template.Template, fields recentKeyFields,
+) (string, error) {
+	buffer := &bytes.Buffer{}
+
+	err := keyTemplate.Execute(buffer, fields)
+	if err != nil {
+		return "", hierr.Errorf(err, "can't evaluate recent key template")
+	}
+
+	return buffer.String(), nil
+}
+
+// getRecentKey returns key of client, which is used for tracking recent
+// clients.
+func (server *Server) getRecentKey(
+	request *http.Request, input hashInput,
+) (string, error) {
+	return executeRecentKeyTemplate(
+		server.recentKeyTemplate,
+		recentKeyFields{
+			Client: input.client,
+			Token:  input.token,
+			Header: request.Header,
+		},
+	)
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// recentClientsLimit bounds amount of distinct recent clients tracked for
+// every token, so flood of requests from spoofed addresses for one token
+// can't inflate recent clients store. Clients above limit are served, but
+// not tracked, so they don't receive alternate entries on repeated requests.
+type recentClientsLimit struct {
+	limit int
+	ttl   time.Duration
+
+	// clients contains expiration times of tracked recent clients of every
+	// token.
+	clients map[string]map[string]time.Time
+
+	// reached contains tokens, which have reached limit, so it's logged
+	// only once until clients of token expire.
+	reached map[string]bool
+
+	lock *sync.Mutex
+}
+
+func newRecentClientsLimit(
+	limit int, ttl time.Duration,
+) *recentClientsLimit {
+	return &recentClientsLimit{
+		limit:   limit,
+		ttl:     ttl,
+		clients: map[string]map[string]time.Time{},
+		reached: map[string]bool{},
+		lock:    &sync.Mutex{},
+	}
+}
+
+// track reports whether specified recent client of token can be tracked
+// and remembers it if so. Limit of zero allows tracking of all clients.
+func (limit *recentClientsLimit) track(
+	token string, remote string, now time.Time,
+) bool {
+	if limit.limit == 0 {
+		return true
+	}
+
+	limit.lock.Lock()
+	defer limit.lock.Unlock()
+
+	clients, ok := limit.clients[token]
+	if !ok {
+		clients = map[string]time.Time{}
+		limit.clients[token] = clients
+	}
+
+	_, tracked := clients[remote]
+	if !tracked && len(clients) >= limit.limit {
+		for client, expiration := range clients {
+			if now.After(expiration) {
+				delete(clients, client)
+			}
+		}
+	}
+
+	if !tracked && len(clients) >= limit.limit {
+		if !limit.reached[token] {
+			log.Printf(
+				"limit of %d recent clients reached for %s, "+
+					"new clients are not tracked",
+				limit.limit, token,
+			)
+
+			limit.reached[token] = true
+		}
+
+		return false
+	}
+
+	delete(limit.reached, token)
+
+	clients[remote] = now.Add(limit.ttl)
+
+	return true
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/reconquest/hierr-go"
+)
+
+const (
+	// recentReplicationBufferSize is amount of events buffered for standby,
+	// events are dropped if standby can't keep up.
+	recentReplicationBufferSize = 1000
+
+	// recentReplicationTimeout is time given to standby for applying event.
+	recentReplicationTimeout = 5 * time.Second
+
+	recentEventAdd     = "add"
+	recentEventRequest = "request"
+	recentEventDelete  = "delete"
+	recentEventFlush   = "flush"
+)
+
+// recentEvent describes change of recent client state, which is replicated
+// to standby server. Flush events have token instead of remote address,
+// empty token flushes recent clients of all tokens.
+type recentEvent struct {
+	Type   string `json:"type"`
+	Remote string `json:"remote,omitempty"`
+	Token  string `json:"token,omitempty"`
+}
+
+// recentReplicator sends recent client events to standby server, so
+// standby serves alternate entries to same clients after failover.
+// Replication is best-effort: events are sent asynchronously and dropped
+// when standby is unreachable or buffer is full.
+type recentReplicator struct {
+	endpoint string
+	client   *http.Client
+	events   chan recentEvent
+}
+
+func newRecentReplicator(
+	address string, client *http.Client,
+) *recentReplicator {
+	client.Timeout = recentReplicationTimeout
+
+	return &recentReplicator{
+		endpoint: "https://" + address + "/admin/replicate/recent",
+		client:   client,
+		events:   make(chan recentEvent, recentReplicationBufferSize),
+	}
+}
+
+// publish queues event without blocking, nothing is done if replication is
+// not configured.
+func (replicator *recentReplicator) publish(event recentEvent) {
+	if replicator == nil {
+		return
+	}
+
+	select {
+	case replicator.events <- event:
+	default:
+	}
+}
+
+// run sends queued events to standby until stop is closed.
+func (replicator *recentReplicator) run(stop <-chan struct{}) {
+	for {
+		select {
+		case <-stop:
+			return
+
+		case event := <-replicator.events:
+			err := replicator.send(event)
+			if err != nil {
+				log.Println(
+					hierr.Errorf(err, "can't replicate recent client"),
+				)
+			}
+		}
+	}
+}
+
+func (replicator *recentReplicator) send(event recentEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	response, err := replicator.client.Post(
+		replicator.endpoint, "application/json", bytes.NewReader(body),
+	)
+	if err != nil {
+		return err
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("standby responded with %s", response.Status)
+	}
+
+	return nil
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	recordSaltMaxLength = 16
+	recordRoundsMin     = 1000
+	recordRoundsMax     = 999999999
+	recordAlphabet      = "./0123456789" +
+		"ABCDEFGHIJKLMNOPQRSTUVWXYZ" +
+		"abcdefghijklmnopqrstuvwxyz"
+)
+
+// recordHashLengths contains lengths of encoded hashes for supported crypt
+// algorithm ids.
+var recordHashLengths = map[string]int{
+	"5": 43,
+	"6": 86,
+}
+
+type shadowRecord struct {
+	id     string
+	rounds string
+	salt   string
+	hash   string
+}
+
+func parseRecord(record string) (*shadowRecord, error) {
+	parts := strings.Split(record, "$")
+	if len(parts) < 4 || parts[0] != "" {
+		return nil, errors.New(
+			"record should be in $id$salt$hash or " +
+				"$id$rounds=N$salt$hash format",
+		)
+	}
+
+	parsed := &shadowRecord{
+		id: parts[1],
+	}
+
+	fields := parts[2:]
+	if len(fields) == 3 && strings.HasPrefix(fields[0], "rounds=") {
+		parsed.rounds = strings.TrimPrefix(fields[0], "rounds=")
+		fields = fields[1:]
+	}
+
+	if len(fields) != 2 {
+		return nil, fmt.Errorf(
+			"record should contain salt and hash, but %d fields found",
+			len(fields),
+		)
+	}
+
+	parsed.salt, parsed.hash = fields[0], fields[1]
+
+	return parsed, nil
+}
+
+// validateRecord checks that record can be verified by crypt(3), salt and
+// hash should have length and symbols which are expected by algorithm.
+// Records of natively implemented algorithms are validated by them.
+func validateRecord(record string) error {
+	if native := getRecordNativeAlgorithm(record); native != nil {
+		return native.validate(record)
+	}
+
+	parsed, err := parseRecord(record)
+	if err != nil {
+		return err
+	}
+
+	hashLength, ok := recordHashLengths[parsed.id]
+	if !ok {
+		return fmt.Errorf("unsupported algorithm id '%s'", parsed.id)
+	}
+
+	if parsed.rounds != "" {
+		rounds, err := strconv.Atoi(parsed.rounds)
+		if err != nil {
+			return fmt.Errorf("invalid rounds value '%s'", parsed.rounds)
+		}
+
+		if rounds < recordRoundsMin || rounds > recordRoundsMax {
+			return fmt.Errorf(
+				"rounds value %d is out of range %d-%d",
+				rounds, recordRoundsMin, recordRoundsMax,
+			)
+		}
+	}
+
+	err = validateSalt(parsed.salt)
+	if err != nil {
+		return err
+	}
+
+	if len(parsed.hash) != hashLength {
+		return fmt.Errorf(
+			"hash length for algorithm id '%s' should be %d, but it is %d",
+			parsed.id, hashLength, len(parsed.hash),
+		)
+	}
+
+	err = validateRecordAlphabet(parsed.hash)
+	if err != nil {
+		return fmt.Errorf("hash %s", err)
+	}
+
+	return nil
+}
+
+// validateSalt checks that salt is accepted by crypt(3) as is, longer salts
+// are truncated and other symbols are rejected or produce broken records.
+func validateSalt(salt string) error {
+	if salt == "" || len(salt) > recordSaltMaxLength {
+		return fmt.Errorf(
+			"salt length should be from 1 to %d, but it is %d",
+			recordSaltMaxLength, len(salt),
+		)
+	}
+
+	err := validateRecordAlphabet(salt)
+	if err != nil {
+		return fmt.Errorf("salt %s", err)
+	}
+
+	return nil
+}
+
+func validateRecordAlphabet(value string) error {
+	for position, symbol := range value {
+		if !strings.ContainsRune(recordAlphabet, symbol) {
+			return fmt.Errorf(
+				"contains invalid symbol %q at position %d",
+				symbol, position+1,
+			)
+		}
+	}
+
+	return nil
+}
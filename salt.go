@@ -0,0 +1,78 @@
+package main
+
+import (
+	"crypto/rand"
+	"io"
+	mathrand "math/rand"
+
+	"github.com/reconquest/hierr-go"
+)
+
+// SaltProvider provides salts for generated hash table records.
+type SaltProvider interface {
+	GetSalt(length int) (string, error)
+}
+
+// saltProvider is used for generating all hash table records.
+var saltProvider SaltProvider = randomSaltProvider{entropy: rand.Reader}
+
+// useDeterministicSalt makes salts reproducible from specified seed, so the
+// same password and seed produce identical hash tables. Such salts are
+// predictable, so it's only for test tables.
+func useDeterministicSalt(seed int64) {
+	saltProvider = randomSaltProvider{
+		entropy: mathrand.New(mathrand.NewSource(seed)),
+	}
+}
+
+// getSalt returns salt of specified length from salt provider, salt is
+// validated before it's passed to crypt(3), so misbehaving provider can't
+// produce malformed records.
+func getSalt(length int) (string, error) {
+	salt, err := saltProvider.GetSalt(length)
+	if err != nil {
+		return "", err
+	}
+
+	err = validateSalt(salt)
+	if err != nil {
+		return "", hierr.Errorf(err, "salt provider returned invalid salt")
+	}
+
+	return salt, nil
+}
+
+// randomSaltProvider generates salts of salt symbols using bytes read from
+// entropy source, which is crypto/rand reader unless other reader is
+// injected, e.g. fixed bytes for checking produced salts.
+type randomSaltProvider struct {
+	entropy io.Reader
+}
+
+func (provider randomSaltProvider) GetSalt(length int) (string, error) {
+	var (
+		salt = make([]rune, 0, length)
+		data = make([]byte, length)
+
+		// bytes above that limit are skipped to keep all symbols equally
+		// probable
+		limit = 256 - 256%len(saltSymbols)
+	)
+
+	for len(salt) < length {
+		_, err := io.ReadFull(provider.entropy, data)
+		if err != nil {
+			return "", err
+		}
+
+		for _, value := range data {
+			if int(value) >= limit || len(salt) == length {
+				continue
+			}
+
+			salt = append(salt, saltSymbols[int(value)%len(saltSymbols)])
+		}
+	}
+
+	return string(salt), nil
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// fixedSaltProvider returns the same salt for every record.
+type fixedSaltProvider struct {
+	salt string
+}
+
+func (provider fixedSaltProvider) GetSalt(length int) (string, error) {
+	return provider.salt, nil
+}
+
+// withSaltProvider replaces salt provider until the end of test.
+func withSaltProvider(t *testing.T, provider SaltProvider) {
+	previous := saltProvider
+	saltProvider = provider
+
+	t.Cleanup(func() {
+		saltProvider = previous
+	})
+}
+
+func TestGenerateRecordsUsingFixedSaltProvider(t *testing.T) {
+	withSaltProvider(t, fixedSaltProvider{salt: "abcdefghijklmnop"})
+
+	for algorithm, prefix := range map[string]string{
+		"sha256": "$5$abcdefghijklmnop$",
+		"sha512": "$6$abcdefghijklmnop$",
+	} {
+		record, err := getAlgorithmImplementation(algorithm)("password")
+		if err != nil {
+			t.Fatalf("%s: %s", algorithm, err)
+		}
+
+		if !strings.HasPrefix(record, prefix) {
+			t.Fatalf(
+				"%s: record %s doesn't use provided salt", algorithm, record,
+			)
+		}
+
+		if !verifyRecord("password", record) {
+			t.Fatalf("%s: record %s doesn't match password", algorithm, record)
+		}
+	}
+}
+
+func TestRejectInvalidSaltOfProvider(t *testing.T) {
+	withSaltProvider(t, fixedSaltProvider{salt: "abc$def"})
+
+	_, err := generateSHA256("password")
+	if err == nil {
+		t.Fatal("salt with invalid symbol is accepted")
+	}
+}
+
+func TestGetSaltFromInjectedEntropy(t *testing.T) {
+	provider := randomSaltProvider{
+		entropy: bytes.NewReader([]byte{0, 1, 2, 63, 64, 127, 128, 255}),
+	}
+
+	salt, err := provider.GetSalt(8)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if salt != "qwe/q/q/" {
+		t.Fatalf("unexpected salt %s", salt)
+	}
+}
+
+func TestGetSaltUsesAllSymbolsEqually(t *testing.T) {
+	entropy := make([]byte, 256)
+	for value := range entropy {
+		entropy[value] = byte(value)
+	}
+
+	provider := randomSaltProvider{entropy: bytes.NewReader(entropy)}
+
+	salt, err := provider.GetSalt(len(entropy))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, symbol := range saltSymbols {
+		count := strings.Count(salt, string(symbol))
+		if count != len(entropy)/len(saltSymbols) {
+			t.Fatalf("symbol %c is used %d times", symbol, count)
+		}
+	}
+}
+
+func TestGetSaltSkipsBytesAboveLimit(t *testing.T) {
+	previous := saltSymbols
+	saltSymbols = []rune("abc")
+	t.Cleanup(func() {
+		saltSymbols = previous
+	})
+
+	// limit is 255 for 3 symbols, so 255 is skipped and the next chunk of
+	// entropy is read
+	provider := randomSaltProvider{
+		entropy: bytes.NewReader([]byte{255, 0, 255, 4}),
+	}
+
+	salt, err := provider.GetSalt(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if salt != "ab" {
+		t.Fatalf("unexpected salt %s", salt)
+	}
+}
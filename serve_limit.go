@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// serveLimitWindow is length of sliding window of global serve limit.
+const serveLimitWindow = time.Minute
+
+// serveLimiter bounds total amount of entries served by server during
+// sliding window, so leaked credentials can't be used for pulling whole
+// hash tables quickly.
+type serveLimiter struct {
+	limit  int
+	window time.Duration
+
+	// served contains times of entries served during window in ascending
+	// order.
+	served []time.Time
+	lock   *sync.Mutex
+}
+
+func newServeLimiter(limit int, window time.Duration) *serveLimiter {
+	return &serveLimiter{
+		limit:  limit,
+		window: window,
+		lock:   &sync.Mutex{},
+	}
+}
+
+// allow reports whether one more entry can be served now and counts it if
+// so. Limiter with zero limit allows everything.
+func (limiter *serveLimiter) allow(now time.Time) bool {
+	if limiter.limit == 0 {
+		return true
+	}
+
+	limiter.lock.Lock()
+	defer limiter.lock.Unlock()
+
+	expired := 0
+	for expired < len(limiter.served) &&
+		!limiter.served[expired].After(now.Add(-limiter.window)) {
+		expired++
+	}
+
+	if expired > 0 {
+		limiter.served = append(limiter.served[:0], limiter.served[expired:]...)
+	}
+
+	if len(limiter.served) >= limiter.limit {
+		return false
+	}
+
+	limiter.served = append(limiter.served, now)
+
+	return true
+}
+
+// cancel gives back slot counted by allow at specified time, when entry
+// hasn't been served after all.
+func (limiter *serveLimiter) cancel(allowed time.Time) {
+	if limiter.limit == 0 {
+		return
+	}
+
+	limiter.lock.Lock()
+	defer limiter.lock.Unlock()
+
+	for i := len(limiter.served) - 1; i >= 0; i-- {
+		if limiter.served[i].Equal(allowed) {
+			limiter.served = append(
+				limiter.served[:i], limiter.served[i+1:]...,
+			)
+			return
+		}
+	}
+}
+
+// retryAfterRange is range of seconds, from which Retry-After value is
+// chosen randomly, so clients denied at the same time don't retry at the
+// same time too.
+type retryAfterRange struct {
+	min int
+	max int
+}
+
+// parseRetryAfterRange parses range in <min>-<max> format, single value
+// disables jitter.
+func parseRetryAfterRange(value string) (retryAfterRange, error) {
+	var (
+		bounds = strings.SplitN(value, "-", 2)
+		result retryAfterRange
+		err    error
+	)
+
+	result.min, err = strconv.Atoi(bounds[0])
+	if err == nil {
+		result.max = result.min
+		if len(bounds) == 2 {
+			result.max, err = strconv.Atoi(bounds[1])
+		}
+	}
+
+	if err != nil || result.min < 0 || result.max < result.min {
+		return result, fmt.Errorf("invalid Retry-After range: %s", value)
+	}
+
+	return result, nil
+}
+
+func (retryAfter retryAfterRange) get() string {
+	return strconv.Itoa(
+		retryAfter.min + rand.Intn(retryAfter.max-retryAfter.min+1),
+	)
+}
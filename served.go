@@ -0,0 +1,73 @@
+package main
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/reconquest/hierr-go"
+)
+
+// servedCounter buffers counts of served hash entries in memory, so backend
+// is not written on every request, counts are written by flush.
+type servedCounter struct {
+	backend Backend
+	counts  map[string]int64
+	lock    *sync.Mutex
+}
+
+func newServedCounter(backend Backend) *servedCounter {
+	return &servedCounter{
+		backend: backend,
+		counts:  map[string]int64{},
+		lock:    &sync.Mutex{},
+	}
+}
+
+func (counter *servedCounter) add(token string) {
+	counter.lock.Lock()
+	defer counter.lock.Unlock()
+
+	counter.counts[token]++
+}
+
+// flush writes buffered counts to backend, counts which can't be written are
+// kept for next flush.
+func (counter *servedCounter) flush() {
+	counter.lock.Lock()
+	counts := counter.counts
+	counter.counts = map[string]int64{}
+	counter.lock.Unlock()
+
+	for token, delta := range counts {
+		err := counter.backend.AddServed(token, delta)
+		if err != nil {
+			log.Println(
+				hierr.Errorf(err, "can't count served entries of %s", token),
+			)
+
+			counter.lock.Lock()
+			counter.counts[token] += delta
+			counter.lock.Unlock()
+		}
+	}
+}
+
+// flushPeriodically flushes buffered counts every specified interval until
+// stop is closed.
+func (counter *servedCounter) flushPeriodically(
+	interval time.Duration, stop <-chan struct{},
+) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		counter.flush()
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// servedTokens is list of glob patterns of tokens, which are served by
+// listener, nil list allows all tokens. Pattern is matched against whole
+// token, so `*` doesn't match `/` of nested namespaces.
+type servedTokens []string
+
+func parseServedTokens(value string) (servedTokens, error) {
+	patterns := servedTokens{}
+	for _, pattern := range strings.Split(value, ",") {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+
+		_, err := path.Match(pattern, "")
+		if err != nil {
+			return nil, fmt.Errorf("invalid token pattern '%s'", pattern)
+		}
+
+		patterns = append(patterns, pattern)
+	}
+
+	if len(patterns) == 0 {
+		return nil, fmt.Errorf("no token patterns specified")
+	}
+
+	return patterns, nil
+}
+
+// allows returns true if specified token matches any of patterns.
+func (patterns servedTokens) allows(token string) bool {
+	if patterns == nil {
+		return true
+	}
+
+	for _, pattern := range patterns {
+		matched, _ := path.Match(pattern, token)
+		if matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+// filter leaves only tokens listed under specified prefix, which are
+// served.
+func (patterns servedTokens) filter(prefix string, tokens []string) []string {
+	if patterns == nil {
+		return tokens
+	}
+
+	served := []string{}
+	for _, token := range tokens {
+		if patterns.allows(prefix + token) {
+			served = append(served, token)
+		}
+	}
+
+	return served
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/url"
+	"sort"
+	"time"
+)
+
+// effectiveSettings is summary of configuration, which server is started
+// with, secrets are never included.
+type effectiveSettings struct {
+	Listen        string   `json:"listen"`
+	ListenHTTP3   string   `json:"listen_http3,omitempty"`
+	ListenGRPC    string   `json:"listen_grpc,omitempty"`
+	Backend       string   `json:"backend"`
+	BackendDSN    string   `json:"backend_dsn,omitempty"`
+	HashTTL       string   `json:"hash_ttl"`
+	Algorithms    []string `json:"algorithms"`
+	TLS           string   `json:"tls"`
+	ProxyProtocol bool     `json:"proxy_protocol"`
+	KeepAlive     string   `json:"keep_alive"`
+	TLSLog        string   `json:"tls_log"`
+	PoolLimit     string   `json:"pool_limit,omitempty"`
+	HMACKeyFile   string   `json:"hmac_key_file,omitempty"`
+	CacheSize     string   `json:"cache_size"`
+	Reserve       bool     `json:"reserve"`
+}
+
+// logEffectiveSettings logs single JSON record with effective settings of
+// server, so operators can confirm running configuration at a glance.
+func logEffectiveSettings(
+	backend Backend, args map[string]interface{}, hashTTL time.Duration,
+) {
+	settings := effectiveSettings{
+		Listen:        args["--listen"].(string),
+		HashTTL:       hashTTL.String(),
+		TLS:           "server",
+		ProxyProtocol: args["--proxy-protocol"].(bool),
+		KeepAlive:     args["--keep-alive"].(string),
+		TLSLog:        args["--tls-log"].(string),
+		CacheSize:     args["--cache-size"].(string),
+		Reserve:       args["--reserve"].(bool),
+	}
+
+	settings.ListenHTTP3, _ = args["--listen-http3"].(string)
+	settings.ListenGRPC, _ = args["--listen-grpc"].(string)
+	settings.HMACKeyFile, _ = args["--hmac-key-file"].(string)
+
+	if _, ok := args["--client-ca"].(string); ok {
+		settings.TLS = "mutual"
+	}
+
+	switch typed := backend.(type) {
+	case *filesystem:
+		settings.Backend = "filesystem"
+
+	case *mongodb:
+		settings.Backend = "mongodb"
+		settings.BackendDSN = redactDSN(typed.dsn)
+		settings.PoolLimit = args["--pool-limit"].(string)
+	}
+
+	for algorithm := range algorithmIDs {
+		settings.Algorithms = append(settings.Algorithms, algorithm)
+	}
+
+	for algorithm := range nativeAlgorithms {
+		settings.Algorithms = append(settings.Algorithms, algorithm)
+	}
+
+	sort.Strings(settings.Algorithms)
+
+	data, err := json.Marshal(settings)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	log.Printf("effective settings: %s", data)
+}
+
+// redactDSN hides password in backend connection string.
+func redactDSN(dsn string) string {
+	parsed, err := url.Parse(dsn)
+	if err != nil {
+		return "<unparseable>"
+	}
+
+	return parsed.Redacted()
+}
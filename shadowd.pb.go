@@ -0,0 +1,386 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.12
+// 	protoc        (unknown)
+// source: shadowd.proto
+
+package main
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type GetRecordRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Token         string                 `protobuf:"bytes,1,opt,name=token,proto3" json:"token,omitempty"`
+	ClientId      string                 `protobuf:"bytes,2,opt,name=client_id,json=clientId,proto3" json:"client_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetRecordRequest) Reset() {
+	*x = GetRecordRequest{}
+	mi := &file_shadowd_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetRecordRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetRecordRequest) ProtoMessage() {}
+
+func (x *GetRecordRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_shadowd_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetRecordRequest.ProtoReflect.Descriptor instead.
+func (*GetRecordRequest) Descriptor() ([]byte, []int) {
+	return file_shadowd_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *GetRecordRequest) GetToken() string {
+	if x != nil {
+		return x.Token
+	}
+	return ""
+}
+
+func (x *GetRecordRequest) GetClientId() string {
+	if x != nil {
+		return x.ClientId
+	}
+	return ""
+}
+
+type GetRecordResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Record        string                 `protobuf:"bytes,1,opt,name=record,proto3" json:"record,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetRecordResponse) Reset() {
+	*x = GetRecordResponse{}
+	mi := &file_shadowd_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetRecordResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetRecordResponse) ProtoMessage() {}
+
+func (x *GetRecordResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_shadowd_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetRecordResponse.ProtoReflect.Descriptor instead.
+func (*GetRecordResponse) Descriptor() ([]byte, []int) {
+	return file_shadowd_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *GetRecordResponse) GetRecord() string {
+	if x != nil {
+		return x.Record
+	}
+	return ""
+}
+
+type ListTokensRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Prefix        string                 `protobuf:"bytes,1,opt,name=prefix,proto3" json:"prefix,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListTokensRequest) Reset() {
+	*x = ListTokensRequest{}
+	mi := &file_shadowd_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListTokensRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListTokensRequest) ProtoMessage() {}
+
+func (x *ListTokensRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_shadowd_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListTokensRequest.ProtoReflect.Descriptor instead.
+func (*ListTokensRequest) Descriptor() ([]byte, []int) {
+	return file_shadowd_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ListTokensRequest) GetPrefix() string {
+	if x != nil {
+		return x.Prefix
+	}
+	return ""
+}
+
+type ListTokensResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Tokens        []string               `protobuf:"bytes,1,rep,name=tokens,proto3" json:"tokens,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListTokensResponse) Reset() {
+	*x = ListTokensResponse{}
+	mi := &file_shadowd_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListTokensResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListTokensResponse) ProtoMessage() {}
+
+func (x *ListTokensResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_shadowd_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListTokensResponse.ProtoReflect.Descriptor instead.
+func (*ListTokensResponse) Descriptor() ([]byte, []int) {
+	return file_shadowd_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ListTokensResponse) GetTokens() []string {
+	if x != nil {
+		return x.Tokens
+	}
+	return nil
+}
+
+type ValidateRecordRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Token         string                 `protobuf:"bytes,1,opt,name=token,proto3" json:"token,omitempty"`
+	Record        string                 `protobuf:"bytes,2,opt,name=record,proto3" json:"record,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ValidateRecordRequest) Reset() {
+	*x = ValidateRecordRequest{}
+	mi := &file_shadowd_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ValidateRecordRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ValidateRecordRequest) ProtoMessage() {}
+
+func (x *ValidateRecordRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_shadowd_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ValidateRecordRequest.ProtoReflect.Descriptor instead.
+func (*ValidateRecordRequest) Descriptor() ([]byte, []int) {
+	return file_shadowd_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ValidateRecordRequest) GetToken() string {
+	if x != nil {
+		return x.Token
+	}
+	return ""
+}
+
+func (x *ValidateRecordRequest) GetRecord() string {
+	if x != nil {
+		return x.Record
+	}
+	return ""
+}
+
+type ValidateRecordResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Valid         bool                   `protobuf:"varint,1,opt,name=valid,proto3" json:"valid,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ValidateRecordResponse) Reset() {
+	*x = ValidateRecordResponse{}
+	mi := &file_shadowd_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ValidateRecordResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ValidateRecordResponse) ProtoMessage() {}
+
+func (x *ValidateRecordResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_shadowd_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ValidateRecordResponse.ProtoReflect.Descriptor instead.
+func (*ValidateRecordResponse) Descriptor() ([]byte, []int) {
+	return file_shadowd_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ValidateRecordResponse) GetValid() bool {
+	if x != nil {
+		return x.Valid
+	}
+	return false
+}
+
+var File_shadowd_proto protoreflect.FileDescriptor
+
+const file_shadowd_proto_rawDesc = "" +
+	"\n" +
+	"\rshadowd.proto\x12\ashadowd\"E\n" +
+	"\x10GetRecordRequest\x12\x14\n" +
+	"\x05token\x18\x01 \x01(\tR\x05token\x12\x1b\n" +
+	"\tclient_id\x18\x02 \x01(\tR\bclientId\"+\n" +
+	"\x11GetRecordResponse\x12\x16\n" +
+	"\x06record\x18\x01 \x01(\tR\x06record\"+\n" +
+	"\x11ListTokensRequest\x12\x16\n" +
+	"\x06prefix\x18\x01 \x01(\tR\x06prefix\",\n" +
+	"\x12ListTokensResponse\x12\x16\n" +
+	"\x06tokens\x18\x01 \x03(\tR\x06tokens\"E\n" +
+	"\x15ValidateRecordRequest\x12\x14\n" +
+	"\x05token\x18\x01 \x01(\tR\x05token\x12\x16\n" +
+	"\x06record\x18\x02 \x01(\tR\x06record\".\n" +
+	"\x16ValidateRecordResponse\x12\x14\n" +
+	"\x05valid\x18\x01 \x01(\bR\x05valid2\xe7\x01\n" +
+	"\aShadowd\x12B\n" +
+	"\tGetRecord\x12\x19.shadowd.GetRecordRequest\x1a\x1a.shadowd.GetRecordResponse\x12E\n" +
+	"\n" +
+	"ListTokens\x12\x1a.shadowd.ListTokensRequest\x1a\x1b.shadowd.ListTokensResponse\x12Q\n" +
+	"\x0eValidateRecord\x12\x1e.shadowd.ValidateRecordRequest\x1a\x1f.shadowd.ValidateRecordResponseB\tZ\a./;mainb\x06proto3"
+
+var (
+	file_shadowd_proto_rawDescOnce sync.Once
+	file_shadowd_proto_rawDescData []byte
+)
+
+func file_shadowd_proto_rawDescGZIP() []byte {
+	file_shadowd_proto_rawDescOnce.Do(func() {
+		file_shadowd_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_shadowd_proto_rawDesc), len(file_shadowd_proto_rawDesc)))
+	})
+	return file_shadowd_proto_rawDescData
+}
+
+var file_shadowd_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
+var file_shadowd_proto_goTypes = []any{
+	(*GetRecordRequest)(nil),       // 0: shadowd.GetRecordRequest
+	(*GetRecordResponse)(nil),      // 1: shadowd.GetRecordResponse
+	(*ListTokensRequest)(nil),      // 2: shadowd.ListTokensRequest
+	(*ListTokensResponse)(nil),     // 3: shadowd.ListTokensResponse
+	(*ValidateRecordRequest)(nil),  // 4: shadowd.ValidateRecordRequest
+	(*ValidateRecordResponse)(nil), // 5: shadowd.ValidateRecordResponse
+}
+var file_shadowd_proto_depIdxs = []int32{
+	0, // 0: shadowd.Shadowd.GetRecord:input_type -> shadowd.GetRecordRequest
+	2, // 1: shadowd.Shadowd.ListTokens:input_type -> shadowd.ListTokensRequest
+	4, // 2: shadowd.Shadowd.ValidateRecord:input_type -> shadowd.ValidateRecordRequest
+	1, // 3: shadowd.Shadowd.GetRecord:output_type -> shadowd.GetRecordResponse
+	3, // 4: shadowd.Shadowd.ListTokens:output_type -> shadowd.ListTokensResponse
+	5, // 5: shadowd.Shadowd.ValidateRecord:output_type -> shadowd.ValidateRecordResponse
+	3, // [3:6] is the sub-list for method output_type
+	0, // [0:3] is the sub-list for method input_type
+	0, // [0:0] is the sub-list for extension type_name
+	0, // [0:0] is the sub-list for extension extendee
+	0, // [0:0] is the sub-list for field type_name
+}
+
+func init() { file_shadowd_proto_init() }
+func file_shadowd_proto_init() {
+	if File_shadowd_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_shadowd_proto_rawDesc), len(file_shadowd_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   6,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_shadowd_proto_goTypes,
+		DependencyIndexes: file_shadowd_proto_depIdxs,
+		MessageInfos:      file_shadowd_proto_msgTypes,
+	}.Build()
+	File_shadowd_proto = out.File
+	file_shadowd_proto_goTypes = nil
+	file_shadowd_proto_depIdxs = nil
+}
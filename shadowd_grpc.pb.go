@@ -0,0 +1,211 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.6.2
+// - protoc             (unknown)
+// source: shadowd.proto
+
+package main
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Shadowd_GetRecord_FullMethodName      = "/shadowd.Shadowd/GetRecord"
+	Shadowd_ListTokens_FullMethodName     = "/shadowd.Shadowd/ListTokens"
+	Shadowd_ValidateRecord_FullMethodName = "/shadowd.Shadowd/ValidateRecord"
+)
+
+// ShadowdClient is the client API for Shadowd service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Shadowd serves hash table entries over gRPC, requests are handled by the
+// same code as HTTP requests to /t/ and /v/ paths.
+type ShadowdClient interface {
+	// GetRecord returns hash table entry of token for requesting client,
+	// client_id replaces client address and is accepted only from admins.
+	GetRecord(ctx context.Context, in *GetRecordRequest, opts ...grpc.CallOption) (*GetRecordResponse, error)
+	// ListTokens returns tokens under specified prefix.
+	ListTokens(ctx context.Context, in *ListTokensRequest, opts ...grpc.CallOption) (*ListTokensResponse, error)
+	// ValidateRecord reports whether record exists in hash table of token.
+	ValidateRecord(ctx context.Context, in *ValidateRecordRequest, opts ...grpc.CallOption) (*ValidateRecordResponse, error)
+}
+
+type shadowdClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewShadowdClient(cc grpc.ClientConnInterface) ShadowdClient {
+	return &shadowdClient{cc}
+}
+
+func (c *shadowdClient) GetRecord(ctx context.Context, in *GetRecordRequest, opts ...grpc.CallOption) (*GetRecordResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetRecordResponse)
+	err := c.cc.Invoke(ctx, Shadowd_GetRecord_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shadowdClient) ListTokens(ctx context.Context, in *ListTokensRequest, opts ...grpc.CallOption) (*ListTokensResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListTokensResponse)
+	err := c.cc.Invoke(ctx, Shadowd_ListTokens_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shadowdClient) ValidateRecord(ctx context.Context, in *ValidateRecordRequest, opts ...grpc.CallOption) (*ValidateRecordResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ValidateRecordResponse)
+	err := c.cc.Invoke(ctx, Shadowd_ValidateRecord_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ShadowdServer is the server API for Shadowd service.
+// All implementations must embed UnimplementedShadowdServer
+// for forward compatibility.
+//
+// Shadowd serves hash table entries over gRPC, requests are handled by the
+// same code as HTTP requests to /t/ and /v/ paths.
+type ShadowdServer interface {
+	// GetRecord returns hash table entry of token for requesting client,
+	// client_id replaces client address and is accepted only from admins.
+	GetRecord(context.Context, *GetRecordRequest) (*GetRecordResponse, error)
+	// ListTokens returns tokens under specified prefix.
+	ListTokens(context.Context, *ListTokensRequest) (*ListTokensResponse, error)
+	// ValidateRecord reports whether record exists in hash table of token.
+	ValidateRecord(context.Context, *ValidateRecordRequest) (*ValidateRecordResponse, error)
+	mustEmbedUnimplementedShadowdServer()
+}
+
+// UnimplementedShadowdServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedShadowdServer struct{}
+
+func (UnimplementedShadowdServer) GetRecord(context.Context, *GetRecordRequest) (*GetRecordResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetRecord not implemented")
+}
+func (UnimplementedShadowdServer) ListTokens(context.Context, *ListTokensRequest) (*ListTokensResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ListTokens not implemented")
+}
+func (UnimplementedShadowdServer) ValidateRecord(context.Context, *ValidateRecordRequest) (*ValidateRecordResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ValidateRecord not implemented")
+}
+func (UnimplementedShadowdServer) mustEmbedUnimplementedShadowdServer() {}
+func (UnimplementedShadowdServer) testEmbeddedByValue()                 {}
+
+// UnsafeShadowdServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ShadowdServer will
+// result in compilation errors.
+type UnsafeShadowdServer interface {
+	mustEmbedUnimplementedShadowdServer()
+}
+
+func RegisterShadowdServer(s grpc.ServiceRegistrar, srv ShadowdServer) {
+	// If the following call panics, it indicates UnimplementedShadowdServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Shadowd_ServiceDesc, srv)
+}
+
+func _Shadowd_GetRecord_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRecordRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShadowdServer).GetRecord(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Shadowd_GetRecord_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShadowdServer).GetRecord(ctx, req.(*GetRecordRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Shadowd_ListTokens_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListTokensRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShadowdServer).ListTokens(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Shadowd_ListTokens_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShadowdServer).ListTokens(ctx, req.(*ListTokensRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Shadowd_ValidateRecord_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ValidateRecordRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShadowdServer).ValidateRecord(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Shadowd_ValidateRecord_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShadowdServer).ValidateRecord(ctx, req.(*ValidateRecordRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Shadowd_ServiceDesc is the grpc.ServiceDesc for Shadowd service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Shadowd_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "shadowd.Shadowd",
+	HandlerType: (*ShadowdServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetRecord",
+			Handler:    _Shadowd_GetRecord_Handler,
+		},
+		{
+			MethodName: "ListTokens",
+			Handler:    _Shadowd_ListTokens_Handler,
+		},
+		{
+			MethodName: "ValidateRecord",
+			Handler:    _Shadowd_ValidateRecord_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "shadowd.proto",
+}
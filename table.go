@@ -3,8 +3,9 @@ package main
 import (
 	"bufio"
 	"errors"
-	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/reconquest/hierr-go"
 )
@@ -67,12 +68,35 @@ func (table *hashTable) getRecord(number int64) ([]byte, error) {
 	return record, nil
 }
 
+func (table *hashTable) getRecords() ([]string, error) {
+	defer table.file.Seek(0, 0)
+
+	records := []string{}
+
+	scanner := bufio.NewScanner(table.file)
+	for scanner.Scan() {
+		record, _, err := parseTableLine(scanner.Text())
+		if err != nil {
+			return nil, err
+		}
+
+		records = append(records, record)
+	}
+
+	return records, scanner.Err()
+}
+
 func (table *hashTable) hashExists(hash string) (bool, error) {
 	defer table.file.Seek(0, 0)
 
 	scanner := bufio.NewScanner(table.file)
 	for scanner.Scan() {
-		if scanner.Text() == hash {
+		record, _, err := parseTableLine(scanner.Text())
+		if err != nil {
+			return false, err
+		}
+
+		if record == hash {
 			return true, nil
 		}
 	}
@@ -80,13 +104,31 @@ func (table *hashTable) hashExists(hash string) (bool, error) {
 	return false, scanner.Err()
 }
 
+// parseTableLine splits line of hash table file into record and its expiry,
+// which follows record after space in hash tables with expiring entries.
+func parseTableLine(line string) (string, time.Time, error) {
+	space := strings.IndexByte(line, ' ')
+	if space == -1 {
+		return line, time.Time{}, nil
+	}
+
+	expiry, err := time.Parse(time.RFC3339, line[space+1:])
+	if err != nil {
+		return "", time.Time{}, hierr.Errorf(
+			err, "can't parse expiry of hash table record",
+		)
+	}
+
+	return line[:space], expiry, nil
+}
+
 func (table *hashTable) getRecordSize() (int, error) {
 	if table.recordSize != 0 {
 		return table.recordSize, nil
 	}
 
-	var line string
-	_, err := fmt.Fscanln(table.file, &line)
+	// line can contain expiry after space, so it's read as whole
+	line, err := bufio.NewReader(table.file).ReadString('\n')
 	if err != nil {
 		return 0, err
 	}
@@ -96,7 +138,7 @@ func (table *hashTable) getRecordSize() (int, error) {
 		return 0, err
 	}
 
-	table.recordSize = len(line)
+	table.recordSize = len(line) - 1
 
 	return table.recordSize, nil
 }
@@ -120,7 +162,8 @@ func (table *hashTable) getSize() (int64, error) {
 		)
 	}
 
-	table.size = stat.Size() / int64(recordSize)
+	// +1 for new line
+	table.size = stat.Size() / int64(recordSize+1)
 
 	return table.size, nil
 }
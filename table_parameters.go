@@ -0,0 +1,90 @@
+package main
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/reconquest/hierr-go"
+)
+
+// tableParameters are parameters of algorithm, which hash table has been
+// generated with, so hash table can be regenerated exactly the same way.
+type tableParameters struct {
+	// CryptID is crypt(3) algorithm id, it includes algorithm parameters
+	// other than rounds, e.g. "6" or "y$j9T".
+	CryptID string `json:"crypt_id" bson:"crypt_id"`
+
+	// Rounds is amount of rounds, empty means default of algorithm.
+	Rounds string `json:"rounds,omitempty" bson:"rounds,omitempty"`
+
+	SaltLength int `json:"salt_length" bson:"salt_length"`
+}
+
+// newTableParameters splits rounds from specified crypt(3) algorithm id.
+func newTableParameters(id string, saltLength int) *tableParameters {
+	parameters := &tableParameters{
+		CryptID:    id,
+		SaltLength: saltLength,
+	}
+
+	if index := strings.Index(id, "$rounds="); index >= 0 {
+		parameters.CryptID = id[:index]
+		parameters.Rounds = id[index+len("$rounds="):]
+	}
+
+	return parameters
+}
+
+// getID returns crypt(3) algorithm id with rounds.
+func (parameters *tableParameters) getID() string {
+	if parameters.Rounds == "" {
+		return parameters.CryptID
+	}
+
+	return parameters.CryptID + "$rounds=" + parameters.Rounds
+}
+
+// getTableParameters returns stored parameters of hash table, parameters of
+// hash tables, which have been generated before parameters were stored or
+// have been imported, are taken from first record.
+func getTableParameters(
+	backend Backend, token string,
+) (*tableParameters, error) {
+	info, err := backend.GetTokenInfo(token)
+	if err != nil {
+		return nil, hierr.Errorf(err, "can't get metadata for %s", token)
+	}
+
+	if info.Parameters != nil {
+		return info.Parameters, nil
+	}
+
+	record, err := backend.GetHash(token, 0)
+	if err != nil {
+		return nil, hierr.Errorf(err, "can't get hash table record")
+	}
+
+	if native := getRecordNativeAlgorithm(record); native != nil {
+		return &tableParameters{
+			CryptID:    native.cryptID,
+			SaltLength: saltLength,
+		}, nil
+	}
+
+	parsed, err := parseRecord(record)
+	if err != nil {
+		return nil, hierr.Errorf(err, "can't parse hash table record")
+	}
+
+	parameters := &tableParameters{
+		CryptID:    parsed.id,
+		Rounds:     parsed.rounds,
+		SaltLength: len(parsed.salt),
+	}
+
+	if !cryptIDPattern.MatchString(parameters.getID()) {
+		return nil, errors.New("can't determine algorithm of hash table")
+	}
+
+	return parameters, nil
+}
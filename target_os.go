@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// targetOSCryptIDs lists crypt(3) algorithm ids, which are known to be
+// supported by libc of default installation of target operating system.
+var targetOSCryptIDs = map[string][]string{
+	"alpine":      {"1", "2b", "5", "6"},
+	"debian10":    {"1", "5", "6"},
+	"debian11":    {"1", "2b", "5", "6", "y"},
+	"debian12":    {"1", "2b", "5", "6", "y"},
+	"freebsd":     {"1", "2b", "5", "6"},
+	"openbsd":     {"2b"},
+	"rhel7":       {"1", "5", "6"},
+	"rhel8":       {"1", "2b", "5", "6"},
+	"rhel9":       {"1", "2b", "5", "6", "y"},
+	"ubuntu20.04": {"1", "5", "6"},
+	"ubuntu22.04": {"1", "2b", "5", "6", "y"},
+	"ubuntu24.04": {"1", "2b", "5", "6", "y"},
+}
+
+// checkTargetOS warns if records with specified crypt id can't be verified
+// on specified target operating system, so users would be locked out.
+func checkTargetOS(target string, cryptID string) error {
+	supported, ok := targetOSCryptIDs[target]
+	if !ok {
+		return usageError{
+			fmt.Errorf(
+				"unknown target OS '%s', known are: %s",
+				target, strings.Join(getTargetOSNames(), ", "),
+			),
+		}
+	}
+
+	id := strings.SplitN(cryptID, "$", 2)[0]
+	for _, known := range supported {
+		if id == known {
+			return nil
+		}
+	}
+
+	fmt.Fprintf(
+		os.Stderr,
+		"Warning: crypt id '%s' is not known to be supported by %s, "+
+			"supported are: %s\n",
+		id, target, strings.Join(supported, ", "),
+	)
+
+	return nil
+}
+
+func getTargetOSNames() []string {
+	names := []string{}
+	for name := range targetOSCryptIDs {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
@@ -22,6 +22,7 @@ _shadowd_args=""
         --tables $(tests:get-tmp-dir)/tables/ \
         --keys $(tests:get-tmp-dir)/ssh/ \
         --certs $(tests:get-tmp-dir)/certs/ \
+        --meta $(tests:get-tmp-dir)/meta/ \
         ${_shadowd_args[@]} "$@"
 
     cat $(tests:get-stdout-file)
@@ -35,11 +36,56 @@ _shadowd_args=""
 
     tests:ensure :shadowd -C --bytes 1024
 
+    :shadowd-background -L "$@"
+}
+
+:shadowd-serve-generate() {
+    :shadowd-prepare
+
+    tests:ensure :shadowd -C --bytes 1024
+
+    :shadowd-background --serve-generate "$@"
+}
+
+:shadowd-background() {
     tests:run-background _shadowd shadowd.test \
         --tables $(tests:get-tmp-dir)/tables/ \
         --keys $(tests:get-tmp-dir)/ssh/ \
         --certs $(tests:get-tmp-dir)/certs/ \
-        ${_shadowd_args[@]} -L "$@"
+        --meta $(tests:get-tmp-dir)/meta/ \
+        ${_shadowd_args[@]} "$@"
+}
+
+:client-certificate() {
+    tests:ensure openssl req -x509 -newkey rsa:1024 -nodes -days 1 \
+        -subj /CN=ca -keyout ca.key -out ca.pem
+
+    tests:ensure openssl req -newkey rsa:1024 -nodes \
+        -subj /CN=${1:-client} -keyout client.key -out client.csr
+
+    tests:ensure openssl x509 -req -days 1 -in client.csr \
+        -CA ca.pem -CAkey ca.key -CAcreateserial -out client.pem
+}
+
+:intermediate-certificate() {
+    tests:put ca.ext <<< 'basicConstraints=CA:true'
+
+    tests:ensure openssl req -x509 -newkey rsa:1024 -nodes -days 1 \
+        -subj /CN=root -keyout root.key -out root.pem
+
+    tests:ensure openssl req -newkey rsa:1024 -nodes \
+        -subj /CN=intermediate -keyout intermediate.key -out intermediate.csr
+
+    tests:ensure openssl x509 -req -days 1 -in intermediate.csr \
+        -CA root.pem -CAkey root.key -CAcreateserial -extfile ca.ext \
+        -out intermediate.pem
+
+    tests:ensure openssl req -newkey rsa:1024 -nodes \
+        -subj /CN=127.0.0.1 -keyout leaf.key -out leaf.csr
+
+    tests:ensure openssl x509 -req -days 1 -in leaf.csr \
+        -CA intermediate.pem -CAkey intermediate.key -CAcreateserial \
+        -out leaf.pem
 }
 
 :mongod() {
@@ -0,0 +1,8 @@
+:shadowd-listen "127.0.0.1:60002" --no-session-resumption
+
+tests:ensure openssl s_client -tls1_2 -connect 127.0.0.1:60002 \
+    -sess_out session '</dev/null'
+
+tests:ensure openssl s_client -tls1_2 -connect 127.0.0.1:60002 \
+    -sess_in session '</dev/null'
+tests:not tests:assert-stdout 'Reused, TLS'
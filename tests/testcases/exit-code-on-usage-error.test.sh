@@ -0,0 +1,11 @@
+tests:eval :shadowd --unknown-flag
+tests:assert-exitcode 2
+
+tests:eval :shadowd --ttl blah -G pool/token '<<<' "password"
+tests:assert-exitcode 2
+
+tests:eval :shadowd --no-confirm --length blah -G pool/token '<<<' "password"
+tests:assert-exitcode 2
+
+tests:eval :shadowd --no-confirm -a blah -G pool/token '<<<' "password"
+tests:assert-exitcode 2
@@ -0,0 +1,14 @@
+:shadowd-listen "127.0.0.1:60002"
+
+tests:ensure :shadowd -G --no-confirm --length 100 \
+    --sunset 2100-01-01T00:00:00Z a/b/c/d '<<<' 'password'
+
+tests:ensure curl -sk -D - "https://127.0.0.1:60002/t/a/b/c/d"
+tests:assert-stdout-re '^HTTP/[0-9.]+ 200'
+tests:assert-stdout 'Sunset: Fri, 01 Jan 2100 00:00:00 GMT'
+tests:assert-stdout-re '^.{63}$'
+
+tests:ensure :shadowd -G --no-confirm --length 100 a/b/c/d '<<<' 'password'
+
+tests:ensure curl -sk -D - "https://127.0.0.1:60002/t/a/b/c/d"
+tests:not tests:assert-stdout 'Sunset:'
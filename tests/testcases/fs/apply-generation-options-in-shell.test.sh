@@ -0,0 +1,24 @@
+:shadowd-prepare
+
+tests:put policy.toml <<POLICY
+[[algorithms]]
+pattern = "^secure-"
+allow = ["sha512"]
+POLICY
+
+tests:ensure :shadowd -G --no-confirm --length 10 pool/a '<<<' 'password'
+
+tests:put commands <<COMMANDS
+generate secure-token 10
+generate secure-token 10 sha512
+password
+generate pool/a 10
+COMMANDS
+
+tests:ensure :shadowd --policy policy.toml --no-clobber shell '<' commands
+tests:assert-stdout \
+    'Error: algorithm sha256 is not allowed for token secure-token'
+tests:assert-stdout 'Hash table secure-token with 10 items successfully created.'
+tests:assert-stdout \
+    'Error: hash table pool/a already exists, use --force to overwrite it'
+tests:not tests:assert-stdout 'unknown command'
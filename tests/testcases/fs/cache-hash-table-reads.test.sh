@@ -0,0 +1,36 @@
+:shadowd-listen "127.0.0.1:60002" --cache-size 1000 --cache-ttl 1h \
+    --next-depth 0
+
+tests:ensure :shadowd -G --no-confirm --length 100 a/b/c/d '<<<' 'old'
+
+tests:ensure curl -sk "https://127.0.0.1:60002/t/a/b/c/d"
+tests:ensure curl -sk "https://127.0.0.1:60002/t/a/b/c/d"
+
+# table size and record are read from backend once
+tests:ensure curl -sk "https://127.0.0.1:60002/metrics"
+tests:assert-stdout-re '^shadowd_cache_hits_total 2$'
+tests:assert-stdout-re '^shadowd_cache_misses_total 2$'
+
+# password change replaces hash table through server, so cache is
+# invalidated
+tests:ensure curl -sk -X PUT "https://127.0.0.1:60002/t/a/b/c/d"
+
+salts=($(cat $(tests:get-stdout-file)))
+
+payload="password=new"
+for salt in "${salts[@]}"; do
+    tests:ensure python -c "import crypt; print(crypt.crypt('old', '\$salt'))"
+    payload="$payload&shadow[]=$(cat $(tests:get-stdout-file))"
+done
+
+tests:ensure curl -sk -w '%{http_code}' -o /dev/null -X PUT -d "\$payload" \
+    "https://127.0.0.1:60002/t/a/b/c/d"
+tests:assert-no-diff stdout <<< '200'
+
+tests:ensure curl -sk "https://127.0.0.1:60002/t/a/b/c/d"
+
+new=$(cat $(tests:get-stdout-file))
+salt=$(head -c 19 <<< "$new")
+
+tests:ensure python -c "import crypt; print(crypt.crypt('new', '\$salt'))"
+tests:assert-no-diff stdout <<< "$new"
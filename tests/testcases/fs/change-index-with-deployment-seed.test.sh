@@ -0,0 +1,42 @@
+:shadowd-prepare
+
+tests:ensure :shadowd -G --no-confirm --length 1000 a '<<<' 'password'
+
+tests:put clients <<CLIENTS
+10.0.0.1
+10.0.0.2
+10.0.0.3
+10.0.0.4
+10.0.0.5
+10.0.0.6
+10.0.0.7
+10.0.0.8
+CLIENTS
+
+tests:put seed-a <<< 'first deployment'
+tests:put seed-b <<< 'second deployment'
+
+tests:ensure :shadowd simulate --token a --clients clients -s 87600h
+unseeded=$(cat $(tests:get-stdout-file))
+
+tests:ensure :shadowd --index-seed-file seed-a \
+    simulate --token a --clients clients -s 87600h
+seeded_a=$(cat $(tests:get-stdout-file))
+
+tests:ensure :shadowd --index-seed-file seed-a \
+    simulate --token a --clients clients -s 87600h
+tests:assert-no-diff stdout <<< "$seeded_a"
+
+tests:ensure :shadowd --index-seed-file seed-b \
+    simulate --token a --clients clients -s 87600h
+seeded_b=$(cat $(tests:get-stdout-file))
+
+tests:assert-test "$unseeded" != "$seeded_a"
+tests:assert-test "$seeded_a" != "$seeded_b"
+
+tests:put empty-seed <<< ''
+
+tests:not tests:ensure :shadowd --index-seed-file empty-seed \
+    simulate --token a --clients clients
+tests:assert-exitcode 2
+tests:assert-stderr 'is empty'
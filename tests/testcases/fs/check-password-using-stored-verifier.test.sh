@@ -0,0 +1,15 @@
+tests:ensure :shadowd --no-confirm --length 100 --store-verifier \
+    -G pool/token '<<<' "password"
+
+tests:ensure :shadowd table check pool/token '<<<' "password"
+tests:assert-stdout 'Password matches hash table pool/token'
+
+tests:eval :shadowd table check pool/token '<<<' "wrong"
+tests:assert-exitcode 1
+tests:assert-stderr 'password does not match hash table'
+
+tests:ensure :shadowd --no-confirm --length 100 \
+    -G pool/token '<<<' "password"
+
+tests:eval :shadowd table check pool/token '<<<' "password"
+tests:assert-exitcode 3
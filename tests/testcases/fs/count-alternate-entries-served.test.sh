@@ -0,0 +1,18 @@
+:shadowd-listen "127.0.0.1:60002" --log-next
+
+tests:ensure :shadowd -G --no-confirm --length 100 a/b/c/d '<<<' 'password'
+
+tests:ensure curl -sk "https://127.0.0.1:60002/t/a/b/c/d"
+
+tests:ensure curl -sk "https://127.0.0.1:60002/metrics"
+tests:not tests:assert-stdout 'shadowd_next_entries_served_total'
+
+tests:ensure curl -sk "https://127.0.0.1:60002/t/a/b/c/d"
+tests:ensure curl -sk "https://127.0.0.1:60002/t/a/b/c/d"
+
+tests:ensure curl -sk "https://127.0.0.1:60002/metrics"
+tests:assert-stdout-re '^shadowd_next_entries_served_total 2$'
+
+tests:ensure cat $(tests:get-background-stderr $_shadowd)
+tests:assert-stdout \
+    'client 127.0.0.1 has requested a/b/c/d again, serving alternate entry'
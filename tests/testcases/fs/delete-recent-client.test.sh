@@ -0,0 +1,28 @@
+:client-certificate
+
+:shadowd-listen "127.0.0.1:60002" --client-ca $(tests:get-tmp-dir)/ca.pem
+
+tests:ensure :shadowd -G --no-confirm --length 100 a/b/c/d '<<<' 'password'
+
+tests:ensure curl -sk "https://127.0.0.1:60002/t/a/b/c/d"
+tests:value secret cat $(tests:get-stdout-file)
+
+tests:ensure curl -sk "https://127.0.0.1:60002/t/a/b/c/d"
+tests:not tests:assert-stdout "$secret"
+
+tests:ensure curl -sk -w '%{http_code}' -X DELETE \
+    "https://127.0.0.1:60002/admin/recent?remote=127.0.0.1"
+tests:assert-no-diff stdout <<< '403'
+
+tests:ensure curl -sk -w '%{http_code}' -X DELETE \
+    --cert client.pem --key client.key \
+    "https://127.0.0.1:60002/admin/recent?remote=127.0.0.1"
+tests:assert-no-diff stdout <<< '204'
+
+tests:ensure curl -sk "https://127.0.0.1:60002/t/a/b/c/d"
+tests:assert-no-diff stdout <<< "$secret"
+
+tests:ensure curl -sk -w '%{http_code}' -X DELETE \
+    --cert client.pem --key client.key \
+    "https://127.0.0.1:60002/admin/recent?remote=127.0.0.2"
+tests:assert-no-diff stdout <<< '404'
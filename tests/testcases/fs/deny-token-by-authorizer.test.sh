@@ -0,0 +1,41 @@
+tests:put authorizer.py <<PY
+import json
+
+try:
+    from http.server import BaseHTTPRequestHandler, HTTPServer
+except ImportError:
+    from BaseHTTPServer import BaseHTTPRequestHandler, HTTPServer
+
+
+class Handler(BaseHTTPRequestHandler):
+    def do_POST(self):
+        length = int(self.headers['Content-Length'])
+        subject = json.loads(self.rfile.read(length))
+
+        if subject['token'] == 'pool/denied':
+            self.send_response(403)
+        else:
+            self.send_response(204)
+
+        self.end_headers()
+
+
+HTTPServer(('127.0.0.1', 60005), Handler).serve_forever()
+PY
+
+tests:run-background authorizer python authorizer.py
+tests:ensure sleep 1
+
+:shadowd-listen "127.0.0.1:60002" --authorize-url http://127.0.0.1:60005/
+
+tests:ensure :shadowd -G --no-confirm --length 10 pool/allowed '<<<' 'password'
+tests:ensure :shadowd -G --no-confirm --length 10 pool/denied '<<<' 'password'
+
+tests:ensure curl -sk -w '%{http_code}' "https://127.0.0.1:60002/t/pool/allowed"
+tests:assert-stdout-re '^\$5\$.*200$'
+
+tests:ensure curl -sk -w '%{http_code}' "https://127.0.0.1:60002/t/pool/denied"
+tests:assert-no-diff stdout <<< '403'
+
+tests:ensure cat $(tests:get-background-stderr $_shadowd)
+tests:assert-stdout 'serving pool/denied to 127.0.0.1 is denied by authorizer'
@@ -0,0 +1,44 @@
+:shadowd-listen "127.0.0.1:60002" --proxy-protocol --trace-index
+
+tests:ensure :shadowd -G --no-confirm --length 10 pool/token '<<<' 'password'
+
+tests:put request.py <<PYTHON
+import socket, ssl, struct
+
+header = b'\r\n\r\n\x00\r\nQUIT\n' + bytes([0x21, 0x11]) + \
+    struct.pack('!H', 12) + socket.inet_aton('203.0.113.7') + \
+    socket.inet_aton('127.0.0.1') + struct.pack('!HH', 56324, 60002)
+
+context = ssl.create_default_context()
+context.check_hostname = False
+context.verify_mode = ssl.CERT_NONE
+
+connection = socket.create_connection(('127.0.0.1', 60002))
+connection.sendall(header)
+
+connection = context.wrap_socket(connection)
+connection.sendall(
+    b'GET /t/pool/token HTTP/1.1\r\nHost: 127.0.0.1\r\n'
+    b'Connection: close\r\n\r\n'
+)
+
+response = b''
+while True:
+    data = connection.recv(4096)
+    if not data:
+        break
+    response += data
+
+print(response.decode().split('\r\n')[0])
+PYTHON
+
+tests:ensure python request.py
+tests:assert-stdout 'HTTP/1.1 200 OK'
+
+tests:ensure cat $(tests:get-background-stderr $_shadowd)
+tests:assert-stdout 'trace index: remote=203.0.113.7-pool/token'
+
+tests:not tests:ensure curl -sk "https://127.0.0.1:60002/t/pool/token"
+
+tests:ensure cat $(tests:get-background-stderr $_shadowd)
+tests:assert-stdout "can't read PROXY protocol header from 127.0.0.1"
@@ -0,0 +1,6 @@
+:shadowd-listen "127.0.0.1:60002" --keep-alive off
+
+tests:ensure :shadowd -G --no-confirm --length 10 pool/token '<<<' 'password'
+
+tests:ensure curl -skv --http1.1 "https://127.0.0.1:60002/t/pool/token"
+tests:assert-stderr-re '< Connection: close'
@@ -0,0 +1,7 @@
+tests:ensure :shadowd -G --length 10 pool/token '<<<' 'password'
+tests:assert-stdout 'Hash table pool/token with 10 items successfully created'
+tests:not tests:assert-stdout 'Retype password'
+
+tests:ensure :shadowd table rotate pool/ '<<<' 'password'
+tests:assert-stdout 'pool/token: rotated'
+tests:not tests:assert-stdout 'Retype password'
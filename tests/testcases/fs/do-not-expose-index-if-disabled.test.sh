@@ -0,0 +1,10 @@
+:client-certificate
+
+:shadowd-listen "127.0.0.1:60002" --client-ca $(tests:get-tmp-dir)/ca.pem
+
+tests:ensure :shadowd -G --no-confirm --length 100 a/b/c/d '<<<' 'password'
+
+tests:ensure curl -sk -D - --cert client.pem --key client.key \
+    "https://127.0.0.1:60002/t/a/b/c/d"
+tests:assert-stdout-re '^.{63}$'
+tests:not tests:assert-stdout 'X-Shadowd-Index'
@@ -0,0 +1,9 @@
+:shadowd-listen 127.0.0.1:60002 --serve-tokens 'dmz/*' \
+    --default-token internal/default
+
+tests:ensure :shadowd -G --no-confirm --length 10 internal/default \
+    '<<<' 'password'
+
+tests:ensure curl -sk -o /dev/null -w '%{http_code}' \
+    "https://127.0.0.1:60002/t/dmz/unknown"
+tests:assert-stdout 404
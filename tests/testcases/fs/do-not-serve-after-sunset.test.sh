@@ -0,0 +1,11 @@
+:shadowd-listen "127.0.0.1:60002"
+
+tests:ensure :shadowd -G --no-confirm --length 100 \
+    --sunset 2000-01-01T00:00:00Z a/b/c/d '<<<' 'password'
+
+tests:ensure curl -sk -w '%{http_code}' "https://127.0.0.1:60002/t/a/b/c/d"
+tests:assert-no-diff stdout <<< '410'
+
+tests:not tests:ensure :shadowd -G --no-confirm --length 100 \
+    --sunset tomorrow a/b/c/d '<<<' 'password'
+tests:assert-stderr "can't parse sunset date"
@@ -0,0 +1,6 @@
+:shadowd-listen "127.0.0.1:60002"
+
+tests:ensure :shadowd -G --no-confirm --length 1 pool/bootstrap '<<<' 'password'
+
+tests:ensure curl -sk -w '%{http_code}' "https://127.0.0.1:60002/t/pool/unknown"
+tests:assert-no-diff stdout <<< '404'
@@ -0,0 +1,34 @@
+:shadowd-listen "127.0.0.1:60002"
+
+tests:put records <<RECORDS
+\$6\$abcdefghijklmnop\$J/AWykHqo2Tx5UtavGnFc3ytI33la50JpzLTarSWVhkIXK6wOjNwwZjsrIw2UgmrER2EKrSHCeQyAINEEXAk1/ 2000-01-01T00:00:00Z
+\$6\$ponmlkjihgfedcba\$J/AWykHqo2Tx5UtavGnFc3ytI33la50JpzLTarSWVhkIXK6wOjNwwZjsrIw2UgmrER2EKrSHCeQyAINEEXAk1/ 2100-01-01T00:00:00Z
+RECORDS
+
+tests:ensure :shadowd table import pool/token '<' records
+tests:assert-stdout 'Hash table pool/token with 2 items successfully imported'
+
+tests:put expired <<RECORDS
+\$6\$abcdefghijklmnop\$J/AWykHqo2Tx5UtavGnFc3ytI33la50JpzLTarSWVhkIXK6wOjNwwZjsrIw2UgmrER2EKrSHCeQyAINEEXAk1/ 2000-01-01T00:00:00Z
+RECORDS
+
+tests:ensure :shadowd table import pool/expired '<' expired
+
+for i in 1 2 3 4; do
+    tests:ensure curl -sk "https://127.0.0.1:60002/t/pool/token"
+    tests:assert-stdout 'ponmlkjihgfedcba'
+done
+
+tests:ensure curl -sk -w '%{http_code}' "https://127.0.0.1:60002/t/pool/expired"
+tests:assert-no-diff stdout <<< '410'
+
+tests:put malformed <<RECORDS
+\$6\$abcdefghijklmnop\$J/AWykHqo2Tx5UtavGnFc3ytI33la50JpzLTarSWVhkIXK6wOjNwwZjsrIw2UgmrER2EKrSHCeQyAINEEXAk1/ tomorrow
+RECORDS
+
+tests:not tests:ensure :shadowd table import pool/malformed --lenient \
+    '<' malformed
+tests:assert-stderr "line 1: can't parse expiry"
+tests:assert-stderr '1 malformed lines found'
+tests:not tests:assert-stderr 'use --lenient'
+tests:assert-exitcode 2
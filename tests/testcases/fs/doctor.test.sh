@@ -0,0 +1,15 @@
+tests:ensure :shadowd -C --bytes 1024
+
+tests:ensure :shadowd doctor
+tests:assert-stdout '[ OK ] backend can be initialized'
+tests:assert-stdout '[ OK ] certificate and key are valid'
+tests:assert-stdout '[ OK ] hash tables directory is writable'
+
+tests:ensure chmod 0644 $(tests:get-tmp-dir)/certs/key.pem
+
+tests:eval :shadowd doctor --client-ca $(tests:get-tmp-dir)/missing.pem
+tests:assert-exitcode 1
+tests:assert-stdout '[ OK ] backend can be initialized'
+tests:assert-stdout '[FAIL] certificate and key are valid'
+tests:assert-stdout '[FAIL] client CA can be loaded'
+tests:assert-stderr '2 of 4 checks failed'
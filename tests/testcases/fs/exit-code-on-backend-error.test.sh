@@ -0,0 +1,4 @@
+tests:eval shadowd.test --tables $(tests:get-tmp-dir)/missing/ \
+    --no-confirm -G pool/token '<<<' "password"
+tests:assert-exitcode 4
+tests:assert-stderr "can't initialize shadowd backend"
@@ -0,0 +1,41 @@
+:client-certificate
+
+:shadowd-listen "127.0.0.1:60002" --client-ca $(tests:get-tmp-dir)/ca.pem
+
+tests:ensure :shadowd -G --no-confirm --length 10 pool/token '<<<' 'password'
+
+tests:ensure curl -sk --cert client.pem --key client.key \
+    -o bundle.json.gz "https://127.0.0.1:60002/admin/export/pool/token"
+
+tests:ensure gzip -dc bundle.json.gz
+tests:assert-stdout '"token":"pool/token"'
+
+tests:ensure gzip -dc bundle.json.gz '|' python -c \
+    "import json, sys; print('\n'.join(json.load(sys.stdin)['records']))"
+tests:assert-no-diff stdout < $(tests:get-tmp-dir)/tables/pool/token
+
+tests:ensure curl -sk -w '%{http_code}' \
+    "https://127.0.0.1:60002/admin/export/pool/token"
+tests:assert-no-diff stdout <<< '403'
+
+tests:ensure curl -sk --cert client.pem --key client.key -r 10-19 \
+    -o part -w '%{http_code}' "https://127.0.0.1:60002/admin/export/pool/token"
+tests:assert-no-diff stdout <<< '206'
+
+tests:ensure tail -c +11 bundle.json.gz '|' head -c 10
+tests:assert-no-diff stdout < part
+
+tests:ensure curl -sk --cert client.pem --key client.key -D headers \
+    -o cached.json.gz "https://127.0.0.1:60002/admin/export/pool/token"
+tests:ensure cmp bundle.json.gz cached.json.gz
+tests:ensure grep -i '^etag:' headers '|' tee etag
+
+tests:ensure :shadowd -G --no-confirm --length 10 pool/token '<<<' 'password'
+
+tests:ensure curl -sk --cert client.pem --key client.key -D headers \
+    -o regenerated.json.gz "https://127.0.0.1:60002/admin/export/pool/token"
+tests:not tests:ensure grep -iFf etag headers
+
+tests:ensure gzip -dc regenerated.json.gz '|' python -c \
+    "import json, sys; print('\n'.join(json.load(sys.stdin)['records']))"
+tests:assert-no-diff stdout < $(tests:get-tmp-dir)/tables/pool/token
@@ -0,0 +1,9 @@
+:shadowd-listen "127.0.0.1:60002"
+
+tests:ensure :shadowd -G --no-confirm --length 100 a/b/c/d '<<<' 'password'
+
+tests:ensure curl -sk "https://127.0.0.1:60002/t/a/b/c/d"
+
+tests:ensure curl -sk "https://127.0.0.1:60002/metrics"
+tests:assert-stdout-re '^shadowd_hashes_served_total 1$'
+tests:not tests:assert-stdout 'token='
@@ -0,0 +1,23 @@
+tests:ensure :shadowd -G --no-confirm --length 10 pool/first '<<<' 'password'
+tests:ensure :shadowd -G --no-confirm --length 5 -a sha512 pool/second \
+    '<<<' 'password'
+
+tests:ensure :shadowd table export-csv tokens.csv
+tests:assert-stdout "2 tokens exported to tokens.csv."
+
+tests:ensure head -n1 tokens.csv
+tests:assert-no-diff stdout <<CSV
+token,algorithm,length,created,served,expiry
+CSV
+
+tests:ensure wc -l '<' tokens.csv
+tests:assert-stdout 3
+
+tests:ensure grep -c '^pool/first,sha256,10,[0-9TZ:-]*,0,$' tokens.csv
+tests:assert-stdout 1
+
+tests:ensure grep -c '^pool/second,sha512,5,[0-9TZ:-]*,0,$' tokens.csv
+tests:assert-stdout 1
+
+tests:ensure :shadowd table export-csv -
+tests:assert-stdout "token,algorithm,length,created,served,expiry"
@@ -0,0 +1,20 @@
+tests:ensure :shadowd -G --no-confirm --length 10 --crypt-id zz \
+    --algorithm-fallback sha512 pool/token '<<<' 'password'
+tests:assert-stderr \
+    "Warning: this host cannot verify crypt id 'zz', falling back to sha512"
+tests:assert-stdout 'Hash table pool/token with 10 items successfully created'
+
+tests:ensure head -n 1 $(tests:get-tmp-dir)/tables/pool/token
+tests:assert-stdout-re '^\$6\$[^$]{16}\$[^$]{86}$'
+
+tests:ensure :shadowd -G --no-confirm --length 10 --crypt-id 5 \
+    --algorithm-fallback sha512 pool/token2 '<<<' 'password'
+tests:not tests:assert-stderr 'falling back'
+
+tests:ensure head -n 1 $(tests:get-tmp-dir)/tables/pool/token2
+tests:assert-stdout-re '^\$5\$'
+
+tests:not tests:ensure :shadowd -G --no-confirm --length 10 --crypt-id zz \
+    --algorithm-fallback md5 pool/token3 '<<<' 'password'
+tests:assert-stderr 'fallback algorithm md5 is not available'
+tests:assert-exitcode 2
@@ -0,0 +1,27 @@
+:client-certificate
+
+:shadowd-listen "127.0.0.1:60002" --client-ca $(tests:get-tmp-dir)/ca.pem \
+    --trace-index
+
+tests:ensure :shadowd -G --no-confirm --length 10 pool/token '<<<' 'password'
+
+tests:ensure curl -sk "https://127.0.0.1:60002/t/pool/token"
+tests:ensure curl -sk "https://127.0.0.1:60002/t/pool/token"
+
+tests:ensure :shadowd recent flush 127.0.0.1:60002 \
+    --client-cert client.pem --client-key client.key --token pool/other
+tests:assert-stdout '0 recent clients flushed.'
+
+tests:ensure :shadowd recent flush 127.0.0.1:60002 \
+    --client-cert client.pem --client-key client.key --token pool/token
+tests:assert-stdout '1 recent clients flushed.'
+
+tests:ensure curl -sk "https://127.0.0.1:60002/t/pool/token"
+
+tests:ensure grep -c 'remote=127.0.0.1-pool/token recent=false' \
+    $(tests:get-background-stderr $_shadowd)
+tests:assert-no-diff stdout <<< '2'
+
+tests:ensure curl -sk -w '%{http_code}' -X DELETE \
+    "https://127.0.0.1:60002/admin/recent/"
+tests:assert-no-diff stdout <<< '403'
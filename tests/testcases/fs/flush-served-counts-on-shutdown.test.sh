@@ -0,0 +1,18 @@
+:shadowd-listen "127.0.0.1:60002" --served-flush-interval 1h
+
+tests:ensure :shadowd -G --no-confirm --length 10 pool/token '<<<' 'password'
+
+for i in {1..6}; do
+    tests:ensure curl -sk "https://127.0.0.1:60002/t/pool/token"
+done
+
+tests:ensure :shadowd table low-stock --threshold 0.5
+tests:not tests:assert-stdout 'pool/token'
+
+tests:ensure pkill -TERM -x shadowd.test
+tests:ensure sleep 1
+
+tests:ensure :shadowd table low-stock --threshold 0.5
+tests:assert-no-diff stdout <<TOKENS
+pool/token: 6 of 10 entries served
+TOKENS
@@ -0,0 +1,24 @@
+tests:ensure :shadowd -G --no-confirm --length 100 pool/batch '<<<' 'password'
+
+tests:ensure :shadowd -G --no-confirm --length 100 --stream pool/stream \
+    '<<<' 'password'
+tests:assert-stdout 'Hash table pool/stream with 100 items successfully created'
+
+# records are salted randomly, so tables are compared by format of records
+tests:ensure sed -r 's/[^$]+/x/g' $(tests:get-tmp-dir)/tables/pool/batch
+tests:value batch cat $(tests:get-stdout-file)
+
+tests:ensure sed -r 's/[^$]+/x/g' $(tests:get-tmp-dir)/tables/pool/stream
+tests:assert-no-diff stdout <<< "$batch"
+
+tests:ensure wc -c '<' $(tests:get-tmp-dir)/tables/pool/batch
+tests:value size cat $(tests:get-stdout-file)
+
+tests:ensure wc -c '<' $(tests:get-tmp-dir)/tables/pool/stream
+tests:assert-no-diff stdout <<< "$size"
+
+tests:ensure ls -A $(tests:get-tmp-dir)/tables/pool
+tests:assert-no-diff stdout <<FILES
+batch
+stream
+FILES
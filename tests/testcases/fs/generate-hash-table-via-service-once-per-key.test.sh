@@ -0,0 +1,40 @@
+:client-certificate
+
+:shadowd-serve-generate "127.0.0.1:60003" \
+    --client-ca $(tests:get-tmp-dir)/ca.pem
+
+tests:ensure curl -k --cert client.pem --key client.key \
+    -H "'Idempotency-Key: rotation-1'" \
+    -d token=pool/token -d length=100 -d password=secret \
+    "https://127.0.0.1:60003/admin/generate"
+
+tests:assert-stdout 'Hash table pool/token with 100 items successfully created'
+
+tests:value table cat $(tests:get-tmp-dir)/tables/pool/token
+
+tests:ensure curl -k --cert client.pem --key client.key \
+    -H "'Idempotency-Key: rotation-1'" \
+    -d token=pool/token -d length=100 -d password=secret \
+    "https://127.0.0.1:60003/admin/generate"
+
+tests:assert-stdout 'Hash table pool/token with 100 items successfully created'
+
+tests:assert-no-diff $(tests:get-tmp-dir)/tables/pool/token <<< "$table"
+
+tests:ensure curl -k --cert client.pem --key client.key \
+    -H "'Idempotency-Key: rotation-2'" \
+    -d token=pool/token -d length=100 -d password=secret \
+    "https://127.0.0.1:60003/admin/generate"
+
+tests:not tests:assert-no-diff $(tests:get-tmp-dir)/tables/pool/token <<< "$table"
+
+tests:value table cat $(tests:get-tmp-dir)/tables/pool/token
+
+tests:ensure curl -sk --cert client.pem --key client.key -w '%{http_code}' \
+    -H "'Idempotency-Key: rotation-2'" \
+    -d token=pool/other -d length=100 -d password=secret \
+    "https://127.0.0.1:60003/admin/generate"
+tests:assert-no-diff stdout <<< '422'
+
+tests:assert-test ! -f $(tests:get-tmp-dir)/tables/pool/other
+tests:assert-no-diff $(tests:get-tmp-dir)/tables/pool/token <<< "$table"
@@ -0,0 +1,20 @@
+:client-certificate
+
+:shadowd-serve-generate "127.0.0.1:60003" \
+    --client-ca $(tests:get-tmp-dir)/ca.pem
+
+tests:ensure curl -k --cert client.pem --key client.key \
+    -d token=pool/token -d length=100 -d password=secret \
+    "https://127.0.0.1:60003/admin/generate"
+
+tests:assert-stdout 'Generating hash table... 1%'
+tests:assert-stdout 'Generating hash table... 100%'
+tests:assert-stdout 'Hash table pool/token with 100 items successfully created'
+
+tests:ensure wc -l '<' $(tests:get-tmp-dir)/tables/pool/token
+tests:assert-stdout-re '^100$'
+
+tests:not tests:ensure curl -k -d token=pool/token2 -d password=secret \
+    "https://127.0.0.1:60003/admin/generate"
+
+tests:assert-test ! -f $(tests:get-tmp-dir)/tables/pool/token2
@@ -0,0 +1,12 @@
+tests:ensure :shadowd -G --no-confirm --length 10 --crypt-id 6 pool/token \
+    '<<<' 'password'
+tests:assert-stdout 'Hash table pool/token with 10 items successfully created'
+
+tests:ensure head -n 1 $(tests:get-tmp-dir)/tables/pool/token
+tests:assert-stdout-re '^\$6\$[^$]{16}\$[^$]{86}$'
+
+tests:not tests:ensure :shadowd -G --no-confirm --length 10 --crypt-id zz \
+    pool/token2 '<<<' 'password'
+tests:assert-stderr "this host cannot verify crypt id 'zz'"
+
+tests:assert-test ! -f $(tests:get-tmp-dir)/tables/pool/token2
@@ -0,0 +1,46 @@
+tests:ensure :shadowd -G --no-confirm --length 3 -a bcrypt pool/bcrypt \
+    '<<<' 'password'
+tests:assert-stdout 'Hash table pool/bcrypt with 3 items successfully created'
+
+tests:ensure head -n 1 $(tests:get-tmp-dir)/tables/pool/bcrypt
+tests:assert-stdout-re '^\$2b\$10\$[./A-Za-z0-9]{53}$'
+
+# records are verified by libcrypt of hosts pulling them
+tests:ensure python -c "
+import crypt, sys
+for record in open('$(tests:get-tmp-dir)/tables/pool/bcrypt').read().split():
+    if crypt.crypt('password', record) != record:
+        sys.exit('record %s does not match password' % record)
+"
+
+tests:ensure :shadowd -G --no-confirm --length 3 -a argon2id pool/argon2id \
+    '<<<' 'password'
+tests:assert-stdout \
+    'Hash table pool/argon2id with 3 items successfully created'
+tests:assert-stderr "libcrypt of this host cannot verify crypt id 'argon2id'"
+
+tests:ensure head -n 1 $(tests:get-tmp-dir)/tables/pool/argon2id
+tests:assert-stdout-re '^\$argon2id\$v=19\$m=65536,t=1,p=4\$[^$]+\$[^$]+$'
+
+tests:ensure :shadowd table import pool/imported \
+    '<' $(tests:get-tmp-dir)/tables/pool/argon2id
+tests:assert-stdout 'Hash table pool/imported with 3 items successfully imported'
+
+tests:not tests:ensure :shadowd -G --no-confirm --length 3 -a scrypt \
+    pool/scrypt '<<<' 'password'
+tests:assert-stderr 'algorithm scrypt is not available'
+
+for token in pool/argon2id-a pool/argon2id-b; do
+    tests:ensure :shadowd -G --no-confirm --length 3 -a argon2id \
+        --deterministic-salt 42 --allow-deterministic-salt \
+        $token '<<<' 'password'
+done
+
+tests:ensure cmp $(tests:get-tmp-dir)/tables/pool/argon2id-a \
+    $(tests:get-tmp-dir)/tables/pool/argon2id-b
+
+tests:not tests:ensure :shadowd -G --no-confirm --length 3 -a bcrypt \
+    --deterministic-salt 42 --allow-deterministic-salt \
+    pool/bcrypt-seeded '<<<' 'password'
+tests:assert-stderr "bcrypt salts can't be derived from seed"
+tests:assert-exitcode 2
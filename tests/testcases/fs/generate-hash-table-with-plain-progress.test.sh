@@ -0,0 +1,11 @@
+tests:ensure :shadowd --no-confirm --length 100 --progress plain \
+    -G pool/token '<<<' "password"
+
+tests:assert-stdout 'Generating hash table... 10%'
+tests:assert-stdout 'Generating hash table... 50%'
+tests:assert-stdout 'Generating hash table... 100%'
+tests:not tests:assert-stdout 'Generating hash table... 11%'
+
+tests:ensure :shadowd --no-confirm --length 100 --progress none \
+    -G pool/token '<<<' "password"
+tests:not tests:assert-stdout 'Generating hash table'
@@ -0,0 +1,16 @@
+:shadowd-prepare
+
+tests:put password <<PASSWORD
+password
+password
+PASSWORD
+
+tests:ensure setsid -w shadowd.test \
+    --tables $(tests:get-tmp-dir)/tables/ \
+    --meta $(tests:get-tmp-dir)/meta/ \
+    --length 100 -G pool/token '<' password
+
+tests:assert-stdout 'Hash table pool/token with 100 items successfully created'
+
+tests:ensure wc -l '<' $(tests:get-tmp-dir)/tables/pool/token
+tests:assert-stdout-re '^100$'
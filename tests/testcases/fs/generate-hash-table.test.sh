@@ -8,3 +8,6 @@ tests:assert-test -f $(tests:get-tmp-dir)/tables/pool/token
 
 tests:ensure wc -l '<' $(tests:get-tmp-dir)/tables/pool/token
 tests:assert-stdout-re '^100$'
+
+tests:ensure grep -c '^\$5\$' $(tests:get-tmp-dir)/tables/pool/token
+tests:assert-stdout-re '^100$'
@@ -0,0 +1,21 @@
+for token in pool/a pool/b; do
+    tests:ensure :shadowd -G --no-confirm --length 10 \
+        --deterministic-salt 42 --allow-deterministic-salt \
+        $token '<<<' 'password'
+    tests:assert-stderr 'hash table is not safe for production'
+done
+
+tests:ensure :shadowd -G --no-confirm --length 10 \
+    --deterministic-salt 43 --allow-deterministic-salt pool/c '<<<' 'password'
+
+tests:ensure cmp \
+    $(tests:get-tmp-dir)/tables/pool/a $(tests:get-tmp-dir)/tables/pool/b
+tests:not tests:ensure cmp \
+    $(tests:get-tmp-dir)/tables/pool/a $(tests:get-tmp-dir)/tables/pool/c
+
+tests:not tests:ensure :shadowd -G --no-confirm --length 10 \
+    --deterministic-salt 42 pool/d '<<<' 'password'
+tests:assert-stderr 'use --allow-deterministic-salt for test tables'
+tests:assert-exitcode 2
+
+tests:assert-test ! -f $(tests:get-tmp-dir)/tables/pool/d
@@ -0,0 +1,27 @@
+:client-certificate
+
+:shadowd-listen "127.0.0.1:60002" --client-ca $(tests:get-tmp-dir)/ca.pem
+
+tests:ensure :shadowd -G --no-confirm --length 10 pool/token '<<<' 'password'
+tests:ensure :shadowd -G --no-confirm --length 10 pool/.bootstrap \
+    '<<<' 'password'
+
+tests:ensure curl -sk "https://127.0.0.1:60002/t/pool/"
+tests:assert-no-diff stdout <<TOKENS
+token
+TOKENS
+
+tests:ensure curl -sk "https://127.0.0.1:60002/t/pool/?all=1"
+tests:assert-no-diff stdout <<TOKENS
+token
+TOKENS
+
+tests:ensure curl -sk --cert client.pem --key client.key \
+    "https://127.0.0.1:60002/t/pool/?all=1"
+tests:assert-no-diff stdout <<TOKENS
+.bootstrap
+token
+TOKENS
+
+tests:ensure curl -sk "https://127.0.0.1:60002/t/pool/.bootstrap"
+tests:assert-stdout-re '^\$5\$'
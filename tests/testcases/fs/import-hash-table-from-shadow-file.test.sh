@@ -0,0 +1,23 @@
+tests:put shadow <<SHADOW
+root:\$6\$abcdefghijklmnop\$J/AWykHqo2Tx5UtavGnFc3ytI33la50JpzLTarSWVhkIXK6wOjNwwZjsrIw2UgmrER2EKrSHCeQyAINEEXAk1/:19000:0:99999:7:::
+user:\$6\$ponmlkjihgfedcba\$J/AWykHqo2Tx5UtavGnFc3ytI33la50JpzLTarSWVhkIXK6wOjNwwZjsrIw2UgmrER2EKrSHCeQyAINEEXAk1/:19000:0:99999:7:::
+SHADOW
+
+tests:ensure :shadowd table import pool/token --shadow-format '<' shadow
+tests:assert-stdout 'Hash table pool/token with 2 items successfully imported'
+
+tests:assert-no-diff $(tests:get-tmp-dir)/tables/pool/token <<RECORDS
+\$6\$abcdefghijklmnop\$J/AWykHqo2Tx5UtavGnFc3ytI33la50JpzLTarSWVhkIXK6wOjNwwZjsrIw2UgmrER2EKrSHCeQyAINEEXAk1/
+\$6\$ponmlkjihgfedcba\$J/AWykHqo2Tx5UtavGnFc3ytI33la50JpzLTarSWVhkIXK6wOjNwwZjsrIw2UgmrER2EKrSHCeQyAINEEXAk1/
+RECORDS
+
+tests:put shadow <<SHADOW
+daemon:*:19000:0:99999:7:::
+nobody
+SHADOW
+
+tests:not tests:ensure :shadowd table import pool/locked --shadow-format \
+    '<' shadow
+tests:assert-exitcode 2
+tests:assert-stderr 'line 1:'
+tests:assert-stderr 'line 2: line should contain colon separated user'
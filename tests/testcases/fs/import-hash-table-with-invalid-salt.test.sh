@@ -0,0 +1,13 @@
+tests:put records <<RECORDS
+\$6\$abcdefghijklmnop\$J/AWykHqo2Tx5UtavGnFc3ytI33la50JpzLTarSWVhkIXK6wOjNwwZjsrIw2UgmrER2EKrSHCeQyAINEEXAk1/
+\$6\$abcdefgh*jklmnop\$J/AWykHqo2Tx5UtavGnFc3ytI33la50JpzLTarSWVhkIXK6wOjNwwZjsrIw2UgmrER2EKrSHCeQyAINEEXAk1/
+RECORDS
+
+tests:eval :shadowd table import pool/token '<' records
+tests:assert-exitcode 2
+tests:assert-stderr "line 2: salt contains invalid symbol '*' at position 9"
+tests:assert-test ! -f $(tests:get-tmp-dir)/tables/pool/token
+
+tests:ensure :shadowd table import --lenient pool/token '<' records
+tests:assert-stderr "Warning: line 2: salt contains invalid symbol '*'"
+tests:assert-stdout 'Hash table pool/token with 2 items successfully imported'
@@ -0,0 +1,9 @@
+tests:put records <<RECORDS
+\$6\$abcdefghijklmnop\$J/AWykHqo2Tx5UtavGnFc3ytI33la50JpzLTarSWVhkIXK6wOjNwwZjsrIw2UgmrER2EKrSHCeQyAINEEXAk1/
+\$6\$ponmlkjihgfedcba\$J/AWykHqo2Tx5UtavGnFc3ytI33la50JpzLTarSWVhkIXK6wOjNwwZjsrIw2UgmrER2EKrSHCeQyAINEEXAk1/
+RECORDS
+
+tests:ensure :shadowd table import pool/token '<' records
+tests:assert-stdout 'Hash table pool/token with 2 items successfully imported'
+
+tests:assert-no-diff $(tests:get-tmp-dir)/tables/pool/token < records
@@ -0,0 +1,23 @@
+:shadowd-listen "127.0.0.1:60002" --global-serve-limit 1 \
+    --retry-after 10-1000
+
+tests:ensure :shadowd -G --no-confirm --length 10 pool/a '<<<' 'password'
+
+tests:ensure curl -sk "https://127.0.0.1:60002/t/pool/a"
+
+delays=()
+for i in 1 2 3 4 5; do
+    tests:ensure curl -sk -o /dev/null -D - "https://127.0.0.1:60002/t/pool/a"
+    tests:assert-stdout '503 Service Unavailable'
+
+    delay=$(grep -i '^Retry-After:' $(tests:get-stdout-file) \
+        | tr -d '\r' | cut -d' ' -f2)
+
+    tests:assert-test "$delay" -ge 10
+    tests:assert-test "$delay" -le 1000
+
+    delays+=("$delay")
+done
+
+# values are spread, so denied clients don't retry at the same time
+tests:assert-test "$(printf '%s\n' "${delays[@]}" | sort -u | wc -l)" -gt 1
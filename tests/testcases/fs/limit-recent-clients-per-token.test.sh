@@ -0,0 +1,20 @@
+:shadowd-listen "127.0.0.1:60002" --trace-index \
+    --recent-key-template "'{{.Header.Get \"X-Host-Id\"}}-{{.Token}}'" \
+    --recent-clients-per-token 2
+
+tests:ensure :shadowd -G --no-confirm --length 100 pool/a '<<<' 'password'
+
+for host in host1 host2 host3 host3 host1; do
+    tests:ensure curl -sk -H "'X-Host-Id: $host'" \
+        "https://127.0.0.1:60002/t/pool/a"
+    tests:assert-stdout-re '^\$5\$'
+done
+
+tests:ensure cat $(tests:get-background-stderr $_shadowd)
+tests:assert-stdout 'limit of 2 recent clients reached for pool/a'
+tests:assert-stdout-re 'remote=host1-pool/a recent=true'
+tests:assert-stdout-re 'remote=host3-pool/a recent=false'
+tests:not tests:assert-stdout 'remote=host3-pool/a recent=true'
+
+tests:ensure curl -sk "https://127.0.0.1:60002/metrics"
+tests:assert-stdout 'shadowd_recent_clients_untracked_total 2'
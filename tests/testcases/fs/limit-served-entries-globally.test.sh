@@ -0,0 +1,30 @@
+:shadowd-listen "127.0.0.1:60002" --global-serve-limit 3
+
+tests:ensure :shadowd -G --no-confirm --length 10 pool/a '<<<' 'password'
+tests:ensure :shadowd -G --no-confirm --length 10 pool/b '<<<' 'password'
+
+tests:put expired <<RECORDS
+\$6\$abcdefghijklmnop\$J/AWykHqo2Tx5UtavGnFc3ytI33la50JpzLTarSWVhkIXK6wOjNwwZjsrIw2UgmrER2EKrSHCeQyAINEEXAk1/ 2000-01-01T00:00:00Z
+RECORDS
+
+tests:ensure :shadowd table import pool/expired '<' expired
+
+# entries, which are not served, are not counted
+for i in 1 2 3 4; do
+    tests:ensure curl -sk -o /dev/null -w '%{http_code}' \
+        "https://127.0.0.1:60002/t/pool/expired"
+    tests:assert-no-diff stdout <<< '410'
+done
+
+for token in pool/a pool/b pool/a; do
+    tests:ensure curl -sk -o /dev/null -w '%{http_code}' \
+        "https://127.0.0.1:60002/t/$token"
+    tests:assert-no-diff stdout <<< '200'
+done
+
+tests:ensure curl -sk -o /dev/null -w '%{http_code}' \
+    "https://127.0.0.1:60002/t/pool/b"
+tests:assert-no-diff stdout <<< '503'
+
+tests:ensure curl -sk "https://127.0.0.1:60002/metrics"
+tests:assert-stdout-re '^shadowd_serve_limit_exceeded_total 1$'
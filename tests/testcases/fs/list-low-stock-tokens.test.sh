@@ -0,0 +1,22 @@
+:shadowd-listen "127.0.0.1:60002" --served-flush-interval 100ms
+
+tests:ensure :shadowd -G --no-confirm --length 10 pool/healthy '<<<' 'password'
+tests:ensure :shadowd -G --no-confirm --length 10 pool/exhausted '<<<' 'password'
+
+tests:ensure curl -sk "https://127.0.0.1:60002/t/pool/healthy"
+
+for i in {1..9}; do
+    tests:ensure curl -sk "https://127.0.0.1:60002/t/pool/exhausted"
+done
+
+tests:ensure sleep 1
+
+tests:ensure :shadowd table low-stock --threshold 0.5
+tests:assert-no-diff stdout <<TOKENS
+pool/exhausted: 9 of 10 entries served
+TOKENS
+
+tests:ensure :shadowd table low-stock --threshold 0.5 --json
+tests:assert-no-diff stdout <<TOKENS
+[{"token":"pool/exhausted","served":9,"size":10}]
+TOKENS
@@ -0,0 +1,13 @@
+:client-certificate
+
+:shadowd-listen "127.0.0.1:60002" --redact-tokens \
+    --client-ca $(tests:get-tmp-dir)/ca.pem
+
+tests:ensure :shadowd -G --no-confirm --length 100 a/b/c/d '<<<' 'password'
+
+tests:ensure curl -sk "https://127.0.0.1:60002/t/a/b/c/d"
+
+tests:ensure curl -sk --cert client.pem --key client.key \
+    "https://127.0.0.1:60002/admin/recent/a/b/c/d"
+tests:assert-stdout-re '"client":"[0-9a-f]{16}"'
+tests:not tests:assert-stdout '127.0.0.1'
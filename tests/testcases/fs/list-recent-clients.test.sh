@@ -0,0 +1,15 @@
+:client-certificate
+
+:shadowd-listen "127.0.0.1:60002" --client-ca $(tests:get-tmp-dir)/ca.pem
+
+tests:ensure :shadowd -G --no-confirm --length 100 a/b/c/d '<<<' 'password'
+
+tests:ensure curl -sk "https://127.0.0.1:60002/t/a/b/c/d"
+
+tests:ensure curl -sk --cert client.pem --key client.key \
+    "https://127.0.0.1:60002/admin/recent/a/b/c/d"
+tests:assert-stdout '"client":"127.0.0.1"'
+
+tests:ensure curl -sk -w '%{http_code}' \
+    "https://127.0.0.1:60002/admin/recent/a/b/c/d"
+tests:assert-no-diff stdout <<< '403'
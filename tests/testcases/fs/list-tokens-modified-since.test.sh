@@ -0,0 +1,35 @@
+:shadowd-listen 127.0.0.1:60002
+
+tests:ensure \
+    :shadowd --no-confirm --length 10 -G pool/token '<<<' "password"
+
+tests:ensure \
+    :shadowd --no-confirm --length 10 -G pool/token2 '<<<' "password"
+
+tests:ensure \
+    :shadowd --no-confirm --length 10 -G pool2/token3 '<<<' "password"
+
+sleep 1
+since=$(date -u +%Y-%m-%dT%H:%M:%SZ)
+sleep 1
+
+tests:ensure \
+    :shadowd --no-confirm --length 10 -G pool/token2 '<<<' "password"
+
+tests:ensure \
+    :shadowd --no-confirm --length 10 -G pool2/token4 '<<<' "password"
+
+tests:ensure curl -sk "https://127.0.0.1:60002/t/pool/?since=$since"
+tests:assert-no-diff stdout <<TOKENS
+token2
+TOKENS
+
+sleep 1
+
+tests:ensure curl -sk -w '%{http_code}' \
+    "https://127.0.0.1:60002/t/pool/?since=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+tests:assert-no-diff stdout <<< '204'
+
+tests:ensure curl -sk -o /dev/null -w '%{http_code}' \
+    "https://127.0.0.1:60002/t/pool/?since=yesterday"
+tests:assert-stdout 400
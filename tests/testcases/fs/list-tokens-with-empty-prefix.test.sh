@@ -0,0 +1,12 @@
+:shadowd-listen "127.0.0.1:60002" --not-found-body plain
+
+tests:ensure mkdir -p tables/a/b
+
+tests:ensure curl -sk -w '%{http_code}' "https://127.0.0.1:60002/t/a/b/"
+tests:assert-no-diff stdout <<< '204'
+
+tests:ensure curl -sk -w '%{http_code}' "https://127.0.0.1:60002/t/a/c/"
+tests:assert-no-diff stdout <<BODY
+token a/c/ not found
+404
+BODY
@@ -0,0 +1,16 @@
+tests:put hmac.key <<< 'topsecret'
+
+:shadowd-listen "127.0.0.1:60002" -s 1h --cache-size 10 \
+    --hmac-key-file $(tests:get-tmp-dir)/hmac.key
+
+tests:ensure curl -sk "https://127.0.0.1:60002/t/"
+
+tests:ensure grep 'effective settings:' $(tests:get-background-stderr $_shadowd)
+tests:assert-stdout '"listen":"127.0.0.1:60002"'
+tests:assert-stdout '"backend":"filesystem"'
+tests:assert-stdout '"hash_ttl":"1h0m0s"'
+tests:assert-stdout '"algorithms":["sha256","sha512"]'
+tests:assert-stdout '"tls":"server"'
+tests:assert-stdout '"cache_size":"10"'
+tests:assert-stdout "\"hmac_key_file\":\"$(tests:get-tmp-dir)/hmac.key\""
+tests:not tests:assert-stdout 'topsecret'
@@ -0,0 +1,19 @@
+:client-certificate
+
+tests:ensure openssl req -x509 -newkey rsa:1024 -nodes -days 1 \
+    -subj /CN=rogue -keyout rogue.key -out rogue.pem
+
+:shadowd-listen "127.0.0.1:60002" --client-ca $(tests:get-tmp-dir)/ca.pem \
+    --tls-log verbose
+
+tests:not tests:ensure curl -sk --cert rogue.pem --key rogue.key \
+    "https://127.0.0.1:60002/t/a"
+
+tests:ensure curl -sk --cert client.pem --key client.key \
+    "https://127.0.0.1:60002/t/a"
+
+tests:ensure cat $(tests:get-background-stderr $_shadowd)
+tests:assert-stdout-re \
+    'TLS handshake error from 127.0.0.1:[0-9]+: .*signed by unknown authority'
+tests:assert-stdout-re \
+    'TLS handshake with 127.0.0.1:[0-9]+ completed, client certificate: CN=client'
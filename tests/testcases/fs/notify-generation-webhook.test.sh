@@ -0,0 +1,40 @@
+tests:put webhook.py <<PY
+try:
+    from http.server import BaseHTTPRequestHandler, HTTPServer
+except ImportError:
+    from BaseHTTPServer import BaseHTTPRequestHandler, HTTPServer
+
+
+class Handler(BaseHTTPRequestHandler):
+    def do_POST(self):
+        length = int(self.headers['Content-Length'])
+        with open('webhook.json', 'wb') as payload:
+            payload.write(self.rfile.read(length))
+
+        self.send_response(204)
+        self.end_headers()
+
+
+HTTPServer(('127.0.0.1', 60005), Handler).handle_request()
+PY
+
+tests:run-background webhook python webhook.py
+tests:ensure sleep 1
+
+tests:ensure :shadowd -G --no-confirm --length 10 \
+    --generation-webhook http://127.0.0.1:60005/ pool/token '<<<' 'password'
+tests:assert-stdout 'Hash table pool/token with 10 items successfully created'
+tests:not tests:assert-stderr 'Warning'
+
+tests:ensure cat webhook.json
+tests:assert-stdout '"token":"pool/token"'
+tests:assert-stdout '"length":10'
+tests:assert-stdout '"algorithm":"sha256"'
+tests:assert-stdout-re '"operator":"[^"]+"'
+tests:assert-stdout-re '"timestamp":"[0-9]{4}-'
+tests:not tests:assert-stdout '$5$'
+
+tests:ensure :shadowd -G --no-confirm --length 10 \
+    --generation-webhook http://127.0.0.1:60005/ pool/token '<<<' 'password'
+tests:assert-stdout 'Hash table pool/token with 10 items successfully created'
+tests:assert-stderr "Warning: can't notify generation webhook"
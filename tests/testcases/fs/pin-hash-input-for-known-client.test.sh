@@ -0,0 +1,27 @@
+:shadowd-listen "127.0.0.1:60002" --trace-index
+
+tests:ensure :shadowd -G --no-confirm --length 1000 a/b/c/d '<<<' 'password'
+
+tests:ensure curl -sk "https://127.0.0.1:60002/t/a/b/c/d"
+
+tests:ensure cat $(tests:get-background-stderr $_shadowd)
+tests:value slot sed -rn 's/.*trace index: .* slot=([0-9]+) .*/\1/p' \
+    $(tests:get-stdout-file)
+tests:value number sed -rn 's/.*trace index: .* number=([0-9]+)$/\1/p' \
+    $(tests:get-stdout-file)
+
+tests:describe "slot: $slot, number: $number"
+
+# index is computed from sha256 of "<client>-<token><slot>"
+tests:ensure python -c "
+import hashlib
+digest = bytearray(hashlib.sha256(b'127.0.0.1-a/b/c/d$slot').digest())
+length, index = 1, 0
+for byte in digest:
+    if length > 1000:
+        break
+    length <<= 8
+    index += length * byte
+print(index % 999)
+"
+tests:assert-no-diff stdout <<< "$number"
@@ -0,0 +1,19 @@
+:shadowd-listen "127.0.0.1:60002" --ttl 1s
+
+tests:ensure :shadowd -G --no-confirm --length 1000 a '<<<' 'password'
+
+tests:ensure curl -sk -H "'X-Shadowd-Stable: true'" \
+    "https://127.0.0.1:60002/t/a"
+stable=$(cat $(tests:get-stdout-file))
+
+tests:ensure curl -sk "https://127.0.0.1:60002/t/a"
+rotating=$(cat $(tests:get-stdout-file))
+
+sleep 2
+
+tests:ensure curl -sk -H "'X-Shadowd-Stable: true'" \
+    "https://127.0.0.1:60002/t/a"
+tests:assert-no-diff stdout <<< "$stable"
+
+tests:ensure curl -sk "https://127.0.0.1:60002/t/a"
+tests:assert-test "'$(cat $(tests:get-stdout-file))'" != "'$rotating'"
@@ -0,0 +1,21 @@
+tests:make-tmp-dir provision
+
+:shadowd-listen "127.0.0.1:60002" --next-depth 0 \
+    --provision-dir $(tests:get-tmp-dir)/provision
+
+tests:ensure :shadowd -G --no-confirm --length 1 pool/source '<<<' 'password'
+
+tests:ensure curl -sk -w '%{http_code}' "https://127.0.0.1:60002/t/pool/token"
+tests:assert-no-diff stdout <<< '404'
+
+tests:ensure mkdir $(tests:get-tmp-dir)/provision/pool
+tests:ensure cp $(tests:get-tmp-dir)/tables/pool/source \
+    $(tests:get-tmp-dir)/provision/pool/token
+
+sleep 2
+
+tests:ensure curl -sk "https://127.0.0.1:60002/t/pool/token"
+tests:assert-no-diff stdout < $(tests:get-tmp-dir)/tables/pool/source
+
+tests:ensure cat $(tests:get-background-stderr $_shadowd)
+tests:assert-stdout 'hash table pool/token with 1 items has been provisioned'
@@ -0,0 +1,21 @@
+:shadowd-prepare
+
+tests:ensure :shadowd -G --no-confirm --length 100 \
+    --sunset 2000-01-01T00:00:00Z a/old '<<<' 'password'
+tests:ensure :shadowd -G --no-confirm --length 100 a/new '<<<' 'password'
+
+tests:ensure :shadowd prune --dry-run
+tests:assert-stdout 'Hash table a/old would be removed.'
+tests:assert-stdout '1 expired hash tables would be removed.'
+tests:assert-stdout 'Recent clients are kept in memory of running server'
+tests:assert-test -f $(tests:get-tmp-dir)/tables/a/old
+
+tests:ensure :shadowd prune
+tests:assert-stdout 'Hash table a/old removed.'
+tests:assert-test ! -f $(tests:get-tmp-dir)/tables/a/old
+tests:assert-test ! -f $(tests:get-tmp-dir)/meta/a/old
+tests:assert-test -f $(tests:get-tmp-dir)/tables/a/new
+
+tests:ensure :shadowd prune
+tests:assert-stdout '0 expired hash tables removed.'
+tests:assert-stdout 'for filesystem backend and are not pruned.'
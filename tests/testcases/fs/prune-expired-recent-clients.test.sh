@@ -0,0 +1,16 @@
+:shadowd-listen "127.0.0.1:60002" --ttl 2s --prune-interval 1s
+
+tests:ensure :shadowd -G --no-confirm --length 100 pool/a '<<<' 'password'
+tests:ensure :shadowd -G --no-confirm --length 100 pool/b '<<<' 'password'
+
+tests:ensure curl -sk "https://127.0.0.1:60002/t/pool/a"
+
+# client of pool/a expires after ttl and is pruned on the next tick
+tests:ensure sleep 3.5
+
+tests:ensure curl -sk "https://127.0.0.1:60002/t/pool/b"
+
+tests:ensure sleep 1.5
+
+tests:ensure curl -sk "https://127.0.0.1:60002/metrics"
+tests:assert-stdout-re '^shadowd_recent_clients_pruned_total 1$'
@@ -0,0 +1,40 @@
+tests:ensure :shadowd -G --no-confirm --length 10 \
+    --event-sink file://$(tests:get-tmp-dir)/events.json \
+    pool/token '<<<' 'password'
+tests:assert-stdout 'Hash table pool/token with 10 items successfully created'
+tests:not tests:assert-stderr 'Warning'
+
+tests:ensure cat events.json
+tests:assert-stdout '"type":"generation"'
+tests:assert-stdout '"token":"pool/token"'
+tests:assert-stdout '"length":10'
+tests:assert-stdout '"algorithm":"sha256"'
+tests:not tests:assert-stdout '$5$'
+tests:not tests:assert-stdout 'password'
+
+:shadowd-listen "127.0.0.1:60002" \
+    --event-sink file://$(tests:get-tmp-dir)/events.json
+
+tests:ensure curl -sk "https://127.0.0.1:60002/t/pool/token"
+tests:ensure sleep 1
+
+tests:ensure tail -n 1 events.json
+tests:assert-stdout '"type":"serve"'
+tests:assert-stdout '"token":"pool/token"'
+tests:assert-stdout '"client":"127.0.0.1"'
+tests:not tests:assert-stdout '"index"'
+tests:not tests:assert-stdout '$5$'
+
+:shadowd-background -L "127.0.0.1:60003" --expose-index \
+    --event-sink file://$(tests:get-tmp-dir)/events.json
+
+tests:ensure curl -sk "https://127.0.0.1:60003/t/pool/token"
+tests:ensure sleep 1
+
+tests:ensure tail -n 1 events.json
+tests:assert-stdout-re '"index":[0-9]+'
+
+tests:not tests:ensure :shadowd -G --no-confirm --length 10 \
+    --event-sink amqp://127.0.0.1/ pool/token '<<<' 'password'
+tests:assert-stderr 'unsupported event sink scheme: amqp'
+tests:assert-exitcode 2
@@ -0,0 +1,18 @@
+tests:ensure :shadowd -G --no-confirm --length 10 --no-clobber pool/token \
+    '<<<' 'password'
+tests:assert-stdout 'Hash table pool/token with 10 items successfully created'
+
+tests:ensure cp $(tests:get-tmp-dir)/tables/pool/token token.old
+
+tests:not tests:ensure :shadowd -G --no-confirm --length 10 --no-clobber \
+    pool/token '<<<' 'password'
+tests:assert-stderr 'hash table pool/token already exists'
+tests:assert-exitcode 2
+
+tests:ensure cmp $(tests:get-tmp-dir)/tables/pool/token token.old
+
+tests:ensure :shadowd -G --no-confirm --length 10 --no-clobber --force \
+    pool/token '<<<' 'password'
+tests:assert-stdout 'Hash table pool/token with 10 items successfully created'
+
+tests:not tests:ensure cmp $(tests:get-tmp-dir)/tables/pool/token token.old
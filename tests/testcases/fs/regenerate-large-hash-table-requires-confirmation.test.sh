@@ -0,0 +1,21 @@
+tests:ensure \
+    :shadowd --no-confirm --length 100 -G pool/token '<<<' "password"
+
+tests:value table cat $(tests:get-tmp-dir)/tables/pool/token
+
+tests:eval :shadowd --no-confirm --length 100 --confirm-threshold 50 \
+    -G pool/token '<<<' "password"
+tests:assert-exitcode 2
+tests:assert-stdout 'table size: 100'
+tests:assert-stdout 'recent clients: 0'
+tests:assert-stderr 'use --confirm to regenerate it'
+
+tests:assert-no-diff $(tests:get-tmp-dir)/tables/pool/token <<< "$table"
+
+tests:ensure :shadowd --no-confirm --length 100 --confirm-threshold 50 \
+    --confirm -G pool/token '<<<' "password"
+tests:assert-stdout 'Hash table pool/token with 100 items successfully created'
+
+tests:ensure :shadowd --no-confirm --length 100 --confirm-threshold 200 \
+    -G pool/token '<<<' "password"
+tests:assert-stdout 'Hash table pool/token with 100 items successfully created'
@@ -0,0 +1,11 @@
+:client-certificate
+
+:shadowd-serve-generate "127.0.0.1:60003" \
+    --client-ca $(tests:get-tmp-dir)/ca.pem
+
+tests:ensure curl -sk -w '%{http_code}' --cert client.pem --key client.key \
+    -d token=pool/token -d length=10 -d password= \
+    "https://127.0.0.1:60003/admin/generate"
+tests:assert-no-diff stdout <<< '400'
+
+tests:assert-test ! -f $(tests:get-tmp-dir)/tables/pool/token
@@ -0,0 +1,10 @@
+tests:not tests:ensure :shadowd -G --no-confirm --length 10 pool/token \
+    '<<<' ''
+tests:assert-stderr 'empty password specified'
+tests:assert-exitcode 2
+
+tests:assert-test ! -f $(tests:get-tmp-dir)/tables/pool/token
+
+tests:ensure :shadowd -G --no-confirm --length 10 --allow-empty-password \
+    pool/token '<<<' ''
+tests:assert-stdout 'Hash table pool/token with 10 items successfully created'
@@ -0,0 +1,13 @@
+:shadowd-listen "127.0.0.1:60002"
+
+tests:ensure :shadowd -G --no-confirm --length 100 a/b '<<<' 'password'
+
+for path in "t/a/b%01" "ssh/a/b%01" "v/a/b%01/hash" "t/a%0A/" "v/a/b/%7F"; do
+    tests:ensure curl -sk -o /dev/null -w '%{http_code}' \
+        "https://127.0.0.1:60002/$path"
+    tests:assert-no-diff stdout <<< '400'
+done
+
+tests:ensure curl -sk -o /dev/null -w '%{http_code}' \
+    "https://127.0.0.1:60002/t/a/b"
+tests:assert-no-diff stdout <<< '200'
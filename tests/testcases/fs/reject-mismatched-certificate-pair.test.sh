@@ -0,0 +1,14 @@
+tests:ensure :shadowd -C --bytes 1024
+
+tests:ensure :shadowd cert verify
+tests:assert-stdout 'matches key'
+
+tests:ensure openssl genrsa -out $(tests:get-tmp-dir)/certs/key.pem 1024
+
+tests:not tests:ensure :shadowd cert verify
+tests:assert-stderr-re 'certificate .*/cert.pem does not match key .*/key.pem'
+tests:assert-exitcode 2
+
+tests:not tests:ensure :shadowd -L 127.0.0.1:60002
+tests:assert-stderr-re 'certificate .*/cert.pem does not match key .*/key.pem'
+tests:assert-exitcode 2
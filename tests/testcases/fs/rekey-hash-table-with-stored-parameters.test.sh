@@ -0,0 +1,25 @@
+tests:ensure :shadowd -G --no-confirm --length 10 --crypt-id '5$rounds=2000' \
+    pool/token '<<<' 'password'
+
+tests:ensure :shadowd table rekey pool/token -a sha512 '<<<' 'password'
+tests:assert-stdout \
+    "Hash table pool/token successfully rekeyed to crypt id '6\$rounds=2000'."
+
+tests:ensure wc -l '<' $(tests:get-tmp-dir)/tables/pool/token
+tests:assert-stdout '10'
+
+tests:ensure head -n 1 $(tests:get-tmp-dir)/tables/pool/token
+tests:assert-stdout-re '^\$6\$rounds=2000\$[^$]{16}\$[^$]{86}$'
+
+tests:ensure :shadowd -G --no-confirm --length 10 pool/plain '<<<' 'password'
+
+tests:ensure :shadowd table rekey pool/plain -a sha512 '<<<' 'password'
+
+record=$(head -n 1 $(tests:get-tmp-dir)/tables/pool/plain)
+salt=$(cut -d'$' -f3 <<< "$record")
+
+tests:ensure openssl passwd -6 -salt "$salt" 'password'
+tests:assert-stdout "$record"
+
+tests:not tests:ensure :shadowd table rekey pool/missing '<<<' 'password'
+tests:assert-exitcode 3
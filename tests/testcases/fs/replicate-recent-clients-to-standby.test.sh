@@ -0,0 +1,39 @@
+:client-certificate
+
+:shadowd-listen "127.0.0.1:60002" --client-ca $(tests:get-tmp-dir)/ca.pem
+
+:shadowd-background -L "127.0.0.1:60003" \
+    --client-ca $(tests:get-tmp-dir)/ca.pem \
+    --replicate-recent 127.0.0.1:60002 \
+    --replicate-cert $(tests:get-tmp-dir)/client.pem \
+    --replicate-key $(tests:get-tmp-dir)/client.key
+
+tests:ensure :shadowd -G --no-confirm --length 100 a/b/c/d '<<<' 'password'
+
+tests:ensure curl -sk "https://127.0.0.1:60003/t/a/b/c/d"
+
+sleep 1
+
+tests:ensure curl -sk --cert client.pem --key client.key \
+    "https://127.0.0.1:60002/admin/recent/a/b/c/d"
+tests:assert-stdout '"client":"127.0.0.1"'
+
+tests:ensure curl -sk --cert client.pem --key client.key -X DELETE \
+    "https://127.0.0.1:60003/admin/recent/a/b/c/d"
+
+sleep 1
+
+tests:ensure curl -sk --cert client.pem --key client.key \
+    "https://127.0.0.1:60002/admin/recent/a/b/c/d"
+tests:assert-no-diff stdout <<< '[]'
+
+tests:ensure curl -sk "https://127.0.0.1:60003/t/a/b/c/d"
+
+tests:ensure curl -sk --cert client.pem --key client.key -X DELETE \
+    "https://127.0.0.1:60003/admin/recent?remote=127.0.0.1"
+
+sleep 1
+
+tests:ensure curl -sk --cert client.pem --key client.key \
+    "https://127.0.0.1:60002/admin/recent/a/b/c/d"
+tests:assert-no-diff stdout <<< '[]'
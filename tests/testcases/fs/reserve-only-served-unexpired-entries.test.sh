@@ -0,0 +1,23 @@
+:shadowd-listen "127.0.0.1:60002" --reserve
+
+tests:put records <<RECORDS
+\$6\$abcdefghijklmnop\$J/AWykHqo2Tx5UtavGnFc3ytI33la50JpzLTarSWVhkIXK6wOjNwwZjsrIw2UgmrER2EKrSHCeQyAINEEXAk1/ 2000-01-01T00:00:00Z
+\$6\$ponmlkjihgfedcba\$J/AWykHqo2Tx5UtavGnFc3ytI33la50JpzLTarSWVhkIXK6wOjNwwZjsrIw2UgmrER2EKrSHCeQyAINEEXAk1/ 2100-01-01T00:00:00Z
+\$6\$qrstuvwxyzabcdef\$J/AWykHqo2Tx5UtavGnFc3ytI33la50JpzLTarSWVhkIXK6wOjNwwZjsrIw2UgmrER2EKrSHCeQyAINEEXAk1/ 2000-01-01T00:00:00Z
+\$6\$fedcbazyxwvutsrq\$J/AWykHqo2Tx5UtavGnFc3ytI33la50JpzLTarSWVhkIXK6wOjNwwZjsrIw2UgmrER2EKrSHCeQyAINEEXAk1/ 2100-01-01T00:00:00Z
+RECORDS
+
+tests:ensure :shadowd table import pool/token '<' records
+
+for i in 1 2; do
+    tests:ensure curl -sk "https://127.0.0.1:60002/t/pool/token" \
+        -o record.$i
+done
+
+tests:not tests:ensure cmp -s record.1 record.2
+
+tests:not tests:ensure grep -e abcdefghijklmnop -e qrstuvwxyzabcdef \
+    record.1 record.2
+
+tests:ensure curl -sk -w '%{http_code}' "https://127.0.0.1:60002/t/pool/token"
+tests:assert-no-diff stdout <<< '503'
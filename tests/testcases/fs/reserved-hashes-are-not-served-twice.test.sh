@@ -0,0 +1,16 @@
+:shadowd-listen "127.0.0.1:60002" --reserve
+
+tests:ensure :shadowd -G --no-confirm --length 100 a/b/c/d '<<<' 'password'
+
+:pull-concurrently() {
+    for i in {1..10}; do
+        curl -sk "https://127.0.0.1:60002/t/a/b/c/d" > record.$i &
+    done
+
+    wait
+}
+
+tests:ensure :pull-concurrently
+
+tests:ensure cat record.* \| sort -u \| wc -l
+tests:assert-stdout-re '^10$'
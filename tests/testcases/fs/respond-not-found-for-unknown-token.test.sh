@@ -0,0 +1,4 @@
+:shadowd-listen "127.0.0.1:60002"
+
+tests:ensure curl -sk -w '%{http_code}' "https://127.0.0.1:60002/t/a/b/c/d"
+tests:assert-no-diff stdout <<< '404'
@@ -0,0 +1,9 @@
+:shadowd-listen "127.0.0.1:60002" --not-found-body plain
+
+tests:ensure curl -sk -H "'Accept-Language: fr;q=0.9, de-AT'" \
+    "https://127.0.0.1:60002/t/a/b/c/d"
+tests:assert-no-diff stdout <<< 'Token a/b/c/d nicht gefunden'
+
+tests:ensure curl -sk -H "'Accept-Language: fr'" \
+    "https://127.0.0.1:60002/t/a/b/c/d"
+tests:assert-no-diff stdout <<< 'token a/b/c/d not found'
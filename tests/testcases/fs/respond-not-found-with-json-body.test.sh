@@ -0,0 +1,7 @@
+:shadowd-listen "127.0.0.1:60002" --not-found-body json
+
+tests:ensure curl -sk -w '%{http_code}' "https://127.0.0.1:60002/t/a/b/c/d"
+tests:assert-no-diff stdout <<BODY
+{"error":"not found","message":"token a/b/c/d not found","token":"a/b/c/d"}
+404
+BODY
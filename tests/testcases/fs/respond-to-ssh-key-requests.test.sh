@@ -0,0 +1,22 @@
+:shadowd-listen "127.0.0.1:60002"
+
+tests:ensure ssh-keygen -t rsa -b 1024 -f id_rsa
+tests:ensure :shadowd -K blah/token '<' id_rsa.pub
+
+tests:ensure curl -sk -w '%{http_code}' -o /dev/null \
+    "https://127.0.0.1:60002/ssh/blah/token"
+tests:assert-no-diff stdout <<< '200'
+
+tests:ensure curl -sk -w '%{http_code}' -o /dev/null \
+    "https://127.0.0.1:60002/ssh/blah/unknown"
+tests:assert-no-diff stdout <<< '404'
+
+tests:ensure curl -sk -w '%{http_code}' -o /dev/null -X POST \
+    "https://127.0.0.1:60002/ssh/blah/token"
+tests:assert-no-diff stdout <<< '405'
+
+tests:ensure touch $(tests:get-tmp-dir)/ssh/blah/empty
+
+tests:ensure curl -sk -w '%{http_code}' -o /dev/null \
+    "https://127.0.0.1:60002/ssh/blah/empty"
+tests:assert-no-diff stdout <<< '204'
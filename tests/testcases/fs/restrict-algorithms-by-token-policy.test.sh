@@ -0,0 +1,25 @@
+tests:put policy.toml <<POLICY
+[[algorithms]]
+pattern = "^secure-"
+allow = ["sha512", "crypt:y"]
+POLICY
+
+tests:not tests:ensure :shadowd -G --no-confirm --length 10 \
+    --policy policy.toml secure-token '<<<' 'password'
+tests:assert-stderr 'algorithm sha256 is not allowed for token secure-token'
+tests:assert-exitcode 2
+tests:not tests:assert-stdout 'Enter password'
+
+tests:assert-test ! -f $(tests:get-tmp-dir)/tables/secure-token
+
+tests:not tests:ensure :shadowd -G --no-confirm --length 10 --crypt-id 5 \
+    --policy policy.toml secure-token '<<<' 'password'
+tests:assert-stderr 'algorithm sha256 is not allowed for token secure-token'
+
+tests:ensure :shadowd -G --no-confirm --length 10 -a sha512 \
+    --policy policy.toml secure-token '<<<' 'password'
+tests:assert-stdout 'Hash table secure-token with 10 items successfully created'
+
+tests:ensure :shadowd -G --no-confirm --length 10 \
+    --policy policy.toml legacy-token '<<<' 'password'
+tests:assert-stdout 'Hash table legacy-token with 10 items successfully created'
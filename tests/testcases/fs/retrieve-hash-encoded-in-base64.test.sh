@@ -0,0 +1,23 @@
+# repeated requests should get the same entry
+:shadowd-listen "127.0.0.1:60002" --next-depth 0
+
+tests:ensure :shadowd -G --no-confirm --length 100 a/b/c/d '<<<' 'password'
+
+tests:ensure curl -sk "https://127.0.0.1:60002/t/a/b/c/d"
+tests:value record cat $(tests:get-stdout-file)
+
+tests:ensure curl -sk -D headers -o encoded \
+    "https://127.0.0.1:60002/t/a/b/c/d?encoding=base64"
+
+tests:ensure cat headers
+tests:assert-stdout 'X-Shadowd-Encoding: base64'
+
+tests:ensure base64 -d encoded
+tests:assert-no-diff stdout <<< "$record"
+
+tests:ensure curl -sk -D - "https://127.0.0.1:60002/t/a/b/c/d?encoding=raw"
+tests:not tests:assert-stdout 'X-Shadowd-Encoding'
+
+tests:ensure curl -sk -w '%{http_code}' \
+    "https://127.0.0.1:60002/t/a/b/c/d?encoding=hex"
+tests:assert-no-diff stdout <<< '400'
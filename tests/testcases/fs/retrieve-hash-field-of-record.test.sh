@@ -0,0 +1,16 @@
+# repeated requests should get the same entry
+:shadowd-listen "127.0.0.1:60002" --next-depth 0
+
+tests:ensure :shadowd -G --no-confirm --length 100 a/b/c/d '<<<' 'password'
+
+tests:ensure curl -sk "https://127.0.0.1:60002/t/a/b/c/d?field=full"
+tests:assert-stdout-re '^\$5\$[^$]{16}\$[^$]{43}$'
+tests:value record cat $(tests:get-stdout-file)
+
+tests:ensure curl -sk "https://127.0.0.1:60002/t/a/b/c/d?field=hash"
+tests:assert-stdout-re '^[^$]{43}$'
+tests:assert-no-diff stdout <<< "${record##*\$}"
+
+tests:ensure curl -sk -w '%{http_code}' \
+    "https://127.0.0.1:60002/t/a/b/c/d?field=salt"
+tests:assert-no-diff stdout <<< '400'
@@ -0,0 +1,13 @@
+# repeated requests should get the same entry
+:shadowd-listen "127.0.0.1:60002" --next-depth 0 \
+    --listen-http3 "127.0.0.1:60002"
+
+tests:ensure :shadowd -G --no-confirm --length 100 a/b/c/d '<<<' 'password'
+
+tests:ensure curl -sk -D headers -o record "https://127.0.0.1:60002/t/a/b/c/d"
+
+tests:ensure cat headers
+tests:assert-stdout-re 'Alt-Svc: h3=":60002"'
+
+tests:ensure curl -sk --http3-only "https://127.0.0.1:60002/t/a/b/c/d"
+tests:assert-no-diff stdout < record
@@ -0,0 +1,28 @@
+tests:ensure :shadowd -G --no-confirm --length 10 pool/a '<<<' 'password'
+tests:ensure :shadowd -G --no-confirm --length 10 --crypt-id 6 pool/b \
+    '<<<' 'password'
+tests:ensure :shadowd -G --no-confirm --length 10 other/c '<<<' 'password'
+
+tests:ensure cp -r $(tests:get-tmp-dir)/tables $(tests:get-tmp-dir)/tables.old
+
+tests:ensure :shadowd table rotate pool/ --no-confirm '<<<' 'new-password'
+tests:assert-stdout 'pool/a: rotated'
+tests:assert-stdout 'pool/b: rotated'
+
+tests:not tests:ensure cmp \
+    $(tests:get-tmp-dir)/tables/pool/a $(tests:get-tmp-dir)/tables.old/pool/a
+tests:not tests:ensure cmp \
+    $(tests:get-tmp-dir)/tables/pool/b $(tests:get-tmp-dir)/tables.old/pool/b
+
+tests:ensure wc -l '<' $(tests:get-tmp-dir)/tables/pool/b
+tests:assert-stdout '10'
+
+tests:ensure head -n 1 $(tests:get-tmp-dir)/tables/pool/b
+tests:assert-stdout-re '^\$6\$'
+
+tests:ensure cmp \
+    $(tests:get-tmp-dir)/tables/other/c $(tests:get-tmp-dir)/tables.old/other/c
+
+tests:not tests:ensure :shadowd table rotate missing/ --no-confirm \
+    '<<<' 'new-password'
+tests:assert-exitcode 3
@@ -0,0 +1,26 @@
+:shadowd-prepare
+
+tests:ensure :shadowd -G --no-confirm --length 10 --store-verifier \
+    pool/a '<<<' 'password'
+
+tests:put commands <<COMMANDS
+list
+generate pool/b 20
+secret
+info pool/b
+verify pool/a
+password
+unknown
+delete pool/a
+list pool/
+COMMANDS
+
+tests:ensure :shadowd shell '<' commands
+tests:assert-stdout-re '^pool/a$'
+tests:assert-stdout 'Hash table pool/b with 20 items successfully created.'
+tests:assert-stdout 'Length: 20'
+tests:assert-stdout 'Password matches hash table pool/a.'
+tests:assert-stdout "Error: unknown command 'unknown', use help"
+tests:assert-stdout 'Hash table pool/a removed.'
+tests:assert-test ! -f $(tests:get-tmp-dir)/tables/pool/a
+tests:assert-test -f $(tests:get-tmp-dir)/tables/pool/b
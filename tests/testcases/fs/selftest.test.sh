@@ -0,0 +1,8 @@
+tests:ensure :shadowd -G --no-confirm --length 100 a/b/c/d '<<<' 'password'
+
+tests:ensure :shadowd selftest a/b/c/d
+tests:assert-stdout 'Selftest passed: valid record for a/b/c/d received'
+
+tests:eval :shadowd selftest a/b/c/e
+tests:assert-exitcode 3
+tests:assert-stderr 'selftest failed'
@@ -0,0 +1,18 @@
+:shadowd-listen "127.0.0.1:60002"
+
+tests:ensure :shadowd -G --no-confirm --length 100 a/b/c/d '<<<' 'password'
+
+tests:ensure curl -sk -D - "https://127.0.0.1:60002/t/a/b/c/d"
+tests:not tests:assert-stdout 'X-Shadowd-Notice'
+
+tests:ensure :shadowd table set a/b/c/d --banner "'token deprecated'"
+tests:assert-stdout 'Metadata of hash table a/b/c/d updated.'
+
+tests:ensure curl -sk -D - "https://127.0.0.1:60002/t/a/b/c/d"
+tests:assert-stdout 'X-Shadowd-Notice: token deprecated'
+tests:assert-stdout-re '^.{63}$'
+
+tests:ensure :shadowd table set a/b/c/d --banner "''"
+
+tests:ensure curl -sk -D - "https://127.0.0.1:60002/t/a/b/c/d"
+tests:not tests:assert-stdout 'X-Shadowd-Notice'
@@ -0,0 +1,21 @@
+:shadowd-listen "127.0.0.1:60002" --ttl 1h
+
+tests:ensure :shadowd -G --no-confirm --length 100 pool/token '<<<' 'password'
+
+tests:ensure curl -sk --http1.1 -D - "https://127.0.0.1:60002/t/pool/token"
+tests:not tests:assert-stdout 'X-Shadowd-Max-Age'
+
+tests:ensure :shadowd table set pool/token --record-max-age 12h
+tests:assert-stdout 'Metadata of hash table pool/token updated.'
+
+tests:ensure curl -sk --http1.1 -D - "https://127.0.0.1:60002/t/pool/token"
+tests:assert-stdout 'X-Shadowd-Max-Age: 43200'
+
+tests:ensure :shadowd table set pool/token --record-max-age 0
+
+tests:ensure curl -sk --http1.1 -D - "https://127.0.0.1:60002/t/pool/token"
+tests:not tests:assert-stdout 'X-Shadowd-Max-Age'
+
+tests:not tests:ensure :shadowd table set pool/token --record-max-age soon
+tests:assert-stderr 'invalid record max age: soon'
+tests:assert-exitcode 2
@@ -0,0 +1,43 @@
+:shadowd-listen 127.0.0.1:60002 --serve-tokens 'dmz/*,public'
+
+tests:ensure :shadowd -G --no-confirm --length 10 dmz/token '<<<' 'password'
+tests:ensure :shadowd -G --no-confirm --length 10 public '<<<' 'password'
+tests:ensure :shadowd -G --no-confirm --length 10 internal/token \
+    '<<<' 'password'
+tests:ensure :shadowd -G --no-confirm --length 10 dmz/nested/token \
+    '<<<' 'password'
+
+tests:ensure curl -sk "https://127.0.0.1:60002/t/dmz/token"
+tests:assert-stdout-re '^\$5\$'
+
+tests:ensure curl -sk "https://127.0.0.1:60002/t/public"
+tests:assert-stdout-re '^\$5\$'
+
+tests:ensure curl -sk -o /dev/null -w '%{http_code}' \
+    "https://127.0.0.1:60002/t/internal/token"
+tests:assert-stdout 404
+
+tests:ensure curl -sk -o /dev/null -w '%{http_code}' \
+    "https://127.0.0.1:60002/t/dmz/nested/token"
+tests:assert-stdout 404
+
+tests:ensure curl -sk -w '%{http_code}' "https://127.0.0.1:60002/t/internal/"
+tests:assert-no-diff stdout <<< '204'
+
+tests:put records <<RECORDS
+\$5\$abcdefghijklmnop\$aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa
+RECORDS
+
+tests:value record cat records
+
+for token in dmz/imported internal/imported; do
+    tests:ensure :shadowd table import $token '<' records
+done
+
+tests:ensure curl -sk -o /dev/null -w '%{http_code}' \
+    "https://127.0.0.1:60002/v/dmz/imported/$record"
+tests:assert-no-diff stdout <<< '200'
+
+tests:ensure curl -sk -o /dev/null -w '%{http_code}' \
+    "https://127.0.0.1:60002/v/internal/imported/$record"
+tests:assert-no-diff stdout <<< '404'
@@ -0,0 +1,18 @@
+:shadowd-listen "127.0.0.1:60002"
+
+tests:ensure curl -sk -w '%{http_code}' \
+    "https://127.0.0.1:60002/debug/pprof/"
+tests:assert-stdout-re '404$'
+
+tests:not tests:ensure curl -s "http://127.0.0.1:60003/debug/pprof/"
+
+:shadowd-background -L "127.0.0.1:60004" --pprof-listen "127.0.0.1:60003"
+tests:ensure sleep 1
+
+tests:ensure curl -s -w '%{http_code}' \
+    "http://127.0.0.1:60003/debug/pprof/heap?debug=1"
+tests:assert-stdout-re '200$'
+
+tests:ensure curl -sk -w '%{http_code}' \
+    "https://127.0.0.1:60004/debug/pprof/"
+tests:assert-stdout-re '404$'
@@ -0,0 +1,22 @@
+:shadowd-listen "127.0.0.1:60002" --ttl 1s
+
+tests:ensure :shadowd -G --no-confirm --length 1000 a '<<<' 'password'
+tests:ensure :shadowd table set a --no-rotate
+
+tests:ensure curl -sk "https://127.0.0.1:60002/t/a"
+pinned=$(cat $(tests:get-stdout-file))
+
+tests:ensure curl -sk "https://127.0.0.1:60002/t/a"
+tests:assert-no-diff stdout <<< "$pinned"
+
+sleep 2
+
+tests:ensure curl -sk "https://127.0.0.1:60002/t/a"
+tests:assert-no-diff stdout <<< "$pinned"
+
+tests:ensure :shadowd table set a --rotate
+
+sleep 2
+
+tests:ensure curl -sk "https://127.0.0.1:60002/t/a"
+tests:assert-test "'$(cat $(tests:get-stdout-file))'" != "'$pinned'"
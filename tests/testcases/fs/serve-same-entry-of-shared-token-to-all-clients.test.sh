@@ -0,0 +1,16 @@
+:shadowd-listen "127.0.0.1:60002"
+
+tests:ensure :shadowd -G --no-confirm --length 1000 a '<<<' 'password'
+tests:ensure :shadowd table set a --shared
+
+shared=$(head -n 1 $(tests:get-tmp-dir)/tables/a)
+
+for client in 127.0.0.2 127.0.0.3 127.0.0.4 127.0.0.4; do
+    tests:ensure curl -sk --interface $client "https://127.0.0.1:60002/t/a"
+    tests:assert-no-diff stdout <<< "$shared"
+done
+
+tests:ensure :shadowd table set a --unshared
+
+tests:ensure curl -sk --interface 127.0.0.4 "https://127.0.0.1:60002/t/a"
+tests:assert-test "'$(cat $(tests:get-stdout-file))'" != "'$shared'"
@@ -0,0 +1,12 @@
+:shadowd-listen "127.0.0.1:60002" --next-depth 2 --next-exhausted wrap
+
+tests:ensure :shadowd -G --no-confirm --length 100 a/b/c/d '<<<' 'password'
+
+for i in {1..3}; do
+    tests:ensure curl -sk "https://127.0.0.1:60002/t/a/b/c/d"
+    tests:value record_$i cat $(tests:get-stdout-file)
+done
+
+tests:ensure curl -sk -w "'\n%{http_code}'" \
+    "https://127.0.0.1:60002/t/a/b/c/d"
+tests:assert-no-diff stdout <<< "$record_1"$'\n200'
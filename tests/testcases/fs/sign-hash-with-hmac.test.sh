@@ -0,0 +1,19 @@
+tests:put hmac.key <<< 'secret'
+
+:shadowd-listen "127.0.0.1:60002" --hmac-key-file hmac.key
+
+tests:ensure :shadowd -G --no-confirm --length 100 a/b/c/d '<<<' 'password'
+
+tests:ensure curl -sk -D headers -o record "https://127.0.0.1:60002/t/a/b/c/d"
+
+tests:ensure python -c \
+    "import hmac, hashlib; print(hmac.new(b'secret', open('record', 'rb').read(), hashlib.sha256).hexdigest())"
+tests:value valid cat $(tests:get-stdout-file)
+
+tests:ensure python -c \
+    "import hmac, hashlib; print(hmac.new(b'wrong', open('record', 'rb').read(), hashlib.sha256).hexdigest())"
+tests:value invalid cat $(tests:get-stdout-file)
+
+tests:ensure cat headers
+tests:assert-stdout "X-Shadowd-HMAC: $valid"
+tests:not tests:assert-stdout "X-Shadowd-HMAC: $invalid"
@@ -0,0 +1,49 @@
+:shadowd-prepare
+
+tests:ensure :shadowd -G --no-confirm --length 100 a '<<<' 'password'
+
+tests:put clients <<CLIENTS
+# office
+10.0.0.1
+
+10.0.0.1
+CLIENTS
+
+tests:ensure :shadowd simulate --token a --clients clients
+tests:assert-stdout 'Clients: 2'
+tests:assert-stdout 'Table length: 100'
+tests:assert-stdout 'Distinct indexes: 1'
+tests:assert-stdout 'Max collisions: 2'
+tests:assert-stdout 'Coverage: 1.00%'
+tests:assert-stdout-re '^[0-9]+: 2$'
+
+tests:put clients <<CLIENTS
+10.0.0.1
+10.0.0.2
+10.0.0.3
+CLIENTS
+
+tests:ensure :shadowd simulate --token a --clients clients --size 1
+tests:assert-stdout 'Distinct indexes: 1'
+tests:assert-stdout 'Max collisions: 3'
+tests:assert-stdout 'Coverage: 100.00%'
+tests:assert-stdout-re '^0: 3$'
+
+tests:not tests:ensure :shadowd simulate --token b --clients clients
+tests:assert-exitcode 3
+
+tests:ensure :shadowd table set a --no-rotate
+
+tests:ensure :shadowd simulate --token a --clients clients -s 1s
+tests:ensure cp $(tests:get-stdout-file) simulated
+
+sleep 1
+
+tests:ensure :shadowd simulate --token a --clients clients -s 1s
+tests:assert-no-diff stdout < simulated
+
+tests:ensure :shadowd table set a --shared
+
+tests:ensure :shadowd simulate --token a --clients clients
+tests:assert-stdout 'Distinct indexes: 1'
+tests:assert-stdout-re '^0: 3$'
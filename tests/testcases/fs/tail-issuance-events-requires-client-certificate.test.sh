@@ -0,0 +1,9 @@
+:shadowd-listen "127.0.0.1:60002"
+
+tests:ensure openssl req -x509 -newkey rsa:1024 -nodes -days 1 \
+    -subj /CN=client -keyout client.key -out client.pem
+
+tests:not tests:ensure :shadowd tail 127.0.0.1:60002 \
+    --client-cert client.pem --client-key client.key
+tests:assert-stderr 'client certificate is not accepted by server'
+tests:assert-exitcode 2
@@ -0,0 +1,22 @@
+:client-certificate
+
+:shadowd-listen "127.0.0.1:60002" --client-ca $(tests:get-tmp-dir)/ca.pem
+
+tests:ensure :shadowd -G --no-confirm --length 10 pool/token '<<<' 'password'
+tests:ensure :shadowd -G --no-confirm --length 10 pool/other '<<<' 'password'
+
+tests:run-background _tail shadowd.test \
+    --certs $(tests:get-tmp-dir)/certs/ \
+    tail 127.0.0.1:60002 \
+    --client-cert client.pem --client-key client.key --token pool/token
+
+sleep 0.5
+
+tests:ensure curl -sk "https://127.0.0.1:60002/t/pool/other"
+tests:ensure curl -sk "https://127.0.0.1:60002/t/pool/token"
+
+sleep 0.5
+
+tests:ensure cat $(tests:get-background-stdout $_tail)
+tests:assert-stdout-re '^[0-9T:+Z-]+ pool/token 127.0.0.1 [0-9]+$'
+tests:not tests:assert-stdout 'pool/other'
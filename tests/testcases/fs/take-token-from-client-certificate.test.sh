@@ -0,0 +1,41 @@
+:client-certificate token:web
+
+:shadowd-listen "127.0.0.1:60002" --client-ca $(tests:get-tmp-dir)/ca.pem \
+    --token-from-cert --next-depth 0
+
+tests:ensure :shadowd -G --no-confirm --length 10 web '<<<' 'password'
+tests:ensure :shadowd -G --no-confirm --length 10 db '<<<' 'password'
+
+tests:ensure curl -sk --cert client.pem --key client.key \
+    "https://127.0.0.1:60002/t/"
+tests:value record cat $(tests:get-stdout-file)
+
+tests:ensure grep -c -F "$record" $(tests:get-tmp-dir)/tables/web
+tests:assert-no-diff stdout <<< '1'
+
+tests:ensure curl -sk --cert client.pem --key client.key \
+    "https://127.0.0.1:60002/t/web"
+tests:assert-no-diff stdout <<< "$record"
+
+tests:ensure curl -sk --cert client.pem --key client.key -w '%{http_code}' \
+    "https://127.0.0.1:60002/t/db"
+tests:assert-no-diff stdout <<< '403'
+
+tests:ensure curl -sk -w '%{http_code}' "https://127.0.0.1:60002/t/web"
+tests:assert-no-diff stdout <<< '403'
+
+tests:ensure curl -sk --cert client.pem --key client.key -w '%{http_code}' \
+    "https://127.0.0.1:60002/v/web/unknown"
+tests:assert-no-diff stdout <<< '404'
+
+tests:ensure curl -sk --cert client.pem --key client.key -w '%{http_code}' \
+    "https://127.0.0.1:60002/v/db/unknown"
+tests:assert-no-diff stdout <<< '403'
+
+tests:ensure curl -sk -w '%{http_code}' "https://127.0.0.1:60002/v/web/unknown"
+tests:assert-no-diff stdout <<< '403'
+
+# certificates identifying tokens do not grant admin access
+tests:ensure curl -sk --cert client.pem --key client.key -w '%{http_code}' \
+    "https://127.0.0.1:60002/admin/recent/web"
+tests:assert-no-diff stdout <<< '403'
@@ -0,0 +1,11 @@
+:shadowd-listen "127.0.0.1:60002" --trace-index --redact-tokens
+
+tests:ensure :shadowd -G --no-confirm --length 100 a/b/c/d '<<<' 'password'
+
+tests:ensure curl -sk "https://127.0.0.1:60002/t/a/b/c/d"
+tests:ensure curl -sk "https://127.0.0.1:60002/t/a/b/c/d"
+
+tests:ensure cat $(tests:get-background-stderr $_shadowd)
+tests:assert-stdout-re 'trace index: remote=[0-9a-f]{16} recent=false '
+tests:assert-stdout-re 'trace index: remote=[0-9a-f]{16} recent=true .* modifier=1 '
+tests:not tests:assert-stdout 'remote=127.0.0.1'
@@ -0,0 +1,14 @@
+:shadowd-listen "127.0.0.1:60002" --trace-index
+
+tests:ensure :shadowd -G --no-confirm --length 100 a/b/c/d '<<<' 'password'
+
+tests:ensure curl -sk "https://127.0.0.1:60002/t/a/b/c/d"
+tests:value record cat $(tests:get-stdout-file)
+
+tests:ensure grep -n -F "$record" $(tests:get-tmp-dir)/tables/a/b/c/d
+tests:value line cut -d: -f1 $(tests:get-stdout-file)
+
+tests:ensure cat $(tests:get-background-stderr $_shadowd)
+tests:assert-stdout-re "trace index: remote=127.0.0.1-a/b/c/d recent=false \
+slot=[0-9]+ bytes=[0-9a-f]+ modifier=0 index=[0-9]+ max=99 \
+number=$(($line - 1))$"
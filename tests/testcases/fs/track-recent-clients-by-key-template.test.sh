@@ -0,0 +1,27 @@
+:shadowd-listen "127.0.0.1:60002" --trace-index \
+    --recent-key-template "'{{.Header.Get \"X-Host-Id\"}}-{{.Token}}'"
+
+tests:ensure :shadowd -G --no-confirm --length 100 pool/a '<<<' 'password'
+
+tests:ensure curl -sk -H "'X-Host-Id: host1'" --interface 127.0.0.2 \
+    "https://127.0.0.1:60002/t/pool/a"
+tests:ensure curl -sk -H "'X-Host-Id: host1'" --interface 127.0.0.3 \
+    "https://127.0.0.1:60002/t/pool/a"
+tests:ensure curl -sk -H "'X-Host-Id: host2'" --interface 127.0.0.2 \
+    "https://127.0.0.1:60002/t/pool/a"
+
+tests:ensure cat $(tests:get-background-stderr $_shadowd)
+tests:assert-stdout-re 'remote=host1-pool/a recent=false'
+tests:assert-stdout-re 'remote=host1-pool/a recent=true'
+tests:assert-stdout-re 'remote=host2-pool/a recent=false'
+tests:not tests:assert-stdout 'remote=host2-pool/a recent=true'
+
+tests:not tests:ensure :shadowd -L 127.0.0.1:60003 \
+    --recent-key-template "'{{.Unknown}}-{{.Token}}'"
+tests:assert-stderr "can't evaluate recent key template"
+tests:assert-exitcode 2
+
+tests:not tests:ensure :shadowd -L 127.0.0.1:60003 \
+    --recent-key-template "'{{.Header.Get \"X-Host-Id\"}}'"
+tests:assert-stderr 'recent key template should end with -{{.Token}}'
+tests:assert-exitcode 2
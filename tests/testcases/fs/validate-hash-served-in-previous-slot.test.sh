@@ -0,0 +1,21 @@
+tests:put records <<RECORDS
+\$6\$abcdefghijklmnop\$aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa
+\$6\$ponmlkjihgfedcba\$aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa
+\$6\$qrstuvwxyzabcdef\$aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa
+RECORDS
+
+tests:ensure :shadowd table import pool/token '<' records
+
+:shadowd-listen "127.0.0.1:60002" --ttl 1s
+
+tests:ensure curl -sk "https://127.0.0.1:60002/t/pool/token"
+tests:value record cat $(tests:get-stdout-file)
+
+tests:describe "record: $record"
+
+# wait for the next time slot
+tests:ensure sleep 2
+
+tests:ensure curl -sk -o /dev/null -w '%{http_code}' \
+    "https://127.0.0.1:60002/v/pool/token/$record"
+tests:assert-no-diff stdout <<< '200'
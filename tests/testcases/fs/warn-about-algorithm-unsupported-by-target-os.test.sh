@@ -0,0 +1,15 @@
+:shadowd-prepare
+
+tests:ensure :shadowd -G --no-confirm --length 10 -a sha512 \
+    --target-os openbsd a '<<<' 'password'
+tests:assert-stderr "crypt id '6' is not known to be supported by openbsd"
+tests:assert-test -f $(tests:get-tmp-dir)/tables/a
+
+tests:ensure :shadowd -G --no-confirm --length 10 -a sha512 \
+    --target-os rhel7 b '<<<' 'password'
+tests:not tests:assert-stderr 'Warning'
+
+tests:not tests:ensure :shadowd -G --no-confirm --length 10 \
+    --target-os beos c '<<<' 'password'
+tests:assert-stderr "unknown target OS 'beos'"
+tests:assert-exitcode 2
@@ -0,0 +1,12 @@
+:shadowd-listen "127.0.0.1:60002" --metrics-textfile-interval 1s \
+    --metrics-textfile $(tests:get-tmp-dir)/shadowd.prom
+
+tests:ensure :shadowd -G --no-confirm --length 100 a/b/c/d '<<<' 'password'
+
+tests:ensure curl -sk "https://127.0.0.1:60002/t/a/b/c/d"
+
+tests:ensure sleep 2
+
+tests:ensure cat $(tests:get-tmp-dir)/shadowd.prom
+tests:assert-stdout '# TYPE shadowd_hashes_served_total counter'
+tests:assert-stdout-re '^shadowd_hashes_served_total 1$'
@@ -0,0 +1,29 @@
+tests:ensure :shadowd pki init --bytes 1024
+tests:assert-stdout 'CA certificate stored in'
+
+tests:ensure :shadowd pki issue --cn admin --bytes 1024
+tests:assert-stdout 'Client certificate for admin stored in'
+
+tests:ensure openssl verify -purpose sslclient \
+    -CAfile $(tests:get-tmp-dir)/certs/ca.pem \
+    $(tests:get-tmp-dir)/certs/clients/admin.pem
+tests:assert-stdout 'OK'
+
+tests:ensure cp $(tests:get-tmp-dir)/certs/ca.pem old-ca.pem
+
+tests:ensure :shadowd pki init --bytes 1024
+tests:assert-stdout '1 client certificates re-signed'
+
+tests:not tests:ensure openssl verify -CAfile old-ca.pem \
+    $(tests:get-tmp-dir)/certs/clients/admin.pem
+
+tests:ensure openssl verify -purpose sslclient \
+    -CAfile $(tests:get-tmp-dir)/certs/ca.pem \
+    $(tests:get-tmp-dir)/certs/clients/admin.pem
+tests:assert-stdout 'OK'
+
+tests:ensure :shadowd pki issue --cn dated --bytes 1024 --till 2030-12-31
+
+tests:ensure openssl x509 -noout -enddate \
+    -in $(tests:get-tmp-dir)/certs/clients/dated.pem
+tests:assert-stdout 'notAfter=Dec 31 00:00:00 2030 GMT'
@@ -0,0 +1,22 @@
+:mongod
+:shadowd-mongodb-config
+
+:client-certificate
+
+:shadowd-listen "127.0.0.1:60002" --client-ca $(tests:get-tmp-dir)/ca.pem
+
+tests:ensure :shadowd -G --no-confirm --length 10 pool/token '<<<' 'password'
+
+tests:ensure curl -sk --cert client.pem --key client.key \
+    -o bundle.json.gz "https://127.0.0.1:60002/admin/export/pool/token"
+
+tests:ensure gzip -dc bundle.json.gz '|' python -c \
+    "import json, sys; print('\n'.join(json.load(sys.stdin)['records']))"
+tests:put records < $(tests:get-stdout-file)
+
+tests:ensure :mongo "db.shadows.find({token: 'pool/token'}).sort({_id: 1})
+    .map(function(doc) { return doc.hash }).join('\n')"
+tests:assert-no-diff stdout < records
+
+tests:ensure wc -l '<' records
+tests:assert-stdout-re '^10$'
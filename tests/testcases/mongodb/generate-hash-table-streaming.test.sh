@@ -0,0 +1,17 @@
+:mongod
+:shadowd-mongodb-config
+
+tests:ensure \
+    :shadowd --no-confirm --length 100 -G pool/token '<<<' "password"
+
+tests:ensure \
+    :shadowd --no-confirm --length 1500 --stream -G pool/token '<<<' "password"
+
+tests:assert-stdout \
+    'Hash table pool/token with 1500 items successfully created'
+
+tests:ensure :mongo "db.shadows.find({token: 'pool/token'}).count()"
+tests:assert-stdout-re '^1500$'
+
+tests:ensure :mongo "db.pending_shadows.find({}).count()"
+tests:assert-stdout-re '^0$'
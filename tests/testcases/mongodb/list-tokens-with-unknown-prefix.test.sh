@@ -0,0 +1,16 @@
+:mongod
+:shadowd-mongodb-config
+
+:shadowd-listen 127.0.0.1:60002
+
+tests:ensure \
+    :shadowd --no-confirm --length 100 -G pool/token '<<<' "password"
+
+tests:ensure curl -sk -w "'\n%{http_code}'" "https://127.0.0.1:60002/t/pool/"
+tests:assert-no-diff stdout <<TOKENS
+token
+200
+TOKENS
+
+tests:ensure curl -sk -w '%{http_code}' "https://127.0.0.1:60002/t/unknown/"
+tests:assert-no-diff stdout <<< '404'
@@ -0,0 +1,21 @@
+:mongod
+:shadowd-mongodb-config
+:shadowd-listen "127.0.0.1:60002"
+
+tests:ensure :shadowd -G --no-confirm --length 100 a/b/c/d '<<<' 'password'
+
+tests:ensure curl -sk "https://127.0.0.1:60002/t/a/b/c/d"
+
+sleep 2
+
+tests:ensure :shadowd prune -s 1s --dry-run
+tests:assert-stdout '0 expired hash tables and 1 expired recent clients would'
+
+tests:ensure :mongo "db.clients.find({}).count()"
+tests:assert-stdout-re '^1$'
+
+tests:ensure :shadowd prune -s 1s
+tests:assert-stdout '0 expired hash tables and 1 expired recent clients removed.'
+
+tests:ensure :mongo "db.clients.find({}).count()"
+tests:assert-stdout-re '^0$'
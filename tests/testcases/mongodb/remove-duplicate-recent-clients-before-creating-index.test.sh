@@ -0,0 +1,22 @@
+:mongod
+:shadowd-mongodb-config
+
+# recent clients stored before unique index has been introduced
+tests:ensure :mongo \
+    "db.clients.insert([
+        {client: '127.0.0.1-pool/a', create_date: 1},
+        {client: '127.0.0.1-pool/a', create_date: 2},
+        {client: '127.0.0.2-pool/a', create_date: 1}
+    ])"
+
+tests:ensure :shadowd -G --no-confirm --length 10 pool/a '<<<' 'password'
+
+tests:ensure :mongo \
+    "db.clients.find({client: '127.0.0.1-pool/a'}).toArray()[0].create_date"
+tests:assert-stdout-re '^2$'
+
+tests:ensure :mongo "db.clients.find({}).count()"
+tests:assert-stdout-re '^2$'
+
+tests:ensure :mongo "db.clients.getIndexes().length"
+tests:assert-stdout-re '^2$'
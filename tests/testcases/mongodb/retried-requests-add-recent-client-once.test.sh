@@ -0,0 +1,18 @@
+:mongod
+:shadowd-mongodb-config
+:shadowd-listen "127.0.0.1:60002"
+
+tests:ensure :shadowd -G --no-confirm --length 100 a/b/c/d '<<<' 'password'
+
+:pull-concurrently() {
+    for i in {1..5}; do
+        curl -sk "https://127.0.0.1:60002/t/a/b/c/d" > /dev/null &
+    done
+
+    wait
+}
+
+tests:ensure :pull-concurrently
+
+tests:ensure :mongo "db.clients.find({}).count()"
+tests:assert-stdout-re '^1$'
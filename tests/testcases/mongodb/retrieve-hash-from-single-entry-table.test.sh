@@ -0,0 +1,10 @@
+:mongod
+:shadowd-mongodb-config
+
+:shadowd-listen "127.0.0.1:60002"
+
+# the only entry of table has index 0
+tests:ensure :shadowd -G --no-confirm --length 1 a '<<<' 'password'
+
+tests:ensure curl -k "https://127.0.0.1:60002/t/a"
+tests:assert-stdout-re '^\$5\$.{59}$'
@@ -0,0 +1,29 @@
+:mongod
+:shadowd-mongodb-config
+
+# imported hash tables have no stored parameters, so they are taken from
+# the first record
+tests:put records <<RECORDS
+\$6\$rounds=2000\$abcdefghijklmnop\$aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa
+\$6\$rounds=2000\$ponmlkjihgfedcba\$aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa
+RECORDS
+
+for token in pool/rotated pool/rekeyed; do
+    tests:ensure :shadowd table import $token '<' records
+done
+
+tests:ensure :shadowd table rotate pool/rotated --no-confirm \
+    '<<<' 'new-password'
+tests:assert-stdout 'pool/rotated: rotated'
+
+tests:ensure :mongo \
+    "db.shadows.find({token: 'pool/rotated'}).sort({_id: -1}).limit(1)[0].hash"
+tests:assert-stdout-re '^\$6\$rounds=2000\$[^$]{16}\$[^$]{86}$'
+
+tests:ensure :shadowd table rekey pool/rekeyed -a sha256 '<<<' 'password'
+tests:assert-stdout \
+    "Hash table pool/rekeyed successfully rekeyed to crypt id '5\$rounds=2000'."
+
+tests:ensure :mongo \
+    "db.shadows.find({token: 'pool/rekeyed'}).sort({_id: -1}).limit(1)[0].hash"
+tests:assert-stdout-re '^\$5\$rounds=2000\$[^$]{16}\$[^$]{43}$'
@@ -0,0 +1,16 @@
+:mongod
+:shadowd-mongodb-config
+
+:shadowd-listen "127.0.0.1:60002"
+
+tests:ensure :shadowd -G --no-confirm --length 100 a '<<<' 'password'
+tests:ensure :shadowd table set a --shared
+
+tests:ensure :mongo \
+    "db.shadows.find({token: 'a'}).sort({_id: 1}).limit(1)[0].hash"
+shared=$(cat $(tests:get-stdout-file))
+
+for client in 127.0.0.2 127.0.0.3 127.0.0.4 127.0.0.4; do
+    tests:ensure curl -sk --interface $client "https://127.0.0.1:60002/t/a"
+    tests:assert-no-diff stdout <<< "$shared"
+done
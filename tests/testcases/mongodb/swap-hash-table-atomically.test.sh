@@ -0,0 +1,60 @@
+:mongod
+:shadowd-mongodb-config
+
+:client-certificate
+
+:shadowd-listen "127.0.0.1:60002" --client-ca $(tests:get-tmp-dir)/ca.pem
+
+:export-records() {
+    local token="$1"
+
+    tests:ensure curl -sk --cert client.pem --key client.key \
+        -o bundle.json.gz "https://127.0.0.1:60002/admin/export/$token"
+
+    tests:ensure gzip -dc bundle.json.gz '|' python -c \
+        "import json, sys; print('\n'.join(json.load(sys.stdin)['records']))"
+}
+
+# salts are derived from the same seed, so tables generated for the same
+# password are identical and whole old and new tables are known in advance
+for password in old new; do
+    tests:ensure :shadowd -G --no-confirm --length 1000 \
+        --deterministic-salt 42 --allow-deterministic-salt \
+        pool/$password '<<<' $password
+
+    :export-records pool/$password
+    tests:put $password < $(tests:get-stdout-file)
+done
+
+tests:ensure :shadowd -G --no-confirm --length 1000 \
+    --deterministic-salt 42 --allow-deterministic-salt \
+    pool/token '<<<' old
+
+tests:put regenerate.sh <<SH
+for i in {1..5}; do
+    for password in new old; do
+        shadowd.test \
+            --tables $(tests:get-tmp-dir)/tables/ \
+            --meta $(tests:get-tmp-dir)/meta/ \
+            ${_shadowd_args[@]} \
+            -G --no-confirm --progress none --length 1000 \
+            --deterministic-salt 42 --allow-deterministic-salt \
+            pool/token <<< \$password
+    done
+done
+SH
+
+tests:run-background _regenerate bash regenerate.sh
+
+for i in {1..30}; do
+    :export-records pool/token
+    tests:put records < $(tests:get-stdout-file)
+
+    tests:ensure cmp -s records old '||' cmp -s records new
+
+    tests:ensure curl -sk -w "'\n%{http_code}'" \
+        "https://127.0.0.1:60002/t/pool/token"
+    tests:assert-stdout-re '^200$'
+
+    tests:ensure head -n 1 $(tests:get-stdout-file) '|' grep -qxF -f - old new
+done
@@ -0,0 +1,10 @@
+:shadowd-prepare
+:intermediate-certificate
+
+tests:ensure cat leaf.pem intermediate.pem '>' certs/cert.pem
+tests:ensure cp leaf.key certs/key.pem
+
+:shadowd-background -L "127.0.0.1:60004"
+
+tests:ensure openssl s_client -connect 127.0.0.1:60004 -showcerts '</dev/null'
+tests:assert-stdout-re '1 s:.*CN ?= ?intermediate'
@@ -0,0 +1,11 @@
+:shadowd-prepare
+:intermediate-certificate
+
+tests:ensure cp leaf.pem certs/cert.pem
+tests:ensure cp leaf.key certs/key.pem
+
+:shadowd-background -L "127.0.0.1:60004" \
+    --tls-chain $(tests:get-tmp-dir)/intermediate.pem
+
+tests:ensure openssl s_client -connect 127.0.0.1:60004 -showcerts '</dev/null'
+tests:assert-stdout-re '1 s:.*CN ?= ?intermediate'
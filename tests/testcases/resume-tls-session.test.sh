@@ -0,0 +1,9 @@
+:shadowd-listen "127.0.0.1:60002"
+
+tests:ensure openssl s_client -tls1_2 -connect 127.0.0.1:60002 \
+    -sess_out session '</dev/null'
+tests:assert-stdout 'New, TLS'
+
+tests:ensure openssl s_client -tls1_2 -connect 127.0.0.1:60002 \
+    -sess_in session '</dev/null'
+tests:assert-stdout 'Reused, TLS'
@@ -0,0 +1,7 @@
+tests:ensure :shadowd tune --algorithm sha512 --target 5ms
+tests:assert-stdout-re '^Recommended rounds for sha512: [0-9]+ '
+
+tests:value rounds sed -r "'s/.*: ([0-9]+) .*/\1/'" $(tests:get-stdout-file)
+
+tests:assert-test "$rounds" -ge 1000
+tests:assert-test "$rounds" -le 999999999
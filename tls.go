@@ -0,0 +1,230 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/reconquest/hierr-go"
+)
+
+func getServerTLSConfig(args map[string]interface{}) (*tls.Config, error) {
+	// session tickets allow clients, which are pulling hashes periodically,
+	// to resume TLS session without full handshake.
+	config := &tls.Config{
+		SessionTicketsDisabled: args["--no-session-resumption"].(bool),
+
+		// HTTP/2 is required by gRPC clients
+		NextProtos: []string{"h2", "http/1.1"},
+	}
+
+	if clientCA, ok := args["--client-ca"].(string); ok {
+		clientCAs, err := loadCertificatePool(clientCA)
+		if err != nil {
+			return nil, hierr.Errorf(
+				err, "can't load client CA from %s", clientCA,
+			)
+		}
+
+		// certificate is optional for regular clients, but only clients with
+		// verified certificate are treated as admins.
+		config.ClientAuth = tls.VerifyClientCertIfGiven
+		config.ClientCAs = clientCAs
+	}
+
+	if !config.SessionTicketsDisabled {
+		rotation, err := time.ParseDuration(
+			args["--ticket-key-rotation"].(string),
+		)
+		if err != nil {
+			return nil, usageError{
+				hierr.Errorf(err, "can't parse ticket key rotation interval"),
+			}
+		}
+
+		if rotation <= 0 {
+			return nil, usageError{
+				errors.New("ticket key rotation interval should be positive"),
+			}
+		}
+
+		err = rotateSessionTicketKeys(config, rotation)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return config, nil
+}
+
+// rotateSessionTicketKeys sets new session ticket key for specified config
+// and replaces it with new one every specified interval. Previous key is kept
+// for decrypting tickets issued before rotation, so tickets are valid no
+// longer than two intervals and keys are never persisted across restarts.
+func rotateSessionTicketKeys(config *tls.Config, interval time.Duration) error {
+	current, err := generateSessionTicketKey()
+	if err != nil {
+		return err
+	}
+
+	config.SetSessionTicketKeys([][32]byte{current})
+
+	go func() {
+		for range time.Tick(interval) {
+			next, err := generateSessionTicketKey()
+			if err != nil {
+				log.Println(err)
+				continue
+			}
+
+			config.SetSessionTicketKeys([][32]byte{next, current})
+
+			current = next
+		}
+	}()
+
+	return nil
+}
+
+func generateSessionTicketKey() ([32]byte, error) {
+	var key [32]byte
+
+	_, err := rand.Read(key[:])
+	if err != nil {
+		return key, hierr.Errorf(
+			err, "can't generate session ticket key",
+		)
+	}
+
+	return key, nil
+}
+
+// listenAndServeTLS serves specified server using specified TLS config as is,
+// unlike http.Server.ListenAndServeTLS, which uses copy of config, so session
+// ticket keys rotation would not be applied. If proxyProtocol is set,
+// connections should start with PROXY protocol header.
+func listenAndServeTLS(
+	server *http.Server, config *tls.Config, proxyProtocol bool,
+) error {
+	listener, err := net.Listen("tcp", server.Addr)
+	if err != nil {
+		return err
+	}
+
+	if proxyProtocol {
+		listener = proxyListener{listener}
+	}
+
+	return server.Serve(tls.NewListener(listener, config))
+}
+
+// loadCertificatePair loads certificate and key, mismatching pair is
+// reported explicitly instead of TLS startup failure.
+func loadCertificatePair(
+	certFile string, keyFile string,
+) (tls.Certificate, error) {
+	certificate, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		if strings.Contains(err.Error(), "does not match") {
+			return certificate, usageError{
+				fmt.Errorf(
+					"certificate %s does not match key %s, "+
+						"regenerate pair using 'shadowd -C' or fix paths",
+					certFile, keyFile,
+				),
+			}
+		}
+
+		return certificate, hierr.Errorf(
+			err, "can't load certificate pair %s and %s", certFile, keyFile,
+		)
+	}
+
+	return certificate, nil
+}
+
+// loadServerCertificate loads certificate pair, which will be presented to
+// clients. Certificate file can contain whole chain (leaf and
+// intermediates), also intermediates can be appended from file specified via
+// --tls-chain flag.
+func loadServerCertificate(
+	certFile string, keyFile string, args map[string]interface{},
+) (tls.Certificate, error) {
+	certificate, err := loadCertificatePair(certFile, keyFile)
+	if err != nil {
+		return certificate, err
+	}
+
+	chainFile, ok := args["--tls-chain"].(string)
+	if !ok {
+		return certificate, nil
+	}
+
+	data, err := ioutil.ReadFile(chainFile)
+	if err != nil {
+		return certificate, hierr.Errorf(
+			err, "can't read certificate chain %s", chainFile,
+		)
+	}
+
+	intermediates := 0
+	for {
+		var block *pem.Block
+
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		certificate.Certificate = append(certificate.Certificate, block.Bytes)
+		intermediates++
+	}
+
+	if intermediates == 0 {
+		return certificate, fmt.Errorf(
+			"no PEM certificates found in %s", chainFile,
+		)
+	}
+
+	return certificate, nil
+}
+
+// isAdmin reports whether request is made by client with certificate signed
+// by CA specified via --client-ca. Certificates identifying tokens of hosts
+// are not admin certificates.
+func isAdmin(request *http.Request) bool {
+	if request.TLS == nil || len(request.TLS.VerifiedChains) == 0 {
+		return false
+	}
+
+	_, identifiesToken := getCertificateToken(request)
+
+	return !identifiesToken
+}
+
+func loadCertificatePool(path string) (*x509.CertPool, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, errors.New("no PEM certificates found")
+	}
+
+	return pool, nil
+}
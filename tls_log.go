@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"log"
+)
+
+const (
+	tlsLogOff     = "off"
+	tlsLogErrors  = "errors"
+	tlsLogVerbose = "verbose"
+)
+
+// tlsHandshakeError is part of lines logged by net/http when TLS handshake
+// fails, including failed verification of client certificate.
+var tlsHandshakeError = []byte("http: TLS handshake error")
+
+// newServerErrorLog returns logger for errors of HTTP server, TLS handshake
+// errors are dropped if TLS logging is turned off, so scanners probing the
+// port don't flood the log.
+func newServerErrorLog(mode string) *log.Logger {
+	return log.New(
+		tlsErrorFilter{drop: mode == tlsLogOff}, "", log.LstdFlags,
+	)
+}
+
+type tlsErrorFilter struct {
+	drop bool
+}
+
+func (filter tlsErrorFilter) Write(data []byte) (int, error) {
+	if filter.drop && bytes.Contains(data, tlsHandshakeError) {
+		return len(data), nil
+	}
+
+	return log.Writer().Write(data)
+}
+
+// logTLSHandshakes makes specified config to log every completed TLS
+// handshake with remote address and subject of client certificate, so
+// rollout of client certificates can be followed.
+func logTLSHandshakes(config *tls.Config) {
+	config.GetConfigForClient = func(
+		hello *tls.ClientHelloInfo,
+	) (*tls.Config, error) {
+		remote := "unknown address"
+		if hello.Conn != nil {
+			remote = hello.Conn.RemoteAddr().String()
+		}
+
+		verbose := config.Clone()
+		verbose.GetConfigForClient = nil
+		verbose.VerifyConnection = func(state tls.ConnectionState) error {
+			client := "none"
+			if len(state.PeerCertificates) > 0 {
+				client = state.PeerCertificates[0].Subject.String()
+			}
+
+			log.Printf(
+				"TLS handshake with %s completed, client certificate: %s",
+				remote, client,
+			)
+
+			return nil
+		}
+
+		return verbose, nil
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+)
+
+// tokenCertPrefix is prefix of common name of client certificates, which
+// identify token of host, e.g. "token:pool/login". Such certificates do not
+// grant admin access.
+const tokenCertPrefix = "token:"
+
+// getCertificateToken returns token identified by verified client
+// certificate of request.
+func getCertificateToken(request *http.Request) (string, bool) {
+	if request.TLS == nil || len(request.TLS.VerifiedChains) == 0 {
+		return "", false
+	}
+
+	name := request.TLS.VerifiedChains[0][0].Subject.CommonName
+	if !strings.HasPrefix(name, tokenCertPrefix) {
+		return "", false
+	}
+
+	token := strings.TrimPrefix(name, tokenCertPrefix)
+	if validateToken(token) != nil {
+		return "", false
+	}
+
+	return token, true
+}
+
+// isTokenAllowedByCertificate reports whether client of request can access
+// specified token: when tokens are taken from certificates, only token
+// identified by client certificate can be accessed.
+func (server *Server) isTokenAllowedByCertificate(
+	request *http.Request, token string,
+) bool {
+	if !server.tokenFromCert {
+		return true
+	}
+
+	certToken, ok := getCertificateToken(request)
+
+	return ok && certToken == token
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// getCertificateState returns TLS state of client, which has verified
+// certificate with specified common name.
+func getCertificateState(name string) *tls.ConnectionState {
+	return &tls.ConnectionState{
+		VerifiedChains: [][]*x509.Certificate{
+			{{Subject: pkix.Name{CommonName: name}}},
+		},
+	}
+}
+
+func TestValidateChecksCertificateToken(t *testing.T) {
+	service, _ := newGRPCTestService(
+		t, "--client-ca", "ca.pem", "--token-from-cert",
+	)
+
+	for _, test := range []struct {
+		name string
+		code int
+	}{
+		{"token:pool/token", http.StatusNotFound},
+		{"token:pool/other", http.StatusForbidden},
+		{"client", http.StatusForbidden},
+	} {
+		request := httptest.NewRequest("GET", "/v/pool/token/unknown", nil)
+		request.TLS = getCertificateState(test.name)
+
+		response := httptest.NewRecorder()
+
+		service.handler.ServeHTTP(response, request)
+
+		if response.Code != test.code {
+			t.Fatalf(
+				"%s: expected %d, got %d", test.name, test.code, response.Code,
+			)
+		}
+	}
+}
+
+func TestGRPCValidateRecordChecksCertificateToken(t *testing.T) {
+	service, table := newGRPCTestService(
+		t, "--client-ca", "ca.pem", "--token-from-cert",
+	)
+
+	for _, test := range []struct {
+		name string
+		code codes.Code
+	}{
+		{"token:pool/token", codes.OK},
+		{"token:pool/other", codes.PermissionDenied},
+		{"client", codes.PermissionDenied},
+	} {
+		ctx := peer.NewContext(context.Background(), &peer.Peer{
+			Addr: &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1},
+			AuthInfo: credentials.TLSInfo{
+				State: *getCertificateState(test.name),
+			},
+		})
+
+		response, err := service.ValidateRecord(
+			ctx,
+			&ValidateRecordRequest{Token: "pool/token", Record: table[0]},
+		)
+		if status.Code(err) != test.code {
+			t.Fatalf("%s: expected %s, got %v", test.name, test.code, err)
+		}
+
+		if err == nil && !response.Valid {
+			t.Fatalf("%s: record %s is not valid", test.name, table[0])
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"time"
+
+	"github.com/reconquest/hierr-go"
+)
+
+// tokenInfo contains metadata of hash table stored for token.
+type tokenInfo struct {
+	// Verifier is argon2 hash of password, which has been used for hash table
+	// generation.
+	Verifier string `json:"verifier,omitempty" bson:"verifier,omitempty"`
+
+	// Banner is operational message, which is sent to clients in
+	// X-Shadowd-Notice header along with hash table records.
+	Banner string `json:"banner,omitempty" bson:"banner,omitempty"`
+
+	// Served is amount of hash entries served since hash table has been
+	// generated.
+	Served int64 `json:"served,omitempty" bson:"served,omitempty"`
+
+	// RecordMaxAge is amount of seconds, during which client should keep
+	// served record before pulling new one, it's sent to clients in
+	// X-Shadowd-Max-Age header, zero means that it's not sent.
+	RecordMaxAge int64 `json:"record_max_age,omitempty" bson:"record_max_age,omitempty"`
+
+	// NoRotate makes every client to be served the same hash table entry
+	// regardless of time slot, so password of account is never changed
+	// automatically.
+	NoRotate bool `json:"no_rotate,omitempty" bson:"no_rotate,omitempty"`
+
+	// Shared makes all clients to be served the first hash table entry, so
+	// every host gets the same record of shared account.
+	Shared bool `json:"shared,omitempty" bson:"shared,omitempty"`
+
+	// Sunset is date after which hash table is not served anymore, it is
+	// announced to clients in Sunset header before that date.
+	Sunset *time.Time `json:"sunset,omitempty" bson:"sunset,omitempty"`
+
+	// Created is time when hash table has been stored.
+	Created *time.Time `json:"created,omitempty" bson:"created,omitempty"`
+
+	// Modified is time when hash table or its metadata has been changed
+	// last time, served counter doesn't change it.
+	Modified *time.Time `json:"modified,omitempty" bson:"modified,omitempty"`
+
+	// Parameters are parameters of algorithm, which hash table has been
+	// generated with, they are reused when hash table is regenerated.
+	Parameters *tableParameters `json:"parameters,omitempty" bson:"parameters,omitempty"`
+}
+
+// isSunsetPassed reports whether hash table is not served anymore, because
+// its sunset date has passed.
+func (info *tokenInfo) isSunsetPassed(now time.Time) bool {
+	return info.Sunset != nil && now.After(*info.Sunset)
+}
+
+// updateTokenInfo reads metadata of specified token, passes it to specified
+// function and stores changed metadata.
+func updateTokenInfo(
+	backend Backend, token string, update func(info *tokenInfo),
+) error {
+	info, err := backend.GetTokenInfo(token)
+	if err != nil {
+		return hierr.Errorf(
+			err, "can't get metadata for %s", token,
+		)
+	}
+
+	update(info)
+
+	now := time.Now()
+	info.Modified = &now
+
+	err = backend.SetTokenInfo(token, info)
+	if err != nil {
+		return hierr.Errorf(
+			err, "can't save metadata for %s", token,
+		)
+	}
+
+	return nil
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+const (
+	verifierTime      = 1
+	verifierMemory    = 64 * 1024
+	verifierThreads   = 4
+	verifierKeyLength = 32
+	verifierSaltSize  = 16
+)
+
+// generateVerifier returns argon2id hash of password in PHC string format.
+func generateVerifier(password string) (string, error) {
+	salt := make([]byte, verifierSaltSize)
+	_, err := io.ReadFull(rand.Reader, salt)
+	if err != nil {
+		return "", err
+	}
+
+	return hashArgon2id(password, salt), nil
+}
+
+// hashArgon2id returns argon2id hash of password with specified salt in PHC
+// string format.
+func hashArgon2id(password string, salt []byte) string {
+	key := argon2.IDKey(
+		[]byte(password), salt,
+		verifierTime, verifierMemory, verifierThreads, verifierKeyLength,
+	)
+
+	return fmt.Sprintf(
+		"$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, verifierMemory, verifierTime, verifierThreads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	)
+}
+
+// argon2Verifier is parsed argon2id hash in PHC string format.
+type argon2Verifier struct {
+	memory  uint32
+	time    uint32
+	threads uint8
+	salt    []byte
+	key     []byte
+}
+
+func parseVerifier(verifier string) (*argon2Verifier, error) {
+	parts := strings.Split(verifier, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return nil, fmt.Errorf("unsupported verifier format")
+	}
+
+	var (
+		version int
+		parsed  argon2Verifier
+	)
+
+	_, err := fmt.Sscanf(parts[2], "v=%d", &version)
+	if err != nil {
+		return nil, fmt.Errorf("invalid verifier version: %s", err)
+	}
+
+	if version != argon2.Version {
+		return nil, fmt.Errorf("unsupported argon2 version %d", version)
+	}
+
+	_, err = fmt.Sscanf(
+		parts[3], "m=%d,t=%d,p=%d",
+		&parsed.memory, &parsed.time, &parsed.threads,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("invalid verifier parameters: %s", err)
+	}
+
+	parsed.salt, err = base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return nil, fmt.Errorf("invalid verifier salt: %s", err)
+	}
+
+	parsed.key, err = base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return nil, fmt.Errorf("invalid verifier hash: %s", err)
+	}
+
+	return &parsed, nil
+}
+
+func checkVerifier(verifier string, password string) (bool, error) {
+	parsed, err := parseVerifier(verifier)
+	if err != nil {
+		return false, err
+	}
+
+	key := argon2.IDKey(
+		[]byte(password), parsed.salt,
+		parsed.time, parsed.memory, parsed.threads, uint32(len(parsed.key)),
+	)
+
+	return subtle.ConstantTimeCompare(key, parsed.key) == 1, nil
+}